@@ -0,0 +1,165 @@
+package eventsource
+
+import (
+	"container/list"
+	"context"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// Cloner is implemented by aggregates which can be kept in the aggregate cache of the
+// repository, see WithAggregateCache.
+type Cloner interface {
+	Aggregate
+	// Clone returns a copy of the aggregate, of the same pointer type, which doesn't
+	// share any mutable state with it
+	Clone() Aggregate
+}
+
+// WithAggregateCache makes the repository keep clones of up to size aggregates
+// implementing Cloner, evicting the least recently loaded. When a cached aggregate is
+// loaded again, only the records with a later version than the cached state are
+// loaded and replayed. A ttl greater than zero limits how long an aggregate is cached. Aggregates
+// are removed from the cache when events are saved to them by the repository.
+func WithAggregateCache(size int, ttl time.Duration) RepositoryOption {
+	return func(repo *repository) {
+		repo.cache = newAggregateCache(size, ttl)
+	}
+}
+
+// loadCached loads the aggregate from the cache of the repository, if it is cached,
+// and replays the records with a later version. The version is used rather than the
+// sequence ID, as the records may be committed in another order than their sequence
+// IDs were created.
+func (repo repository) loadCached(ctx context.Context, aggregateID string, aggr Aggregate) (cached bool, deleted bool, err error) {
+	if repo.cache == nil {
+		return false, false, nil
+	}
+
+	version, ok := repo.cache.get(aggregateID, aggr)
+	if !ok {
+		return false, false, nil
+	}
+
+	history, err := repo.store.LoadByAggregate(ctx, aggregateID, ByVersion(version))
+	if err != nil {
+		return true, false, err
+	}
+
+	history, version = recordsAfterVersion(history, version)
+	if deleted, err = repo.replay(ctx, aggr, history); deleted || err != nil {
+		repo.cache.invalidate(aggregateID)
+		return true, deleted, err
+	}
+
+	if len(history) > 0 {
+		repo.cache.put(aggregateID, aggr, version)
+	}
+
+	return true, false, nil
+}
+
+type cacheEntry struct {
+	aggregateID string
+	aggregate   Aggregate
+	version     int64
+	expires     time.Time
+}
+
+// aggregateCache is a LRU cache of aggregates and their versions
+type aggregateCache struct {
+	size    int
+	ttl     time.Duration
+	mutex   sync.Mutex
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+func newAggregateCache(size int, ttl time.Duration) *aggregateCache {
+	return &aggregateCache{
+		size:    size,
+		ttl:     ttl,
+		order:   list.New(),
+		entries: map[string]*list.Element{},
+	}
+}
+
+// get copies a clone of the cached aggregate into aggr and returns its version, if
+// the aggregate is cached with the same type as aggr
+func (c *aggregateCache) get(aggregateID string, aggr Aggregate) (int64, bool) {
+	if _, ok := aggr.(Cloner); !ok || reflect.ValueOf(aggr).Kind() != reflect.Pointer {
+		return 0, false
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	element, ok := c.entries[aggregateID]
+	if !ok {
+		return 0, false
+	}
+
+	entry := element.Value.(*cacheEntry) // nolint:forcetypeassert
+	if !entry.expires.IsZero() && time.Now().After(entry.expires) {
+		c.remove(element)
+		return 0, false
+	}
+
+	if reflect.TypeOf(entry.aggregate) != reflect.TypeOf(aggr) {
+		return 0, false
+	}
+
+	clone := entry.aggregate.(Cloner).Clone() // nolint:forcetypeassert
+	if reflect.TypeOf(clone) != reflect.TypeOf(aggr) {
+		return 0, false
+	}
+
+	reflect.ValueOf(aggr).Elem().Set(reflect.ValueOf(clone).Elem())
+	c.order.MoveToFront(element)
+
+	return entry.version, true
+}
+
+// put caches a clone of the aggregate at the version, if it implements Cloner
+func (c *aggregateCache) put(aggregateID string, aggr Aggregate, version int64) {
+	cloner, ok := aggr.(Cloner)
+	if !ok || c.size <= 0 || version <= 0 {
+		return
+	}
+
+	entry := &cacheEntry{aggregateID: aggregateID, aggregate: cloner.Clone(), version: version}
+	if c.ttl > 0 {
+		entry.expires = time.Now().Add(c.ttl)
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if element, ok := c.entries[aggregateID]; ok {
+		c.remove(element)
+	}
+
+	c.entries[aggregateID] = c.order.PushFront(entry)
+
+	for c.order.Len() > c.size {
+		c.remove(c.order.Back())
+	}
+}
+
+// invalidate removes the aggregates from the cache
+func (c *aggregateCache) invalidate(aggregateIDs ...string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for _, aggregateID := range aggregateIDs {
+		if element, ok := c.entries[aggregateID]; ok {
+			c.remove(element)
+		}
+	}
+}
+
+func (c *aggregateCache) remove(element *list.Element) {
+	c.order.Remove(element)
+	delete(c.entries, element.Value.(*cacheEntry).aggregateID) // nolint:forcetypeassert
+}
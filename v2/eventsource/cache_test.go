@@ -0,0 +1,173 @@
+package eventsource
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type counterAggregate struct {
+	id     string
+	events []Event
+}
+
+func (a *counterAggregate) On(_ context.Context, event Event) error {
+	a.events = append(a.events, event)
+	return nil
+}
+
+func (a *counterAggregate) SetAggregateID(id string) {
+	a.id = id
+}
+
+func (a *counterAggregate) Clone() Aggregate {
+	return &counterAggregate{id: a.id, events: append([]Event{}, a.events...)}
+}
+
+type uncachedAggregate struct{}
+
+func (a *uncachedAggregate) On(context.Context, Event) error {
+	return nil
+}
+
+func (a *uncachedAggregate) SetAggregateID(string) {}
+
+func byOptions(count int) interface{} {
+	return mock.MatchedBy(func(opts []QueryOption) bool { return len(opts) == count })
+}
+
+func setupCachedRepository() (*StoreMock, Repository) {
+	storeMock := CreateStoreMock()
+	serializerMock := CreateSerializerMock()
+	serializerMock.On("Unmarshal", mock.Anything, mock.Anything).Return(&BaseEvent{}, nil)
+	serializerMock.On("Marshal", mock.Anything).Return([]byte("{}"), nil)
+
+	return storeMock, NewRepository(storeMock, serializerMock, WithAggregateCache(10, time.Minute))
+}
+
+func Test_AggregateCache_LoadsRecordsAfterCached(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.TODO()
+	storeMock, repo := setupCachedRepository()
+	storeMock.On("LoadByAggregate", ctx, "counter", byOptions(0)).Return([]Record{{SequenceID: "1", Version: 1}, {SequenceID: "2", Version: 2}}, nil).Once()
+	storeMock.On("LoadByAggregate", ctx, "counter", byOptions(1)).Return([]Record{{SequenceID: "3", Version: 3}}, nil).Once()
+	storeMock.On("LoadByAggregate", ctx, "counter", byOptions(1)).Return([]Record{}, nil).Once()
+
+	first := &counterAggregate{}
+	_, err := repo.Load(ctx, "counter", first)
+	require.NoError(t, err)
+	assert.Len(t, first.events, 2)
+
+	second := &counterAggregate{}
+	_, err = repo.Load(ctx, "counter", second)
+	require.NoError(t, err)
+	assert.Equal(t, "counter", second.id)
+	assert.Len(t, second.events, 3)
+	assert.Len(t, first.events, 2, "The loaded aggregates don't share state")
+
+	third := &counterAggregate{}
+	_, err = repo.Load(ctx, "counter", third)
+	require.NoError(t, err)
+	assert.Len(t, third.events, 3)
+
+	storeMock.AssertExpectations(t)
+}
+
+func Test_AggregateCache_RecordsCommittedOutOfSequence(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.TODO()
+	storeMock, repo := setupCachedRepository()
+	// the record with sequence ID "2" was committed before the one with "1"
+	storeMock.On("LoadByAggregate", ctx, "counter", byOptions(0)).Return([]Record{{SequenceID: "2", Version: 1}}, nil).Once()
+	// the store returns all records, as if it didn't support ByVersion
+	storeMock.On("LoadByAggregate", ctx, "counter", byOptions(1)).Return([]Record{{SequenceID: "1", Version: 2}, {SequenceID: "2", Version: 1}}, nil).Once()
+
+	_, err := repo.Load(ctx, "counter", &counterAggregate{})
+	require.NoError(t, err)
+
+	aggr := &counterAggregate{}
+	_, err = repo.Load(ctx, "counter", aggr)
+	require.NoError(t, err)
+	assert.Len(t, aggr.events, 2, "The record with the lower sequence ID is replayed after the cached state")
+
+	storeMock.AssertExpectations(t)
+}
+
+func Test_AggregateCache_InvalidatedOnSave(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.TODO()
+	storeMock, repo := setupCachedRepository()
+	txMock := CreateStoreTransactionMock()
+	storeMock.On("LoadByAggregate", ctx, "counter", byOptions(0)).Return([]Record{{SequenceID: "1"}}, nil).Twice()
+	storeMock.On("NewTransaction", ctx, mock.Anything).Return(txMock, nil).Once()
+	txMock.On("Commit").Return(nil).Once()
+	txMock.On("GetRecords").Return([]Record{{AggregateID: "counter", SequenceID: "2"}})
+
+	_, err := repo.Load(ctx, "counter", &counterAggregate{})
+	require.NoError(t, err)
+
+	require.NoError(t, repo.Save(ctx, &BaseEvent{AggregateID: "counter"}))
+
+	_, err = repo.Load(ctx, "counter", &counterAggregate{})
+	require.NoError(t, err)
+
+	storeMock.AssertExpectations(t)
+	txMock.AssertExpectations(t)
+}
+
+func Test_AggregateCache_OnlyCloners(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.TODO()
+	storeMock, repo := setupCachedRepository()
+	storeMock.On("LoadByAggregate", ctx, "counter", byOptions(0)).Return([]Record{{SequenceID: "1"}}, nil).Twice()
+
+	_, err := repo.Load(ctx, "counter", &uncachedAggregate{})
+	require.NoError(t, err)
+
+	_, err = repo.Load(ctx, "counter", &uncachedAggregate{})
+	require.NoError(t, err)
+
+	storeMock.AssertExpectations(t)
+}
+
+func Test_AggregateCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	t.Parallel()
+
+	cache := newAggregateCache(2, 0)
+	cache.put("a", &counterAggregate{id: "a"}, 1)
+	cache.put("b", &counterAggregate{id: "b"}, 1)
+
+	_, ok := cache.get("a", &counterAggregate{})
+	require.True(t, ok)
+
+	cache.put("c", &counterAggregate{id: "c"}, 1)
+
+	_, ok = cache.get("b", &counterAggregate{})
+	assert.False(t, ok, "b was used least recently")
+
+	aggr := &counterAggregate{}
+	version, ok := cache.get("a", aggr)
+	require.True(t, ok)
+	assert.Equal(t, int64(1), version)
+	assert.Equal(t, "a", aggr.id)
+}
+
+func Test_AggregateCache_Expires(t *testing.T) {
+	t.Parallel()
+
+	cache := newAggregateCache(2, time.Nanosecond)
+	cache.put("a", &counterAggregate{id: "a"}, 1)
+
+	time.Sleep(time.Millisecond)
+
+	_, ok := cache.get("a", &counterAggregate{})
+	assert.False(t, ok)
+}
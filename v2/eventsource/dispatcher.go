@@ -0,0 +1,364 @@
+package eventsource
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const defaultBatchSize = 10
+
+// BatchNotificationService is implemented by notification services which can send
+// several records in one call, like SNS PublishBatch. If only some of the records
+// fail, a *NotificationError with the failed records is returned, otherwise all
+// records are considered failed.
+type BatchNotificationService interface {
+	NotificationService
+	SendBatchWithContext(ctx context.Context, records []Record) error
+}
+
+// OrderedNotificationService is implemented by notification services which have to
+// receive the records in order, like FIFO topics and queues. When a record sent to an
+// ordered service fails, the following records aren't sent before it, see Dispatcher.
+type OrderedNotificationService interface {
+	NotificationService
+	Ordered() bool
+}
+
+// ErrPrecedingRecordFailed is the error of records which weren't sent to an ordered
+// notification service, because a record before them failed
+var ErrPrecedingRecordFailed = errors.New("not sent as a preceding record failed")
+
+// NotificationFailure is a record which couldn't be sent by a notification service
+type NotificationFailure struct {
+	Service NotificationService
+	Record  Record
+	Err     error
+}
+
+// NotificationError is returned when records couldn't be sent by the notification
+// services, listing each failed record and service pair. It matches ErrNotificationFailed
+// and the errors of the failures with errors.Is.
+type NotificationError struct {
+	Failures []NotificationFailure
+}
+
+func (e *NotificationError) Error() string {
+	failures := make([]string, len(e.Failures))
+	for i, failure := range e.Failures {
+		failures[i] = fmt.Sprintf("record %s to %T: %s", failure.Record.SequenceID, failure.Service, failure.Err)
+	}
+
+	return fmt.Sprintf("%s: %s", ErrNotificationFailed, strings.Join(failures, "; "))
+}
+
+func (e *NotificationError) Unwrap() []error {
+	errs := []error{ErrNotificationFailed}
+	for _, failure := range e.Failures {
+		errs = append(errs, failure.Err)
+	}
+
+	return errs
+}
+
+// RetryPolicy decides how many times sending a record is attempted, waiting
+// InitialBackoff before the first retry and doubling it up to MaxBackoff
+type RetryPolicy struct {
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+}
+
+// Backoff returns the delay before the given retry, starting at 1
+func (p RetryPolicy) Backoff(retry int) time.Duration {
+	backoff := p.InitialBackoff
+	for i := 1; i < retry && backoff < p.MaxBackoff; i++ {
+		backoff *= 2
+	}
+
+	if p.MaxBackoff > 0 {
+		backoff = min(backoff, p.MaxBackoff)
+	}
+
+	return backoff
+}
+
+// Dispatcher sends saved records to notification services. The services are called
+// concurrently, while each service gets the records in order, in batches if it implements
+// BatchNotificationService. A failed record doesn't stop the following records or services,
+// unless the service is an ordered one. Then the records from the failed one are retried,
+// and if it still fails the following records aren't sent.
+type Dispatcher struct {
+	batchSize   int
+	concurrency int
+	retry       RetryPolicy
+}
+
+// DispatcherOption is used for configuring a Dispatcher
+type DispatcherOption func(d *Dispatcher)
+
+// WithBatchSize sets the maximum number of records sent in one call to a
+// BatchNotificationService, 10 by default. Services with a lower limit split the batches.
+func WithBatchSize(batchSize int) DispatcherOption {
+	return func(d *Dispatcher) {
+		d.batchSize = max(batchSize, 1)
+	}
+}
+
+// WithConcurrency sets the maximum number of services called at the same time,
+// by default all of them
+func WithConcurrency(concurrency int) DispatcherOption {
+	return func(d *Dispatcher) {
+		d.concurrency = concurrency
+	}
+}
+
+// WithRetryPolicy makes the dispatcher retry failed records, by default they are only attempted once
+func WithRetryPolicy(policy RetryPolicy) DispatcherOption {
+	return func(d *Dispatcher) {
+		d.retry = policy
+	}
+}
+
+// NewDispatcher creates a new dispatcher
+func NewDispatcher(opts ...DispatcherOption) *Dispatcher {
+	d := &Dispatcher{
+		batchSize: defaultBatchSize,
+		retry:     RetryPolicy{MaxAttempts: 1},
+	}
+
+	for _, opt := range opts {
+		opt(d)
+	}
+
+	return d
+}
+
+// WithDispatcher makes the repository send the saved records to the notification
+// services using the dispatcher
+func WithDispatcher(dispatcher *Dispatcher) RepositoryOption {
+	return func(repo *repository) {
+		repo.dispatcher = dispatcher
+	}
+}
+
+// Dispatch sends the records to all services, returning a *NotificationError if some
+// records couldn't be sent after all retries
+func (d *Dispatcher) Dispatch(ctx context.Context, services []NotificationService, records []Record) error {
+	if len(services) == 0 || len(records) == 0 {
+		return nil
+	}
+
+	var (
+		wg       sync.WaitGroup
+		mutex    sync.Mutex
+		failures []NotificationFailure
+	)
+
+	concurrency := d.concurrency
+	if concurrency <= 0 {
+		concurrency = len(services)
+	}
+
+	semaphore := make(chan struct{}, concurrency)
+
+	for _, service := range services {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			serviceFailures := d.dispatchToService(ctx, service, records)
+
+			mutex.Lock()
+			failures = append(failures, serviceFailures...)
+			mutex.Unlock()
+		}()
+	}
+
+	wg.Wait()
+
+	if len(failures) > 0 {
+		return &NotificationError{Failures: failures}
+	}
+
+	return nil
+}
+
+func (d *Dispatcher) dispatchToService(ctx context.Context, service NotificationService, records []Record) []NotificationFailure {
+	batchSize := 1
+	if _, ok := service.(BatchNotificationService); ok {
+		batchSize = d.batchSize
+	}
+
+	ordered := isOrdered(service)
+
+	var failures []NotificationFailure
+
+	for start := 0; start < len(records); start += batchSize {
+		batch := records[start:min(start+batchSize, len(records))]
+		batchFailures := d.sendWithRetries(ctx, service, batch, ordered)
+		failures = append(failures, batchFailures...)
+
+		if ordered && len(batchFailures) > 0 {
+			return append(failures, precedingFailed(service, records[start+len(batch):])...)
+		}
+	}
+
+	return failures
+}
+
+// sendWithRetries sends the batch, retrying the failed records, or for ordered services
+// all records from the first failed one
+func (d *Dispatcher) sendWithRetries(ctx context.Context, service NotificationService, batch []Record, ordered bool) []NotificationFailure {
+	failures := send(ctx, service, batch, ordered)
+
+	for retry := 1; retry < d.retry.MaxAttempts && len(failures) > 0; retry++ {
+		select {
+		case <-ctx.Done():
+			return failures
+		case <-time.After(d.retry.Backoff(retry)):
+		}
+
+		failed := make([]Record, len(failures))
+		for i, failure := range failures {
+			failed[i] = failure.Record
+		}
+
+		failures = send(ctx, service, failed, ordered)
+	}
+
+	return failures
+}
+
+func isOrdered(service NotificationService) bool {
+	ordered, ok := service.(OrderedNotificationService)
+	return ok && ordered.Ordered()
+}
+
+// precedingFailed returns failures for records which weren't sent as a preceding record failed
+func precedingFailed(service NotificationService, records []Record) []NotificationFailure {
+	failures := make([]NotificationFailure, len(records))
+	for i, record := range records {
+		failures[i] = NotificationFailure{Service: service, Record: record, Err: ErrPrecedingRecordFailed}
+	}
+
+	return failures
+}
+
+// failedFromFirst returns the failures of the records from the first failed one, so they
+// are retried in order. The records without an error of their own get ErrPrecedingRecordFailed.
+func failedFromFirst(service NotificationService, records []Record, failures []NotificationFailure) []NotificationFailure {
+	errs := make(map[string]error, len(failures))
+	for _, failure := range failures {
+		errs[failure.Record.SequenceID] = failure.Err
+	}
+
+	for i, record := range records {
+		if _, ok := errs[record.SequenceID]; !ok {
+			continue
+		}
+
+		result := precedingFailed(service, records[i:])
+		for j := range result {
+			if err, ok := errs[result[j].Record.SequenceID]; ok {
+				result[j].Err = err
+			}
+		}
+
+		return result
+	}
+
+	return failures
+}
+
+// SendInBatches sends the records in batches of at most size records, for notification
+// services whose API limits the number of records per call. The failures of all batches
+// are returned in one *NotificationError. If ordered, the batches after a failed one aren't
+// sent, and their records fail with ErrPrecedingRecordFailed.
+func SendInBatches(ctx context.Context, service NotificationService, records []Record, size int, ordered bool, sendBatch func(ctx context.Context, records []Record) error) error {
+	var failures []NotificationFailure
+
+	for start := 0; start < len(records); start += size {
+		batch := records[start:min(start+size, len(records))]
+
+		err := sendBatch(ctx, batch)
+		if err == nil {
+			continue
+		}
+
+		var notificationErr *NotificationError
+		if errors.As(err, &notificationErr) {
+			failures = append(failures, notificationErr.Failures...)
+		} else if len(records) <= size {
+			return err
+		} else {
+			for _, record := range batch {
+				failures = append(failures, NotificationFailure{Service: service, Record: record, Err: err})
+			}
+		}
+
+		if ordered {
+			failures = append(failures, precedingFailed(service, records[start+len(batch):])...)
+			break
+		}
+	}
+
+	if len(failures) > 0 {
+		return &NotificationError{Failures: failures}
+	}
+
+	return nil
+}
+
+func send(ctx context.Context, service NotificationService, records []Record, ordered bool) []NotificationFailure {
+	batchService, ok := service.(BatchNotificationService)
+	if !ok {
+		var failures []NotificationFailure
+
+		for i, record := range records {
+			if err := service.SendWithContext(ctx, record); err != nil {
+				failures = append(failures, NotificationFailure{Service: service, Record: record, Err: err})
+
+				if ordered {
+					return append(failures, precedingFailed(service, records[i+1:])...)
+				}
+			}
+		}
+
+		return failures
+	}
+
+	err := batchService.SendBatchWithContext(ctx, records)
+	if err == nil {
+		return nil
+	}
+
+	var notificationErr *NotificationError
+	if errors.As(err, &notificationErr) {
+		failures := make([]NotificationFailure, len(notificationErr.Failures))
+		for i, failure := range notificationErr.Failures {
+			failures[i] = NotificationFailure{Service: service, Record: failure.Record, Err: failure.Err}
+		}
+
+		if ordered {
+			return failedFromFirst(service, records, failures)
+		}
+
+		return failures
+	}
+
+	failures := make([]NotificationFailure, len(records))
+	for i, record := range records {
+		failures[i] = NotificationFailure{Service: service, Record: record, Err: err}
+	}
+
+	return failures
+}
@@ -0,0 +1,190 @@
+package eventsource
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func createDispatchRecords(n int) []Record {
+	records := make([]Record, n)
+	for i := range records {
+		records[i] = Record{SequenceID: NewULID()}
+	}
+
+	return records
+}
+
+func Test_DispatchContinuesAfterFailures(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.TODO()
+	records := createDispatchRecords(3)
+	sendErr := errors.New("unavailable")
+
+	failing := CreateNotificationServiceMock()
+	failing.On("SendWithContext", ctx, records[0]).Return(nil).Once()
+	failing.On("SendWithContext", ctx, records[1]).Return(sendErr).Once()
+	failing.On("SendWithContext", ctx, records[2]).Return(nil).Once()
+
+	working := CreateNotificationServiceMock()
+	working.On("SendWithContext", ctx, mock.Anything).Return(nil).Times(3)
+
+	err := NewDispatcher().Dispatch(ctx, []NotificationService{failing, working}, records)
+
+	failing.AssertExpectations(t)
+	working.AssertExpectations(t)
+
+	var notificationErr *NotificationError
+	require.ErrorAs(t, err, &notificationErr)
+	assert.ErrorIs(t, err, ErrNotificationFailed)
+	assert.ErrorIs(t, err, sendErr)
+	assert.Equal(t, []NotificationFailure{{Service: failing, Record: records[1], Err: sendErr}}, notificationErr.Failures)
+}
+
+func Test_DispatchRetries(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.TODO()
+	records := createDispatchRecords(2)
+
+	service := CreateNotificationServiceMock()
+	service.On("SendWithContext", ctx, records[0]).Return(nil).Once()
+	service.On("SendWithContext", ctx, records[1]).Return(errors.New("throttled")).Twice()
+	service.On("SendWithContext", ctx, records[1]).Return(nil).Once()
+
+	dispatcher := NewDispatcher(WithRetryPolicy(RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}))
+	err := dispatcher.Dispatch(ctx, []NotificationService{service}, records)
+
+	service.AssertExpectations(t)
+	assert.NoError(t, err)
+}
+
+type batchServiceMock struct {
+	*NotificationServiceMock
+	mutex   sync.Mutex
+	batches [][]Record
+	fail    map[string]error
+}
+
+func (b *batchServiceMock) SendBatchWithContext(_ context.Context, records []Record) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.batches = append(b.batches, records)
+
+	var failures []NotificationFailure
+
+	for _, record := range records {
+		if err, ok := b.fail[record.SequenceID]; ok {
+			delete(b.fail, record.SequenceID)
+			failures = append(failures, NotificationFailure{Record: record, Err: err})
+		}
+	}
+
+	if len(failures) > 0 {
+		return &NotificationError{Failures: failures}
+	}
+
+	return nil
+}
+
+func Test_DispatchBatches(t *testing.T) {
+	t.Parallel()
+
+	records := createDispatchRecords(5)
+	service := &batchServiceMock{
+		NotificationServiceMock: CreateNotificationServiceMock(),
+		fail:                    map[string]error{records[1].SequenceID: errors.New("throttled")},
+	}
+
+	dispatcher := NewDispatcher(WithBatchSize(2), WithRetryPolicy(RetryPolicy{MaxAttempts: 2}))
+	err := dispatcher.Dispatch(context.TODO(), []NotificationService{service}, records)
+	require.NoError(t, err)
+
+	assert.Equal(t, [][]Record{records[0:2], records[1:2], records[2:4], records[4:5]}, service.batches, "Only the failed record is retried")
+}
+
+type orderedServiceMock struct {
+	*batchServiceMock
+}
+
+func (o orderedServiceMock) Ordered() bool {
+	return true
+}
+
+func Test_DispatchOrderedBatches(t *testing.T) {
+	t.Parallel()
+
+	records := createDispatchRecords(5)
+	sendErr := errors.New("throttled")
+
+	service := orderedServiceMock{&batchServiceMock{
+		NotificationServiceMock: CreateNotificationServiceMock(),
+		fail:                    map[string]error{records[1].SequenceID: sendErr},
+	}}
+
+	dispatcher := NewDispatcher(WithBatchSize(3), WithRetryPolicy(RetryPolicy{MaxAttempts: 2}))
+	err := dispatcher.Dispatch(context.TODO(), []NotificationService{service}, records)
+	require.NoError(t, err)
+
+	assert.Equal(t, [][]Record{records[0:3], records[1:3], records[3:5]}, service.batches, "The records from the failed one are retried")
+
+	service.batches = nil
+	service.fail = map[string]error{records[1].SequenceID: sendErr}
+
+	err = NewDispatcher(WithBatchSize(3), WithRetryPolicy(RetryPolicy{MaxAttempts: 1})).Dispatch(context.TODO(), []NotificationService{service}, records)
+
+	var notificationErr *NotificationError
+	require.ErrorAs(t, err, &notificationErr)
+	assert.Equal(t, [][]Record{records[0:3]}, service.batches, "The batches after a failed one aren't sent")
+	assert.Equal(t, []NotificationFailure{
+		{Service: service, Record: records[1], Err: sendErr},
+		{Service: service, Record: records[2], Err: ErrPrecedingRecordFailed},
+		{Service: service, Record: records[3], Err: ErrPrecedingRecordFailed},
+		{Service: service, Record: records[4], Err: ErrPrecedingRecordFailed},
+	}, notificationErr.Failures)
+}
+
+func Test_RetryPolicyBackoff(t *testing.T) {
+	t.Parallel()
+
+	policy := RetryPolicy{MaxAttempts: 5, InitialBackoff: 100 * time.Millisecond, MaxBackoff: 300 * time.Millisecond}
+
+	assert.Equal(t, 100*time.Millisecond, policy.Backoff(1))
+	assert.Equal(t, 200*time.Millisecond, policy.Backoff(2))
+	assert.Equal(t, 300*time.Millisecond, policy.Backoff(3))
+	assert.Equal(t, 300*time.Millisecond, policy.Backoff(4))
+}
+
+func Test_RepoSaveNotificationFailed(t *testing.T) {
+	t.Parallel()
+
+	storeMock, storeTransactionMock, serializerMock, _ := setupMocks()
+	testEvent, testData := createMockDataForSave()
+	record := Record{UserID: testEvent.UserID, AggregateID: testEvent.AggregateID, Data: testData}
+	notificationService := CreateNotificationServiceMock()
+
+	ctx := context.TODO()
+
+	serializerMock.On("Marshal", testEvent).Return(testData, nil)
+	storeMock.On("NewTransaction", ctx, mock.Anything).Return(storeTransactionMock, nil).Once()
+	storeTransactionMock.On("GetRecords").Return([]Record{record}).Once()
+	storeTransactionMock.On("Commit").Return(nil).Once()
+	storeTransactionMock.On("Rollback").Return(nil).Once()
+	notificationService.On("SendWithContext", ctx, record).Return(errors.New("unavailable")).Twice()
+
+	repo := NewRepository(storeMock, serializerMock, WithDispatcher(NewDispatcher(WithRetryPolicy(RetryPolicy{MaxAttempts: 2}))))
+	repo.AddNotificationService(notificationService)
+	err := repo.Save(ctx, testEvent)
+
+	storeTransactionMock.AssertExpectations(t)
+	notificationService.AssertExpectations(t)
+	assert.ErrorIs(t, err, ErrNotificationFailed)
+}
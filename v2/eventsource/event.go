@@ -8,16 +8,19 @@ type Event interface {
 	GetUserID() string
 	GetSequenceID() string
 	GetTimestamp() int64
+	GetVersion() int64
 	SetSequenceID(string)
 	SetTimestamp(int64)
+	SetVersion(int64)
 }
 
 // BaseEvent ...
 type BaseEvent struct {
-	AggregateID string `json:"aggregateId"`
-	UserID      string `json:"userId"`
-	SequenceID  string `json:"sequenceId"`
-	Timestamp   int64  `json:"timestamp"`
+	AggregateID string `json:"aggregateId" avro:"aggregateId"`
+	UserID      string `json:"userId" avro:"userId"`
+	SequenceID  string `json:"sequenceId" avro:"sequenceId"`
+	Timestamp   int64  `json:"timestamp" avro:"timestamp"`
+	Version     int64  `json:"version" avro:"version"`
 }
 
 // GetType the type of the given input value, or if input is a pointer, return the type of the pointed to object
@@ -54,6 +57,11 @@ func (e BaseEvent) GetTimestamp() int64 {
 	return e.Timestamp
 }
 
+// GetVersion returns the position of the event in the stream of its aggregate
+func (e BaseEvent) GetVersion() int64 {
+	return e.Version
+}
+
 // SetSequenceID ...
 func (e *BaseEvent) SetSequenceID(sequenceID string) {
 	e.SequenceID = sequenceID
@@ -63,3 +71,8 @@ func (e *BaseEvent) SetSequenceID(sequenceID string) {
 func (e *BaseEvent) SetTimestamp(timestamp int64) {
 	e.Timestamp = timestamp
 }
+
+// SetVersion ...
+func (e *BaseEvent) SetVersion(version int64) {
+	e.Version = version
+}
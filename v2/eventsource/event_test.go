@@ -17,7 +17,8 @@ func Test_Getters(t *testing.T) {
 		userID          = "testUser"
 		ulid            = "ULID"
 		timestamp int64 = 123
-		testEvent       = eventsource.BaseEvent{aggID, userID, ulid, timestamp}
+		version   int64 = 3
+		testEvent       = eventsource.BaseEvent{aggID, userID, ulid, timestamp, version}
 	)
 
 	assert.Equal(t, aggID, testEvent.GetAggregateID())
@@ -26,6 +27,8 @@ func Test_Getters(t *testing.T) {
 	assert.Equal(t, testEvent.SequenceID, testEvent.GetSequenceID())
 	assert.Equal(t, timestamp, testEvent.GetTimestamp())
 	assert.Equal(t, testEvent.Timestamp, testEvent.GetTimestamp())
+	assert.Equal(t, version, testEvent.GetVersion())
+	assert.Equal(t, testEvent.Version, testEvent.GetVersion())
 	assert.Equal(t, userID, testEvent.GetUserID())
 	assert.Equal(t, testEvent.UserID, testEvent.GetUserID())
 }
@@ -40,13 +43,17 @@ func Test_Setters(t *testing.T) {
 		ulid2            = "CHANGCED_ULID"
 		timestamp  int64 = 123
 		timestamp2 int64 = 456
-		testEvent        = eventsource.BaseEvent{aggID, userID, ulid, timestamp}
+		version    int64 = 1
+		version2   int64 = 2
+		testEvent        = eventsource.BaseEvent{aggID, userID, ulid, timestamp, version}
 	)
 
 	testEvent.SetSequenceID(ulid2)
 	testEvent.SetTimestamp(timestamp2)
+	testEvent.SetVersion(version2)
 	assert.Equal(t, ulid2, testEvent.GetSequenceID())
 	assert.Equal(t, timestamp2, testEvent.GetTimestamp())
+	assert.Equal(t, version2, testEvent.GetVersion())
 }
 
 func Test_GetTypeName(t *testing.T) {
@@ -0,0 +1,282 @@
+package eventsource
+
+import (
+	"context"
+	"fmt"
+	"iter"
+
+	"github.com/pkg/errors"
+)
+
+// OperationKind is the kind of store call seen by a StoreInterceptor
+type OperationKind int
+
+// The operations intercepted by WrapStore
+const (
+	OperationLoadByAggregate OperationKind = iota + 1
+	OperationLoad
+	OperationNewTransaction
+	OperationCommit
+	OperationRollback
+	OperationIterByAggregate
+	OperationIter
+)
+
+// ErrNoTransaction is returned when an interceptor returns neither a transaction nor
+// an error for OperationNewTransaction
+var ErrNoTransaction = errors.New("no transaction created")
+
+// deprecatedLoad is the deprecated store method called for OperationLoad
+type deprecatedLoad int
+
+const (
+	loadBySequenceID deprecatedLoad = iota + 1
+	loadBySequenceIDAndType
+	loadByTimestamp
+)
+
+func (k OperationKind) String() string {
+	switch k {
+	case OperationLoadByAggregate:
+		return "LoadByAggregate"
+	case OperationLoad:
+		return "Load"
+	case OperationNewTransaction:
+		return "NewTransaction"
+	case OperationCommit:
+		return "Commit"
+	case OperationRollback:
+		return "Rollback"
+	case OperationIterByAggregate:
+		return "IterByAggregate"
+	case OperationIter:
+		return "Iter"
+	default:
+		return fmt.Sprintf("OperationKind(%d)", int(k))
+	}
+}
+
+// StoreOperation is a store call seen by a StoreInterceptor. AggregateID is set when
+// loading or iterating by aggregate, Options when loading or iterating, and Records when
+// creating, committing or rolling back a transaction. The records of a transaction can
+// only be changed when creating it. The deprecated load methods are seen as
+// OperationLoad with their filters in SequenceID, EventType and Timestamp.
+type StoreOperation struct {
+	Kind        OperationKind
+	AggregateID string
+	Options     []QueryOption
+	Records     []Record
+	SequenceID  string
+	EventType   string
+	Timestamp   int64
+
+	// load is the deprecated store method to call for OperationLoad, when not Store.Load
+	load deprecatedLoad
+	// tx is the transaction to commit or roll back
+	tx StoreTransaction
+}
+
+// StoreResult is the result of a store operation, Records is set when loading, Iter
+// when iterating and Transaction when creating a transaction. The records of Iter are
+// read when iterating over it, after the interceptors have returned.
+type StoreResult struct {
+	Records     []Record
+	Iter        iter.Seq2[Record, error]
+	Transaction StoreTransaction
+}
+
+// StoreHandler performs a store operation
+type StoreHandler func(ctx context.Context, op StoreOperation) (StoreResult, error)
+
+// StoreInterceptor is called for each operation of a store wrapped by WrapStore. It
+// can observe the operation and its result, modify the operation before passing it to
+// next, or short-circuit it by returning without calling next.
+type StoreInterceptor func(ctx context.Context, op StoreOperation, next StoreHandler) (StoreResult, error)
+
+type interceptedStore struct {
+	store   Store
+	handler StoreHandler
+}
+
+// WrapStore returns a store passing each operation through the interceptors, in the
+// order given, before it reaches the store. The deprecated load methods are
+// intercepted as OperationLoad. The optional interfaces of the store, like
+// NotifyingStore, are found by the repository through Unwrap.
+func WrapStore(store Store, interceptors ...StoreInterceptor) IterStore {
+	s := &interceptedStore{store: store}
+	s.handler = s.perform
+
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		interceptor, next := interceptors[i], s.handler
+		s.handler = func(ctx context.Context, op StoreOperation) (StoreResult, error) {
+			return interceptor(ctx, op, next)
+		}
+	}
+
+	return s
+}
+
+// perform calls the wrapped store, after all interceptors
+func (s *interceptedStore) perform(ctx context.Context, op StoreOperation) (result StoreResult, err error) {
+	switch op.Kind {
+	case OperationLoadByAggregate:
+		result.Records, err = s.store.LoadByAggregate(ctx, op.AggregateID, op.Options...)
+	case OperationLoad:
+		result.Records, err = s.load(ctx, op)
+	case OperationIterByAggregate:
+		result.Iter = s.iterByAggregate(ctx, op.AggregateID, op.Options)
+	case OperationIter:
+		result.Iter = IterRecords(ctx, s.store, op.Options...)
+	case OperationNewTransaction:
+		var tx StoreTransaction
+
+		tx, err = s.store.NewTransaction(ctx, op.Records...)
+		if err == nil {
+			result.Transaction = &interceptedTransaction{StoreTransaction: tx, ctx: ctx, store: s}
+		}
+	case OperationCommit:
+		err = op.tx.Commit()
+	case OperationRollback:
+		err = op.tx.Rollback()
+	default:
+		err = errors.Errorf("unknown store operation %s", op.Kind)
+	}
+
+	return result, err // nolint:wrapcheck
+}
+
+// load calls Store.Load, or the deprecated load method of the operation
+func (s *interceptedStore) load(ctx context.Context, op StoreOperation) ([]Record, error) {
+	switch op.load {
+	case loadBySequenceID:
+		return s.store.LoadBySequenceID(ctx, op.SequenceID, op.Options...) // nolint:staticcheck,wrapcheck
+	case loadBySequenceIDAndType:
+		return s.store.LoadBySequenceIDAndType(ctx, op.SequenceID, op.EventType, op.Options...) // nolint:staticcheck,wrapcheck
+	case loadByTimestamp:
+		return s.store.LoadByTimestamp(ctx, op.Timestamp, op.Options...) // nolint:staticcheck,wrapcheck
+	default:
+		return s.store.Load(ctx, op.Options...) // nolint:wrapcheck
+	}
+}
+
+// iterByAggregate iterates over the records of the aggregate, which are loaded with
+// Store.LoadByAggregate if the store doesn't implement IterStore
+func (s *interceptedStore) iterByAggregate(ctx context.Context, aggregateID string, opts []QueryOption) iter.Seq2[Record, error] {
+	if iterStore, ok := s.store.(IterStore); ok {
+		return iterStore.IterByAggregate(ctx, aggregateID, opts...)
+	}
+
+	return func(yield func(Record, error) bool) {
+		records, err := s.store.LoadByAggregate(ctx, aggregateID, opts...)
+		if err != nil {
+			yield(Record{}, err)
+			return
+		}
+
+		for _, record := range records {
+			if !yield(record, nil) {
+				return
+			}
+		}
+	}
+}
+
+func (s *interceptedStore) NewTransaction(ctx context.Context, records ...Record) (StoreTransaction, error) {
+	result, err := s.handler(ctx, StoreOperation{Kind: OperationNewTransaction, Records: records})
+	if err == nil && result.Transaction == nil {
+		return nil, ErrNoTransaction
+	}
+
+	return result.Transaction, err
+}
+
+func (s *interceptedStore) LoadByAggregate(ctx context.Context, aggregateID string, opts ...QueryOption) ([]Record, error) {
+	result, err := s.handler(ctx, StoreOperation{Kind: OperationLoadByAggregate, AggregateID: aggregateID, Options: opts})
+
+	return result.Records, err
+}
+
+func (s *interceptedStore) Load(ctx context.Context, opts ...QueryOption) ([]Record, error) {
+	result, err := s.handler(ctx, StoreOperation{Kind: OperationLoad, Options: opts})
+
+	return result.Records, err
+}
+
+func (s *interceptedStore) LoadBySequenceID(ctx context.Context, sequenceID string, opts ...QueryOption) ([]Record, error) {
+	result, err := s.handler(ctx, StoreOperation{Kind: OperationLoad, Options: opts, SequenceID: sequenceID, load: loadBySequenceID})
+
+	return result.Records, err
+}
+
+func (s *interceptedStore) LoadBySequenceIDAndType(ctx context.Context, sequenceID string, eventType string, opts ...QueryOption) ([]Record, error) {
+	result, err := s.handler(ctx, StoreOperation{
+		Kind: OperationLoad, Options: opts, SequenceID: sequenceID, EventType: eventType, load: loadBySequenceIDAndType,
+	})
+
+	return result.Records, err
+}
+
+func (s *interceptedStore) LoadByTimestamp(ctx context.Context, timestamp int64, opts ...QueryOption) ([]Record, error) {
+	result, err := s.handler(ctx, StoreOperation{Kind: OperationLoad, Options: opts, Timestamp: timestamp, load: loadByTimestamp})
+
+	return result.Records, err
+}
+
+// IterByAggregate passes the operation through the interceptors when called, while the
+// records are read when iterating
+func (s *interceptedStore) IterByAggregate(ctx context.Context, aggregateID string, opts ...QueryOption) iter.Seq2[Record, error] {
+	return s.iter(ctx, StoreOperation{Kind: OperationIterByAggregate, AggregateID: aggregateID, Options: opts})
+}
+
+// Iter passes the operation through the interceptors when called, while the records
+// are read when iterating
+func (s *interceptedStore) Iter(ctx context.Context, opts ...QueryOption) iter.Seq2[Record, error] {
+	return s.iter(ctx, StoreOperation{Kind: OperationIter, Options: opts})
+}
+
+func (s *interceptedStore) iter(ctx context.Context, op StoreOperation) iter.Seq2[Record, error] {
+	result, err := s.handler(ctx, op)
+
+	return func(yield func(Record, error) bool) {
+		if err != nil {
+			yield(Record{}, err)
+			return
+		}
+
+		if result.Iter != nil {
+			result.Iter(yield)
+			return
+		}
+
+		for _, record := range result.Records {
+			if !yield(record, nil) {
+				return
+			}
+		}
+	}
+}
+
+// Unwrap returns the wrapped store
+func (s *interceptedStore) Unwrap() Store {
+	return s.store
+}
+
+// interceptedTransaction intercepts committing and rolling back, within the context
+// passed when creating it
+type interceptedTransaction struct {
+	StoreTransaction
+	ctx   context.Context // nolint:containedctx
+	store *interceptedStore
+}
+
+func (tx *interceptedTransaction) Commit() error {
+	_, err := tx.store.handler(tx.ctx, StoreOperation{Kind: OperationCommit, Records: tx.GetRecords(), tx: tx.StoreTransaction})
+
+	return err
+}
+
+func (tx *interceptedTransaction) Rollback() error {
+	_, err := tx.store.handler(tx.ctx, StoreOperation{Kind: OperationRollback, Records: tx.GetRecords(), tx: tx.StoreTransaction})
+
+	return err
+}
@@ -0,0 +1,202 @@
+package eventsource
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func recordingInterceptor(name string, calls *[]string) StoreInterceptor {
+	return func(ctx context.Context, op StoreOperation, next StoreHandler) (StoreResult, error) {
+		*calls = append(*calls, name+" "+op.Kind.String())
+		return next(ctx, op)
+	}
+}
+
+func Test_WrapStore_InterceptorOrder(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.TODO()
+	record := Record{AggregateID: "order", SequenceID: "1"}
+
+	storeMock := CreateStoreMock()
+	txMock := CreateStoreTransactionMock()
+	storeMock.On("NewTransaction", ctx, []Record{record}).Return(txMock, nil).Once()
+	storeMock.On("LoadByAggregate", ctx, "order", mock.Anything).Return([]Record{record}, nil).Once()
+	txMock.On("GetRecords").Return([]Record{record})
+	txMock.On("Commit").Return(nil).Once()
+	txMock.On("Rollback").Return(nil).Once()
+
+	var calls []string
+	store := WrapStore(storeMock, recordingInterceptor("first", &calls), recordingInterceptor("second", &calls))
+
+	tx, err := store.NewTransaction(ctx, record)
+	require.NoError(t, err)
+	require.NoError(t, tx.Commit())
+	require.NoError(t, tx.Rollback())
+
+	records, err := store.LoadByAggregate(ctx, "order")
+	require.NoError(t, err)
+	assert.Equal(t, []Record{record}, records)
+
+	assert.Equal(t, []string{
+		"first NewTransaction", "second NewTransaction",
+		"first Commit", "second Commit",
+		"first Rollback", "second Rollback",
+		"first LoadByAggregate", "second LoadByAggregate",
+	}, calls)
+
+	storeMock.AssertExpectations(t)
+	txMock.AssertExpectations(t)
+}
+
+func Test_WrapStore_ModifyOperation(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.TODO()
+	storeMock := CreateStoreMock()
+	storeMock.On("Load", ctx, mock.MatchedBy(func(opts []QueryOption) bool { return len(opts) == 1 })).Return([]Record{}, nil).Once()
+
+	store := WrapStore(storeMock, func(ctx context.Context, op StoreOperation, next StoreHandler) (StoreResult, error) {
+		op.Options = append(op.Options, BySequenceID("1"))
+		return next(ctx, op)
+	})
+
+	_, err := store.Load(ctx)
+	require.NoError(t, err)
+
+	storeMock.AssertExpectations(t)
+}
+
+func Test_WrapStore_ShortCircuit(t *testing.T) {
+	t.Parallel()
+
+	errForbidden := errors.New("forbidden")
+	storeMock := CreateStoreMock()
+
+	store := WrapStore(storeMock, func(ctx context.Context, op StoreOperation, next StoreHandler) (StoreResult, error) {
+		if op.Kind == OperationLoadByAggregate && op.AggregateID == "secret" {
+			return StoreResult{}, errForbidden
+		}
+
+		return next(ctx, op)
+	})
+
+	_, err := store.LoadByAggregate(context.TODO(), "secret")
+	require.ErrorIs(t, err, errForbidden)
+
+	storeMock.AssertNotCalled(t, "LoadByAggregate", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func Test_WrapStore_DeprecatedLoad(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.TODO()
+	record := Record{AggregateID: "order", SequenceID: "2"}
+	storeMock := CreateStoreMock()
+	storeMock.On("LoadBySequenceID", ctx, "1", []QueryOption(nil)).Return([]Record{record}, nil).Once()
+	storeMock.On("LoadByTimestamp", ctx, int64(43), []QueryOption(nil)).Return([]Record{}, nil).Once()
+
+	var ops []StoreOperation
+	store := WrapStore(storeMock, func(ctx context.Context, op StoreOperation, next StoreHandler) (StoreResult, error) {
+		ops = append(ops, op)
+
+		if op.Timestamp == 42 {
+			op.Timestamp = 43
+		}
+
+		return next(ctx, op)
+	})
+
+	records, err := store.LoadBySequenceID(ctx, "1") // nolint:staticcheck
+	require.NoError(t, err)
+	assert.Equal(t, []Record{record}, records)
+
+	_, err = store.LoadByTimestamp(ctx, 42) // nolint:staticcheck
+	require.NoError(t, err)
+
+	require.Len(t, ops, 2)
+	assert.Equal(t, OperationLoad, ops[0].Kind)
+	assert.Equal(t, "1", ops[0].SequenceID)
+	assert.Equal(t, OperationLoad, ops[1].Kind)
+	assert.Equal(t, int64(42), ops[1].Timestamp)
+
+	storeMock.AssertExpectations(t)
+}
+
+func Test_WrapStore_Iter(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.TODO()
+	record := Record{AggregateID: "order", SequenceID: "1"}
+	storeMock := CreateStoreMock()
+	storeMock.On("Load", ctx, []QueryOption(nil)).Return([]Record{record}, nil).Once()
+	storeMock.On("LoadByAggregate", ctx, "order", []QueryOption(nil)).Return([]Record{record}, nil).Once()
+
+	var calls []string
+	store := WrapStore(storeMock, recordingInterceptor("interceptor", &calls))
+
+	records, err := CollectRecords(store.Iter(ctx))
+	require.NoError(t, err)
+	assert.Equal(t, []Record{record}, records)
+
+	records, err = CollectRecords(store.IterByAggregate(ctx, "order"))
+	require.NoError(t, err)
+	assert.Equal(t, []Record{record}, records)
+
+	assert.Equal(t, []string{"interceptor Iter", "interceptor IterByAggregate"}, calls)
+
+	storeMock.AssertExpectations(t)
+}
+
+func Test_WrapStore_NoTransaction(t *testing.T) {
+	t.Parallel()
+
+	store := WrapStore(CreateStoreMock(), func(context.Context, StoreOperation, StoreHandler) (StoreResult, error) {
+		return StoreResult{}, nil
+	})
+
+	_, err := store.NewTransaction(context.TODO())
+	require.ErrorIs(t, err, ErrNoTransaction)
+}
+
+func Test_WrapStore_Repository(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.TODO()
+	storeMock := CreateStoreMock()
+	serializerMock := CreateSerializerMock()
+	txMock := CreateStoreTransactionMock()
+
+	event := &BaseEvent{AggregateID: "order"}
+	serializerMock.On("Marshal", event).Return([]byte("{}"), nil)
+	storeMock.On("NewTransaction", ctx, mock.Anything).Return(txMock, nil).Once()
+	txMock.On("GetRecords").Return([]Record{})
+	txMock.On("Commit").Return(nil).Once()
+
+	var kinds []OperationKind
+	store := WrapStore(storeMock, func(ctx context.Context, op StoreOperation, next StoreHandler) (StoreResult, error) {
+		kinds = append(kinds, op.Kind)
+		return next(ctx, op)
+	})
+
+	require.NoError(t, NewRepository(store, serializerMock).Save(ctx, event))
+	assert.Equal(t, []OperationKind{OperationNewTransaction, OperationCommit}, kinds)
+
+	storeMock.AssertExpectations(t)
+	txMock.AssertExpectations(t)
+}
+
+func Test_WrapStore_Unwrap(t *testing.T) {
+	t.Parallel()
+
+	storeMock := CreateStoreMock()
+	store := WrapStore(WrapStore(relayingStore{storeMock}))
+
+	assert.True(t, StoreRelays(store), "The relaying store is found through the wrappers")
+	assert.False(t, StoreRelays(WrapStore(storeMock)))
+}
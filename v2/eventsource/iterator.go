@@ -0,0 +1,54 @@
+package eventsource
+
+import (
+	"context"
+	"iter"
+)
+
+// IterStore is implemented by stores which can read records lazily, row by row or
+// page by page, instead of loading all matching records into memory. All included
+// stores implement it.
+type IterStore interface {
+	Store
+	Iter(ctx context.Context, opts ...QueryOption) iter.Seq2[Record, error]
+	IterByAggregate(ctx context.Context, aggregateID string, opts ...QueryOption) iter.Seq2[Record, error]
+}
+
+// IterRecords iterates over the records of the store matching the query options.
+// The records are read lazily if the store implements IterStore, otherwise they are
+// loaded with Store.Load. The iteration stops after the first error.
+func IterRecords(ctx context.Context, store Store, opts ...QueryOption) iter.Seq2[Record, error] {
+	if s, ok := store.(IterStore); ok {
+		return s.Iter(ctx, opts...)
+	}
+
+	return func(yield func(Record, error) bool) {
+		records, err := store.Load(ctx, opts...)
+		if err != nil {
+			yield(Record{}, err)
+			return
+		}
+
+		for _, record := range records {
+			if !yield(record, nil) {
+				return
+			}
+		}
+	}
+}
+
+// CollectRecords reads all records of the sequence into a slice, returning the
+// records read before the first error together with the error.
+func CollectRecords(seq iter.Seq2[Record, error]) ([]Record, error) {
+	records := []Record{}
+
+	for record, err := range seq {
+		if err != nil {
+			return records, err
+		}
+
+		records = append(records, record)
+	}
+
+	return records, nil
+}
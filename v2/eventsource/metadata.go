@@ -0,0 +1,61 @@
+package eventsource
+
+import (
+	"context"
+	"maps"
+)
+
+// The keys of the metadata with predefined meaning
+const (
+	// CorrelationIDKey is the ID shared by all records caused by the same request or message
+	CorrelationIDKey = "correlationId"
+	// CausationIDKey is the ID of the command or message which caused the record
+	CausationIDKey = "causationId"
+)
+
+// Metadata is stored with the records, for tracing which command or message caused
+// them, and can carry arbitrary headers
+type Metadata map[string]string
+
+// CorrelationID returns the correlation ID of the metadata
+func (m Metadata) CorrelationID() string {
+	return m[CorrelationIDKey]
+}
+
+// CausationID returns the causation ID of the metadata
+func (m Metadata) CausationID() string {
+	return m[CausationIDKey]
+}
+
+type metadataKey struct{}
+
+// WithMetadata returns a context with the metadata added to the metadata of the parent
+// context. The records saved with the context get the metadata.
+func WithMetadata(ctx context.Context, metadata Metadata) context.Context {
+	merged := maps.Clone(MetadataFromContext(ctx))
+	if merged == nil {
+		merged = Metadata{}
+	}
+
+	maps.Copy(merged, metadata)
+
+	return context.WithValue(ctx, metadataKey{}, merged)
+}
+
+// WithCorrelationID returns a context with the correlation ID added to its metadata
+func WithCorrelationID(ctx context.Context, correlationID string) context.Context {
+	return WithMetadata(ctx, Metadata{CorrelationIDKey: correlationID})
+}
+
+// WithCausationID returns a context with the causation ID added to its metadata
+func WithCausationID(ctx context.Context, causationID string) context.Context {
+	return WithMetadata(ctx, Metadata{CausationIDKey: causationID})
+}
+
+// MetadataFromContext returns the metadata of the context, or nil if it has none. The
+// metadata must not be modified.
+func MetadataFromContext(ctx context.Context) Metadata {
+	metadata, _ := ctx.Value(metadataKey{}).(Metadata)
+
+	return metadata
+}
@@ -0,0 +1,47 @@
+package eventsource
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_MetadataFromContext(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.TODO()
+	assert.Nil(t, MetadataFromContext(ctx))
+
+	parent := WithMetadata(WithCorrelationID(ctx, "correlation"), Metadata{"tenant": "a"})
+	child := WithMetadata(WithCausationID(parent, "command"), Metadata{"tenant": "b"})
+
+	assert.Equal(t, Metadata{CorrelationIDKey: "correlation", "tenant": "a"}, MetadataFromContext(parent), "The parent isn't modified")
+
+	metadata := MetadataFromContext(child)
+	assert.Equal(t, Metadata{CorrelationIDKey: "correlation", CausationIDKey: "command", "tenant": "b"}, metadata)
+	assert.Equal(t, "correlation", metadata.CorrelationID())
+	assert.Equal(t, "command", metadata.CausationID())
+}
+
+func Test_RepoSaveWithMetadata(t *testing.T) {
+	t.Parallel()
+
+	storeMock, storeTransactionMock, serializerMock, _ := setupMocks()
+	testEvent, testData := createMockDataForSave()
+
+	ctx := WithCorrelationID(context.TODO(), "correlation")
+	serializerMock.On("Marshal", testEvent).Return(testData, nil)
+	storeMock.On("NewTransaction", ctx, mock.MatchedBy(func(rs []Record) bool {
+		return len(rs) == 1 && rs[0].Metadata.CorrelationID() == "correlation"
+	})).Return(storeTransactionMock, nil).Once()
+	storeTransactionMock.On("Commit").Return(nil).Once()
+	storeTransactionMock.On("GetRecords").Return([]Record{}).Once()
+
+	repo := NewRepository(storeMock, serializerMock)
+	require.NoError(t, repo.Save(ctx, testEvent))
+
+	storeMock.AssertExpectations(t)
+}
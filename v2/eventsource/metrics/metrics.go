@@ -0,0 +1,151 @@
+// Package metrics measures repositories and stores, by wrapping them
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/SKF/go-eventsource/v2/eventsource"
+)
+
+// Metrics receives the measurements of the wrapped repositories and stores
+type Metrics interface {
+	// EventsSaved is called with the number of records of the event type committed to the store
+	EventsSaved(eventType string, count int)
+	// RecordsLoaded is called with the number of records loaded to replay the aggregate
+	RecordsLoaded(aggregateID string, count int)
+	// LoadDuration is called with the time taken to load the aggregate
+	LoadDuration(aggregateID string, duration time.Duration, err error)
+	// CommitDuration is called with the time taken to commit a transaction to the store
+	CommitDuration(duration time.Duration, err error)
+	// NotificationFailed is called for each record the notification service failed to send
+	NotificationFailed(service string, eventType string)
+}
+
+type store struct {
+	eventsource.Store
+	metrics Metrics
+}
+
+// WrapStore returns a store measuring the records loaded by aggregate and the commits.
+// Iterating is passed on if the store supports it, and its other optional interfaces are
+// found through Unwrap.
+func WrapStore(wrapped eventsource.Store, metrics Metrics) eventsource.IterStore {
+	return &store{Store: wrapped, metrics: metrics}
+}
+
+func (s *store) LoadByAggregate(ctx context.Context, aggregateID string, opts ...eventsource.QueryOption) ([]eventsource.Record, error) {
+	records, err := s.Store.LoadByAggregate(ctx, aggregateID, opts...)
+	if err == nil {
+		s.metrics.RecordsLoaded(aggregateID, len(records))
+	}
+
+	return records, err // nolint:wrapcheck
+}
+
+func (s *store) NewTransaction(ctx context.Context, records ...eventsource.Record) (eventsource.StoreTransaction, error) {
+	tx, err := s.Store.NewTransaction(ctx, records...)
+	if err != nil {
+		return nil, err // nolint:wrapcheck
+	}
+
+	return &transaction{StoreTransaction: tx, metrics: s.metrics}, nil
+}
+
+func (s *store) Iter(ctx context.Context, opts ...eventsource.QueryOption) iter.Seq2[eventsource.Record, error] {
+	return eventsource.IterRecords(ctx, s.Store, opts...)
+}
+
+func (s *store) IterByAggregate(ctx context.Context, aggregateID string, opts ...eventsource.QueryOption) iter.Seq2[eventsource.Record, error] {
+	if iterStore, ok := s.Store.(eventsource.IterStore); ok {
+		return iterStore.IterByAggregate(ctx, aggregateID, opts...)
+	}
+
+	return func(yield func(eventsource.Record, error) bool) {
+		records, err := s.LoadByAggregate(ctx, aggregateID, opts...)
+		if err != nil {
+			yield(eventsource.Record{}, err)
+			return
+		}
+
+		for _, record := range records {
+			if !yield(record, nil) {
+				return
+			}
+		}
+	}
+}
+
+// Unwrap returns the measured store
+func (s *store) Unwrap() eventsource.Store {
+	return s.Store
+}
+
+type transaction struct {
+	eventsource.StoreTransaction
+	metrics Metrics
+}
+
+func (tx *transaction) Commit() error {
+	start := time.Now()
+	err := tx.StoreTransaction.Commit()
+	tx.metrics.CommitDuration(time.Since(start), err)
+
+	if err != nil {
+		return err // nolint:wrapcheck
+	}
+
+	counts := map[string]int{}
+	for _, record := range tx.GetRecords() {
+		counts[record.Type]++
+	}
+
+	for eventType, count := range counts {
+		tx.metrics.EventsSaved(eventType, count)
+	}
+
+	return nil
+}
+
+type repository struct {
+	eventsource.Repository
+	metrics Metrics
+}
+
+// WrapRepository returns a repository measuring the time taken to load aggregates and the
+// failed notifications. Wrap the store of the repository with WrapStore for the other metrics.
+func WrapRepository(wrapped eventsource.Repository, metrics Metrics) eventsource.Repository {
+	return &repository{Repository: wrapped, metrics: metrics}
+}
+
+func (repo *repository) Load(ctx context.Context, id string, aggr eventsource.Aggregate) (bool, error) {
+	start := time.Now()
+	deleted, err := repo.Repository.Load(ctx, id, aggr)
+	repo.metrics.LoadDuration(id, time.Since(start), err)
+
+	return deleted, err // nolint:wrapcheck
+}
+
+func (repo *repository) Save(ctx context.Context, events ...eventsource.Event) error {
+	return repo.notificationFailures(repo.Repository.Save(ctx, events...))
+}
+
+func (repo *repository) SaveWithExpectedVersion(ctx context.Context, aggregateID string, expectedVersion int64, events ...eventsource.Event) error {
+	return repo.notificationFailures(repo.Repository.SaveWithExpectedVersion(ctx, aggregateID, expectedVersion, events...))
+}
+
+// notificationFailures counts the failures of the notification error and passes on the error
+func (repo *repository) notificationFailures(err error) error {
+	var notificationErr *eventsource.NotificationError
+	if errors.As(err, &notificationErr) {
+		for _, failure := range notificationErr.Failures {
+			repo.metrics.NotificationFailed(fmt.Sprintf("%T", failure.Service), failure.Record.Type)
+		}
+	}
+
+	return err
+}
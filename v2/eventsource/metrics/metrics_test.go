@@ -0,0 +1,159 @@
+package metrics_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/SKF/go-eventsource/v2/eventsource"
+	"github.com/SKF/go-eventsource/v2/eventsource/metrics"
+	"github.com/SKF/go-eventsource/v2/eventsource/serializers/json"
+	"github.com/SKF/go-eventsource/v2/eventsource/stores/memorystore"
+)
+
+type OrderCreated struct {
+	*eventsource.BaseEvent
+}
+
+type OrderShipped struct {
+	*eventsource.BaseEvent
+}
+
+type order struct {
+	id string
+}
+
+func (o *order) On(context.Context, eventsource.Event) error {
+	return nil
+}
+
+func (o *order) SetAggregateID(id string) {
+	o.id = id
+}
+
+type failingService struct {
+	err error
+}
+
+func (s failingService) Send(record eventsource.Record) error {
+	return s.SendWithContext(context.Background(), record)
+}
+
+func (s failingService) SendWithContext(context.Context, eventsource.Record) error {
+	return s.err
+}
+
+type fakeMetrics struct {
+	saved         map[string]int
+	loaded        map[string]int
+	loads         []error
+	commits       []error
+	notifications []string
+}
+
+func newFakeMetrics() *fakeMetrics {
+	return &fakeMetrics{saved: map[string]int{}, loaded: map[string]int{}}
+}
+
+func (m *fakeMetrics) EventsSaved(eventType string, count int) {
+	m.saved[eventType] += count
+}
+
+func (m *fakeMetrics) RecordsLoaded(aggregateID string, count int) {
+	m.loaded[aggregateID] = count
+}
+
+func (m *fakeMetrics) LoadDuration(_ string, _ time.Duration, err error) {
+	m.loads = append(m.loads, err)
+}
+
+func (m *fakeMetrics) CommitDuration(_ time.Duration, err error) {
+	m.commits = append(m.commits, err)
+}
+
+func (m *fakeMetrics) NotificationFailed(service string, eventType string) {
+	m.notifications = append(m.notifications, service+" "+eventType)
+}
+
+func newEvent(aggregateID string) *eventsource.BaseEvent {
+	return &eventsource.BaseEvent{AggregateID: aggregateID}
+}
+
+func TestRepositoryAndStore(t *testing.T) {
+	t.Parallel()
+
+	m := newFakeMetrics()
+	store := metrics.WrapStore(memorystore.New(), m)
+	repo := metrics.WrapRepository(eventsource.NewRepository(store, json.NewSerializer(OrderCreated{}, OrderShipped{})), m)
+
+	ctx := context.TODO()
+	require.NoError(t, repo.Save(ctx, OrderCreated{newEvent("order")}, OrderShipped{newEvent("order")}, OrderCreated{newEvent("other")}))
+
+	_, err := repo.Load(ctx, "order", &order{})
+	require.NoError(t, err)
+
+	assert.Equal(t, map[string]int{"OrderCreated": 2, "OrderShipped": 1}, m.saved)
+	assert.Equal(t, map[string]int{"order": 2}, m.loaded)
+	assert.Equal(t, []error{nil}, m.loads)
+	assert.Equal(t, []error{nil}, m.commits)
+	assert.Empty(t, m.notifications)
+}
+
+func TestNotificationFailures(t *testing.T) {
+	t.Parallel()
+
+	m := newFakeMetrics()
+	sendErr := errors.New("unavailable")
+	repo := metrics.WrapRepository(eventsource.NewRepository(memorystore.New(), json.NewSerializer(OrderCreated{})), m)
+	repo.AddNotificationService(failingService{err: sendErr})
+
+	err := repo.Save(context.TODO(), OrderCreated{newEvent("order")})
+	require.ErrorIs(t, err, sendErr)
+
+	assert.Equal(t, []string{"metrics_test.failingService OrderCreated"}, m.notifications)
+}
+
+func TestPrometheus(t *testing.T) {
+	t.Parallel()
+
+	registry := prometheus.NewRegistry()
+	m, err := metrics.NewPrometheus(registry)
+	require.NoError(t, err)
+
+	m.EventsSaved("OrderCreated", 2)
+	m.RecordsLoaded("order", 3)
+	m.LoadDuration("order", time.Millisecond, nil)
+	m.CommitDuration(time.Millisecond, errors.New("failed"))
+	m.NotificationFailed("sns.Service", "OrderCreated")
+
+	families, err := registry.Gather()
+	require.NoError(t, err)
+
+	byName := map[string]float64{}
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			switch {
+			case metric.GetCounter() != nil:
+				byName[family.GetName()] += metric.GetCounter().GetValue()
+			case metric.GetHistogram() != nil:
+				byName[family.GetName()] += float64(metric.GetHistogram().GetSampleCount())
+			}
+		}
+	}
+
+	assert.Equal(t, map[string]float64{
+		"eventsource_events_saved_total":              2,
+		"eventsource_aggregate_records_loaded":        1,
+		"eventsource_aggregate_load_duration_seconds": 1,
+		"eventsource_commit_duration_seconds":         1,
+		"eventsource_notification_failures_total":     1,
+	}, byName)
+
+	_, err = metrics.NewPrometheus(registry)
+	require.Error(t, err, "The collectors are already registered")
+}
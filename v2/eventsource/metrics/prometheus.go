@@ -0,0 +1,89 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const namespace = "eventsource"
+
+type prometheusMetrics struct {
+	eventsSaved          *prometheus.CounterVec
+	recordsLoaded        prometheus.Histogram
+	loadDuration         *prometheus.HistogramVec
+	commitDuration       *prometheus.HistogramVec
+	notificationFailures *prometheus.CounterVec
+}
+
+// NewPrometheus returns metrics registered with the registerer. The aggregate ID isn't used
+// as a label, as it would give every aggregate its own series.
+func NewPrometheus(registerer prometheus.Registerer) (Metrics, error) {
+	m := &prometheusMetrics{
+		eventsSaved: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "events_saved_total",
+			Help:      "Number of events committed to the store.",
+		}, []string{"type"}),
+		recordsLoaded: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "aggregate_records_loaded",
+			Help:      "Number of records loaded to replay an aggregate.",
+			Buckets:   prometheus.ExponentialBuckets(1, 4, 8), // nolint:mnd
+		}),
+		loadDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "aggregate_load_duration_seconds",
+			Help:      "Time taken to load an aggregate.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"result"}),
+		commitDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "commit_duration_seconds",
+			Help:      "Time taken to commit a transaction to the store.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"result"}),
+		notificationFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "notification_failures_total",
+			Help:      "Number of records a notification service failed to send.",
+		}, []string{"service", "type"}),
+	}
+
+	for _, collector := range []prometheus.Collector{m.eventsSaved, m.recordsLoaded, m.loadDuration, m.commitDuration, m.notificationFailures} {
+		if err := registerer.Register(collector); err != nil {
+			return nil, errors.Wrap(err, "failed to register collector")
+		}
+	}
+
+	return m, nil
+}
+
+func (m *prometheusMetrics) EventsSaved(eventType string, count int) {
+	m.eventsSaved.WithLabelValues(eventType).Add(float64(count))
+}
+
+func (m *prometheusMetrics) RecordsLoaded(_ string, count int) {
+	m.recordsLoaded.Observe(float64(count))
+}
+
+func (m *prometheusMetrics) LoadDuration(_ string, duration time.Duration, err error) {
+	m.loadDuration.WithLabelValues(result(err)).Observe(duration.Seconds())
+}
+
+func (m *prometheusMetrics) CommitDuration(duration time.Duration, err error) {
+	m.commitDuration.WithLabelValues(result(err)).Observe(duration.Seconds())
+}
+
+func (m *prometheusMetrics) NotificationFailed(service string, eventType string) {
+	m.notificationFailures.WithLabelValues(service, eventType).Inc()
+}
+
+func result(err error) string {
+	if err != nil {
+		return "error"
+	}
+
+	return "ok"
+}
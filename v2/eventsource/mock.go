@@ -2,6 +2,7 @@ package eventsource
 
 import (
 	"context"
+	"iter"
 
 	"github.com/stretchr/testify/mock"
 )
@@ -129,6 +130,30 @@ func (o AggregatorMock) On(ctx context.Context, event Event) error {
 // SetAggregateID is not implemented
 func (o AggregatorMock) SetAggregateID(id string) {}
 
+// SnapshotStoreMock is a mock
+type SnapshotStoreMock struct {
+	*mock.Mock
+}
+
+// CreateSnapshotStoreMock returns a snapshot store mock
+func CreateSnapshotStoreMock() *SnapshotStoreMock {
+	return &SnapshotStoreMock{
+		Mock: &mock.Mock{},
+	}
+}
+
+// SaveSnapshot is a mock
+func (o SnapshotStoreMock) SaveSnapshot(ctx context.Context, snapshot Snapshot) error {
+	args := o.Called(ctx, snapshot)
+	return args.Error(0)
+}
+
+// LoadSnapshot is a mock
+func (o SnapshotStoreMock) LoadSnapshot(ctx context.Context, aggregateID string) (Snapshot, error) {
+	args := o.Called(ctx, aggregateID)
+	return args.Get(0).(Snapshot), args.Error(1)
+}
+
 // RepositoryMock is a mock
 type RepositoryMock struct {
 	*mock.Mock
@@ -159,6 +184,12 @@ func (r RepositoryMock) SaveTransaction(ctx context.Context, events ...Event) (S
 	return args.Get(0).(StoreTransaction), args.Error(1)
 }
 
+// SaveWithExpectedVersion is a mock
+func (r RepositoryMock) SaveWithExpectedVersion(ctx context.Context, aggregateID string, expectedVersion int64, events ...Event) error {
+	args := r.Called(ctx, aggregateID, expectedVersion, events)
+	return args.Error(0)
+}
+
 // Load is a mock
 func (r RepositoryMock) Load(ctx context.Context, id string, aggr Aggregate) (deleted bool, err error) {
 	args := r.Called(ctx, id, aggr)
@@ -177,6 +208,18 @@ func (r RepositoryMock) LoadEvents(ctx context.Context, opts ...QueryOption) ([]
 	return args.Get(0).([]Event), args.Error(1)
 }
 
+// Subscribe is a mock
+func (r RepositoryMock) Subscribe(ctx context.Context, fromSequenceID string, handler EventHandler, opts ...SubscribeOption) error {
+	args := r.Called(ctx, fromSequenceID, handler, opts)
+	return args.Error(0)
+}
+
+// IterEvents is a mock
+func (r RepositoryMock) IterEvents(ctx context.Context, opts ...QueryOption) iter.Seq2[Event, error] {
+	args := r.Called(ctx, opts)
+	return args.Get(0).(iter.Seq2[Event, error])
+}
+
 // GetEventsBySequenceID is a mock
 func (r RepositoryMock) GetEventsBySequenceID(ctx context.Context, sequenceID string, opts ...QueryOption) ([]Event, error) {
 	args := r.Called(ctx, sequenceID, opts)
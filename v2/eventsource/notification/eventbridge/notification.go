@@ -0,0 +1,102 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/eventbridge"
+	"github.com/aws/aws-sdk-go-v2/service/eventbridge/types"
+
+	"github.com/SKF/go-eventsource/v2/eventsource"
+)
+
+// Client is the part of the EventBridge API used for publishing, implemented by *eventbridge.Client
+type Client interface {
+	PutEvents(ctx context.Context, params *eventbridge.PutEventsInput, optFns ...func(*eventbridge.Options)) (*eventbridge.PutEventsOutput, error)
+}
+
+// maxBatchSize is the number of events EventBridge accepts in one PutEvents call
+const maxBatchSize = 10
+
+type eventBridgeNotification struct {
+	client       Client
+	eventBusName string
+	source       string
+}
+
+// NewWithClient publishes the records to the given event bus, using the provided EventBridge
+// client. The events get the source, the record type as detail type and the record as detail.
+func NewWithClient(eventBusName, source string, client Client) eventsource.NotificationService {
+	return &eventBridgeNotification{client: client, eventBusName: eventBusName, source: source}
+}
+
+func (eb *eventBridgeNotification) Send(record eventsource.Record) error {
+	return eb.SendWithContext(context.Background(), record)
+}
+
+func (eb *eventBridgeNotification) SendWithContext(ctx context.Context, record eventsource.Record) error {
+	return eb.SendBatchWithContext(ctx, []eventsource.Record{record})
+}
+
+// SendBatchWithContext publishes the records in PutEvents calls of up to 10 records,
+// returning an *eventsource.NotificationError with the records that failed.
+func (eb *eventBridgeNotification) SendBatchWithContext(ctx context.Context, records []eventsource.Record) error {
+	return eventsource.SendInBatches(ctx, eb, records, maxBatchSize, false, eb.putEvents)
+}
+
+// putEvents publishes up to 10 records in one PutEvents call
+func (eb *eventBridgeNotification) putEvents(ctx context.Context, records []eventsource.Record) error {
+	entries := make([]types.PutEventsRequestEntry, len(records))
+
+	for i, record := range records {
+		data, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+
+		// The detail type is the event type without its schema version, which is
+		// left in the type of the record in the detail
+		eventType, _ := eventsource.ParseRecordType(record.Type)
+
+		entries[i] = types.PutEventsRequestEntry{
+			EventBusName: &eb.eventBusName,
+			Source:       &eb.source,
+			DetailType:   aws.String(eventType),
+			Detail:       aws.String(string(data)),
+			Time:         aws.Time(time.Unix(0, record.Timestamp)),
+		}
+	}
+
+	output, err := eb.client.PutEvents(ctx, &eventbridge.PutEventsInput{Entries: entries})
+	if err != nil {
+		return err
+	}
+
+	if output.FailedEntryCount == 0 {
+		return nil
+	}
+
+	// The result entries are in the same order as the request entries
+	if len(output.Entries) != len(records) {
+		return fmt.Errorf("%d of %d events failed, but the response has %d entries", output.FailedEntryCount, len(records), len(output.Entries))
+	}
+
+	var failures []eventsource.NotificationFailure
+
+	for i, entry := range output.Entries {
+		if entry.ErrorCode == nil {
+			continue
+		}
+
+		failures = append(failures, eventsource.NotificationFailure{
+			Service: eb,
+			Record:  records[i],
+			Err:     fmt.Errorf("%s: %s", aws.ToString(entry.ErrorCode), aws.ToString(entry.ErrorMessage)),
+		})
+	}
+
+	return &eventsource.NotificationError{Failures: failures}
+}
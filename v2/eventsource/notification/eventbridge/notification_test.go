@@ -0,0 +1,105 @@
+package notification_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/eventbridge"
+	"github.com/aws/aws-sdk-go-v2/service/eventbridge/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/SKF/go-eventsource/v2/eventsource"
+	notification "github.com/SKF/go-eventsource/v2/eventsource/notification/eventbridge"
+)
+
+type fakeClient struct {
+	inputs []*eventbridge.PutEventsInput
+	failed map[int]string
+}
+
+func (f *fakeClient) PutEvents(_ context.Context, params *eventbridge.PutEventsInput, _ ...func(*eventbridge.Options)) (*eventbridge.PutEventsOutput, error) {
+	f.inputs = append(f.inputs, params)
+
+	output := &eventbridge.PutEventsOutput{}
+
+	for i := range params.Entries {
+		if code, ok := f.failed[i]; ok {
+			output.Entries = append(output.Entries, types.PutEventsResultEntry{ErrorCode: aws.String(code), ErrorMessage: aws.String("failed")})
+			output.FailedEntryCount++
+		} else {
+			output.Entries = append(output.Entries, types.PutEventsResultEntry{EventId: aws.String("id")})
+		}
+	}
+
+	return output, nil
+}
+
+var record = eventsource.Record{
+	AggregateID: "aggregate",
+	SequenceID:  "sequence",
+	Type:        "Created",
+	UserID:      "user",
+	Timestamp:   1700000000000000000,
+}
+
+func TestSendWithContext(t *testing.T) {
+	t.Parallel()
+
+	client := &fakeClient{}
+	service := notification.NewWithClient("events", "com.skf.orders", client)
+
+	require.NoError(t, service.SendWithContext(context.TODO(), record))
+	require.Len(t, client.inputs, 1)
+	require.Len(t, client.inputs[0].Entries, 1)
+
+	entry := client.inputs[0].Entries[0]
+	assert.Equal(t, "events", aws.ToString(entry.EventBusName))
+	assert.Equal(t, "com.skf.orders", aws.ToString(entry.Source))
+	assert.Equal(t, "Created", aws.ToString(entry.DetailType))
+	assert.Equal(t, record.Timestamp, entry.Time.UnixNano())
+
+	var detail eventsource.Record
+	require.NoError(t, json.Unmarshal([]byte(aws.ToString(entry.Detail)), &detail))
+	assert.Equal(t, record, detail)
+}
+
+func TestSendWithContext_SchemaVersion(t *testing.T) {
+	t.Parallel()
+
+	client := &fakeClient{}
+	service := notification.NewWithClient("events", "com.skf.orders", client)
+
+	versioned := record
+	versioned.Type = eventsource.RecordType("Created", 2)
+
+	require.NoError(t, service.SendWithContext(context.TODO(), versioned))
+	require.Len(t, client.inputs, 1)
+
+	entry := client.inputs[0].Entries[0]
+	assert.Equal(t, "Created", aws.ToString(entry.DetailType))
+	assert.Contains(t, aws.ToString(entry.Detail), `"type":"Created/v2"`)
+}
+
+func TestSendBatchWithContext_PartialFailure(t *testing.T) {
+	t.Parallel()
+
+	other := record
+	other.SequenceID = "other"
+
+	client := &fakeClient{failed: map[int]string{1: "ThrottlingException"}}
+	service := notification.NewWithClient("events", "com.skf.orders", client)
+
+	batchService, ok := service.(eventsource.BatchNotificationService)
+	require.True(t, ok)
+
+	err := batchService.SendBatchWithContext(context.TODO(), []eventsource.Record{record, other})
+
+	var notificationErr *eventsource.NotificationError
+	require.ErrorAs(t, err, &notificationErr)
+	require.Len(t, notificationErr.Failures, 1)
+	assert.Equal(t, other, notificationErr.Failures[0].Record)
+	assert.EqualError(t, notificationErr.Failures[0].Err, "ThrottlingException: failed")
+}
@@ -0,0 +1,80 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+
+	"github.com/SKF/go-eventsource/v2/eventsource"
+)
+
+// Header is a Kafka message header
+type Header struct {
+	Key   string
+	Value []byte
+}
+
+// Message is a Kafka message produced for a record
+type Message struct {
+	Topic   string
+	Key     []byte
+	Value   []byte
+	Headers []Header
+}
+
+// Producer writes messages to Kafka, like an adapter for the Kafka client in use.
+// The messages are given in order and should keep their order within each partition.
+type Producer interface {
+	Produce(ctx context.Context, messages ...Message) error
+}
+
+type kafkaNotification struct {
+	producer Producer
+	topic    string
+}
+
+// NewWithProducer produces the records to the given topic, using the provided producer.
+// The aggregate ID is used as message key, so the records of each aggregate end up in the
+// same partition and are received in order.
+func NewWithProducer(topic string, producer Producer) eventsource.NotificationService {
+	return &kafkaNotification{producer: producer, topic: topic}
+}
+
+func (kn *kafkaNotification) Send(record eventsource.Record) error {
+	return kn.SendWithContext(context.Background(), record)
+}
+
+func (kn *kafkaNotification) SendWithContext(ctx context.Context, record eventsource.Record) error {
+	return kn.SendBatchWithContext(ctx, []eventsource.Record{record})
+}
+
+// SendBatchWithContext produces the records in one call to the producer
+func (kn *kafkaNotification) SendBatchWithContext(ctx context.Context, records []eventsource.Record) error {
+	messages := make([]Message, len(records))
+
+	for i, record := range records {
+		data, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+
+		eventType, version := eventsource.ParseRecordType(record.Type)
+
+		messages[i] = Message{
+			Topic: kn.topic,
+			Key:   []byte(record.AggregateID),
+			Value: data,
+			Headers: []Header{
+				{Key: "type", Value: []byte(eventType)},
+				{Key: "sequenceId", Value: []byte(record.SequenceID)},
+				{Key: "userId", Value: []byte(record.UserID)},
+			},
+		}
+
+		if version > 1 {
+			messages[i].Headers = append(messages[i].Headers, Header{Key: "schemaVersion", Value: []byte(strconv.Itoa(version))})
+		}
+	}
+
+	return kn.producer.Produce(ctx, messages...)
+}
@@ -0,0 +1,106 @@
+package notification_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/SKF/go-eventsource/v2/eventsource"
+	notification "github.com/SKF/go-eventsource/v2/eventsource/notification/kafka"
+)
+
+type memoryProducer struct {
+	messages []notification.Message
+	err      error
+}
+
+func (p *memoryProducer) Produce(_ context.Context, messages ...notification.Message) error {
+	if p.err != nil {
+		return p.err
+	}
+
+	p.messages = append(p.messages, messages...)
+
+	return nil
+}
+
+var record = eventsource.Record{
+	AggregateID: "aggregate",
+	SequenceID:  "sequence",
+	Type:        "Created",
+	UserID:      "user",
+}
+
+func TestSendWithContext(t *testing.T) {
+	t.Parallel()
+
+	producer := &memoryProducer{}
+	service := notification.NewWithProducer("events", producer)
+
+	require.NoError(t, service.SendWithContext(context.TODO(), record))
+	require.Len(t, producer.messages, 1)
+
+	message := producer.messages[0]
+	assert.Equal(t, "events", message.Topic)
+	assert.Equal(t, []byte("aggregate"), message.Key)
+	assert.Equal(t, []notification.Header{
+		{Key: "type", Value: []byte("Created")},
+		{Key: "sequenceId", Value: []byte("sequence")},
+		{Key: "userId", Value: []byte("user")},
+	}, message.Headers)
+
+	var value eventsource.Record
+	require.NoError(t, json.Unmarshal(message.Value, &value))
+	assert.Equal(t, record, value)
+}
+
+func TestSendWithContext_SchemaVersion(t *testing.T) {
+	t.Parallel()
+
+	producer := &memoryProducer{}
+	service := notification.NewWithProducer("events", producer)
+
+	versioned := record
+	versioned.Type = eventsource.RecordType("Created", 2)
+
+	require.NoError(t, service.SendWithContext(context.TODO(), versioned))
+	require.Len(t, producer.messages, 1)
+
+	assert.Equal(t, []notification.Header{
+		{Key: "type", Value: []byte("Created")},
+		{Key: "sequenceId", Value: []byte("sequence")},
+		{Key: "userId", Value: []byte("user")},
+		{Key: "schemaVersion", Value: []byte("2")},
+	}, producer.messages[0].Headers)
+}
+
+func TestSendBatchWithContext(t *testing.T) {
+	t.Parallel()
+
+	other := record
+	other.AggregateID = "other"
+
+	producer := &memoryProducer{}
+	service := notification.NewWithProducer("events", producer)
+
+	batchService, ok := service.(eventsource.BatchNotificationService)
+	require.True(t, ok)
+
+	require.NoError(t, batchService.SendBatchWithContext(context.TODO(), []eventsource.Record{record, other}))
+	require.Len(t, producer.messages, 2)
+	assert.Equal(t, []byte("aggregate"), producer.messages[0].Key)
+	assert.Equal(t, []byte("other"), producer.messages[1].Key)
+}
+
+func TestSendWithContext_ProducerError(t *testing.T) {
+	t.Parallel()
+
+	producer := &memoryProducer{err: errors.New("broker unavailable")}
+	service := notification.NewWithProducer("events", producer)
+
+	assert.ErrorIs(t, service.SendWithContext(context.TODO(), record), producer.err)
+}
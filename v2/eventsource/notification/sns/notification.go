@@ -3,22 +3,99 @@ package notification
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/sns"
 	"github.com/aws/aws-sdk-go-v2/service/sns/types"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
 
 	"github.com/SKF/go-eventsource/v2/eventsource"
 )
 
+// Client is the part of the SNS API used for publishing, implemented by *sns.Client
+type Client interface {
+	Publish(ctx context.Context, params *sns.PublishInput, optFns ...func(*sns.Options)) (*sns.PublishOutput, error)
+	PublishBatch(ctx context.Context, params *sns.PublishBatchInput, optFns ...func(*sns.Options)) (*sns.PublishBatchOutput, error)
+}
+
+// AttributeFunc returns the value of a message attribute for the record, or an
+// empty string to leave out the attribute
+type AttributeFunc func(record eventsource.Record) string
+
+const (
+	// maxAttributes is the number of message attributes SNS accepts for a message
+	maxAttributes = 10
+	// maxBatchSize is the number of messages SNS accepts in one PublishBatch call
+	maxBatchSize = 10
+)
+
 type snsNotification struct {
-	sns      *sns.Client
-	topicARN string
+	sns        Client
+	topicARN   string
+	fifo       bool
+	attributes map[string]AttributeFunc
+	metadata   []string
+	propagator propagation.TextMapPropagator
+}
+
+// Option is used for configuring the notification service
+type Option func(sn *snsNotification)
+
+// WithFIFO is used for FIFO topics. The records are published with the aggregate ID as
+// message group ID, so the records of each aggregate are received in order, and with the
+// sequence ID as message deduplication ID.
+func WithFIFO() Option {
+	return func(sn *snsNotification) {
+		sn.fifo = true
+	}
+}
+
+// WithAttribute adds a string message attribute, besides SKF.Hierarchy.EventType and
+// SKF.Hierarchy.Aggregate, to the published records. The event type is published without
+// its schema version, which is published as SKF.Hierarchy.SchemaVersion when above 1.
+func WithAttribute(name string, value AttributeFunc) Option {
+	return func(sn *snsNotification) {
+		sn.attributes[name] = value
+	}
+}
+
+// WithMetadataAttributes adds the metadata of the records with the keys, besides the
+// correlation and causation IDs, to the message attributes
+func WithMetadataAttributes(keys ...string) Option {
+	return func(sn *snsNotification) {
+		sn.metadata = append(sn.metadata, keys...)
+	}
+}
+
+// WithTracePropagation injects the trace context of the context passed to SendWithContext
+// into the message attributes, like traceparent for W3C trace context, so consumers can
+// continue the trace. The global propagator of OpenTelemetry is used.
+func WithTracePropagation() Option {
+	return func(sn *snsNotification) {
+		sn.propagator = otel.GetTextMapPropagator()
+	}
 }
 
 // New connection to the given SNS topic ARN, using the provided SNS client.
-func NewWithClient(topicARN string, client *sns.Client) eventsource.NotificationService {
-	return &snsNotification{topicARN: topicARN, sns: client}
+func NewWithClient(topicARN string, client Client, opts ...Option) eventsource.NotificationService {
+	sn := &snsNotification{
+		topicARN:   topicARN,
+		sns:        client,
+		attributes: map[string]AttributeFunc{},
+		metadata:   []string{eventsource.CorrelationIDKey, eventsource.CausationIDKey},
+	}
+
+	for _, opt := range opts {
+		opt(sn)
+	}
+
+	return sn
 }
 
 func (sn *snsNotification) Send(record eventsource.Record) error {
@@ -32,21 +109,142 @@ func (sn *snsNotification) SendWithContext(ctx context.Context, record eventsour
 	}
 
 	input := sns.PublishInput{
-		TopicArn: &sn.topicARN,
-		Message:  aws.String(string(data)),
-		MessageAttributes: map[string]types.MessageAttributeValue{
-			"SKF.Hierarchy.EventType": {
-				DataType:    aws.String("String"),
-				StringValue: aws.String(record.Type),
-			},
-			"SKF.Hierarchy.Aggregate": {
-				DataType:    aws.String("String"),
-				StringValue: aws.String(record.AggregateID),
-			},
-		},
+		TopicArn:          &sn.topicARN,
+		Message:           aws.String(string(data)),
+		MessageAttributes: sn.messageAttributes(ctx, record),
+	}
+
+	if sn.fifo {
+		input.MessageGroupId = aws.String(record.AggregateID)
+		input.MessageDeduplicationId = aws.String(record.SequenceID)
 	}
 
 	_, err = sn.sns.Publish(ctx, &input)
 
 	return err
 }
+
+// SendBatchWithContext publishes the records in PublishBatch calls of up to 10 records,
+// returning an *eventsource.NotificationError with the records that failed. For FIFO
+// topics the records after a failed call aren't published.
+func (sn *snsNotification) SendBatchWithContext(ctx context.Context, records []eventsource.Record) error {
+	return eventsource.SendInBatches(ctx, sn, records, maxBatchSize, sn.fifo, sn.publishBatch)
+}
+
+// Ordered reports if the records have to be published in order, which they have for FIFO topics
+func (sn *snsNotification) Ordered() bool {
+	return sn.fifo
+}
+
+// publishBatch publishes up to 10 records in one PublishBatch call
+func (sn *snsNotification) publishBatch(ctx context.Context, records []eventsource.Record) error {
+	entries := make([]types.PublishBatchRequestEntry, len(records))
+
+	for i, record := range records {
+		data, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+
+		entries[i] = types.PublishBatchRequestEntry{
+			Id:                aws.String(strconv.Itoa(i)),
+			Message:           aws.String(string(data)),
+			MessageAttributes: sn.messageAttributes(ctx, record),
+		}
+
+		if sn.fifo {
+			entries[i].MessageGroupId = aws.String(record.AggregateID)
+			entries[i].MessageDeduplicationId = aws.String(record.SequenceID)
+		}
+	}
+
+	output, err := sn.sns.PublishBatch(ctx, &sns.PublishBatchInput{
+		TopicArn:                   &sn.topicARN,
+		PublishBatchRequestEntries: entries,
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(output.Failed) == 0 {
+		return nil
+	}
+
+	failures := make([]eventsource.NotificationFailure, 0, len(output.Failed))
+
+	for _, failed := range output.Failed {
+		i, err := strconv.Atoi(aws.ToString(failed.Id))
+		if err != nil || i < 0 || i >= len(records) {
+			return fmt.Errorf("unexpected id in publish batch response: %s", aws.ToString(failed.Id))
+		}
+
+		failures = append(failures, eventsource.NotificationFailure{
+			Service: sn,
+			Record:  records[i],
+			Err:     fmt.Errorf("%s: %s", aws.ToString(failed.Code), aws.ToString(failed.Message)),
+		})
+	}
+
+	return &eventsource.NotificationError{Failures: failures}
+}
+
+// messageAttributes returns the attributes of the record, which are the attributes of the
+// service, the trace context and the allowed metadata of the record, taking precedence in
+// that order. Names SNS doesn't accept are left out, as are attributes beyond the maximum.
+func (sn *snsNotification) messageAttributes(ctx context.Context, record eventsource.Record) map[string]types.MessageAttributeValue {
+	attributes := map[string]types.MessageAttributeValue{}
+
+	add := func(name, value string) {
+		if _, ok := attributes[name]; ok || value == "" || len(attributes) >= maxAttributes || !validAttributeName(name) {
+			return
+		}
+
+		attributes[name] = types.MessageAttributeValue{
+			DataType:    aws.String("String"),
+			StringValue: aws.String(value),
+		}
+	}
+
+	eventType, version := eventsource.ParseRecordType(record.Type)
+	add("SKF.Hierarchy.EventType", eventType)
+	add("SKF.Hierarchy.Aggregate", record.AggregateID)
+
+	if version > 1 {
+		add("SKF.Hierarchy.SchemaVersion", strconv.Itoa(version))
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(sn.attributes)) {
+		add(name, sn.attributes[name](record))
+	}
+
+	if sn.propagator != nil {
+		carrier := propagation.MapCarrier{}
+		sn.propagator.Inject(ctx, carrier)
+
+		for _, name := range slices.Sorted(maps.Keys(carrier)) {
+			add(name, carrier[name])
+		}
+	}
+
+	for _, key := range sn.metadata {
+		add(key, record.Metadata[key])
+	}
+
+	return attributes
+}
+
+// validAttributeName reports if SNS accepts the name of a message attribute
+func validAttributeName(name string) bool {
+	if name == "" || len(name) > 256 || strings.HasPrefix(name, ".") || strings.HasSuffix(name, ".") || strings.Contains(name, "..") {
+		return false
+	}
+
+	lower := strings.ToLower(name)
+	if strings.HasPrefix(lower, "aws.") || strings.HasPrefix(lower, "amazon.") {
+		return false
+	}
+
+	return strings.IndexFunc(name, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-' || r == '.')
+	}) < 0
+}
@@ -0,0 +1,245 @@
+package notification_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/aws/aws-sdk-go-v2/service/sns/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+
+	"github.com/SKF/go-eventsource/v2/eventsource"
+	notification "github.com/SKF/go-eventsource/v2/eventsource/notification/sns"
+)
+
+const topicARN = "arn:aws:sns:eu-west-1:123456789012:events.fifo"
+
+type fakeClient struct {
+	published []*sns.PublishInput
+	batches   []*sns.PublishBatchInput
+	failed    []types.BatchResultErrorEntry
+}
+
+func (f *fakeClient) Publish(_ context.Context, params *sns.PublishInput, _ ...func(*sns.Options)) (*sns.PublishOutput, error) {
+	f.published = append(f.published, params)
+
+	return &sns.PublishOutput{}, nil
+}
+
+func (f *fakeClient) PublishBatch(_ context.Context, params *sns.PublishBatchInput, _ ...func(*sns.Options)) (*sns.PublishBatchOutput, error) {
+	f.batches = append(f.batches, params)
+
+	return &sns.PublishBatchOutput{Failed: f.failed}, nil
+}
+
+var record = eventsource.Record{
+	AggregateID: "aggregate",
+	SequenceID:  "sequence",
+	Type:        "Created",
+	UserID:      "user",
+}
+
+func TestSendWithContext(t *testing.T) {
+	t.Parallel()
+
+	client := &fakeClient{}
+	service := notification.NewWithClient(topicARN, client)
+
+	require.NoError(t, service.SendWithContext(context.TODO(), record))
+	require.Len(t, client.published, 1)
+
+	input := client.published[0]
+	assert.Equal(t, topicARN, aws.ToString(input.TopicArn))
+	assert.Nil(t, input.MessageGroupId)
+	assert.Nil(t, input.MessageDeduplicationId)
+	assert.Equal(t, "Created", aws.ToString(input.MessageAttributes["SKF.Hierarchy.EventType"].StringValue))
+	assert.Equal(t, "aggregate", aws.ToString(input.MessageAttributes["SKF.Hierarchy.Aggregate"].StringValue))
+}
+
+func TestSendWithContext_SchemaVersion(t *testing.T) {
+	t.Parallel()
+
+	client := &fakeClient{}
+	service := notification.NewWithClient(topicARN, client)
+
+	versioned := record
+	versioned.Type = eventsource.RecordType("Created", 2)
+
+	require.NoError(t, service.SendWithContext(context.TODO(), versioned))
+	require.Len(t, client.published, 1)
+
+	attributes := client.published[0].MessageAttributes
+	assert.Equal(t, "Created", aws.ToString(attributes["SKF.Hierarchy.EventType"].StringValue))
+	assert.Equal(t, "2", aws.ToString(attributes["SKF.Hierarchy.SchemaVersion"].StringValue))
+}
+
+func TestSendWithContext_FIFOAndAttributes(t *testing.T) {
+	t.Parallel()
+
+	client := &fakeClient{}
+	service := notification.NewWithClient(topicARN, client,
+		notification.WithFIFO(),
+		notification.WithAttribute("SKF.Hierarchy.User", func(r eventsource.Record) string { return r.UserID }),
+		notification.WithAttribute("Empty", func(eventsource.Record) string { return "" }),
+	)
+
+	require.NoError(t, service.SendWithContext(context.TODO(), record))
+	require.Len(t, client.published, 1)
+
+	input := client.published[0]
+	assert.Equal(t, "aggregate", aws.ToString(input.MessageGroupId))
+	assert.Equal(t, "sequence", aws.ToString(input.MessageDeduplicationId))
+	assert.Equal(t, "user", aws.ToString(input.MessageAttributes["SKF.Hierarchy.User"].StringValue))
+	assert.Equal(t, "String", aws.ToString(input.MessageAttributes["SKF.Hierarchy.User"].DataType))
+	assert.NotContains(t, input.MessageAttributes, "Empty")
+	assert.Len(t, input.MessageAttributes, 3)
+}
+
+func TestSendWithContext_Metadata(t *testing.T) {
+	t.Parallel()
+
+	client := &fakeClient{}
+	service := notification.NewWithClient(topicARN, client, notification.WithMetadataAttributes("tenant", "AWS.Forbidden", "bad name"))
+
+	withMetadata := record
+	withMetadata.Metadata = eventsource.Metadata{
+		eventsource.CorrelationIDKey: "correlation",
+		"SKF.Hierarchy.EventType":    "Overridden",
+		"tenant":                     "acme",
+		"AWS.Forbidden":              "value",
+		"bad name":                   "value",
+		"other":                      "value",
+	}
+
+	require.NoError(t, service.SendWithContext(context.TODO(), withMetadata))
+	require.Len(t, client.published, 1)
+
+	input := client.published[0]
+	assert.Equal(t, "correlation", aws.ToString(input.MessageAttributes["correlationId"].StringValue))
+	assert.Equal(t, "acme", aws.ToString(input.MessageAttributes["tenant"].StringValue))
+	assert.Equal(t, "Created", aws.ToString(input.MessageAttributes["SKF.Hierarchy.EventType"].StringValue))
+	assert.NotContains(t, input.MessageAttributes, "other", "Only allowed metadata is forwarded")
+	assert.NotContains(t, input.MessageAttributes, "AWS.Forbidden")
+	assert.NotContains(t, input.MessageAttributes, "bad name")
+	assert.Len(t, input.MessageAttributes, 4)
+	assert.Contains(t, aws.ToString(input.Message), `"metadata":{`)
+}
+
+func TestSendWithContext_AttributeLimit(t *testing.T) {
+	t.Parallel()
+
+	keys := make([]string, 12)
+	withMetadata := record
+	withMetadata.Metadata = eventsource.Metadata{}
+
+	for i := range keys {
+		keys[i] = fmt.Sprintf("key%02d", i)
+		withMetadata.Metadata[keys[i]] = "value"
+	}
+
+	client := &fakeClient{}
+	service := notification.NewWithClient(topicARN, client, notification.WithMetadataAttributes(keys...))
+
+	require.NoError(t, service.SendWithContext(context.TODO(), withMetadata))
+	require.Len(t, client.published, 1)
+
+	attributes := client.published[0].MessageAttributes
+	assert.Len(t, attributes, 10)
+	assert.Contains(t, attributes, "SKF.Hierarchy.EventType", "The attributes of the service take precedence")
+	assert.Contains(t, attributes, "key07")
+	assert.NotContains(t, attributes, "key08")
+}
+
+func TestSendWithContext_TracePropagation(t *testing.T) { // nolint:paralleltest
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	client := &fakeClient{}
+	service := notification.NewWithClient(topicARN, client, notification.WithTracePropagation())
+
+	ctx, span := sdktrace.NewTracerProvider().Tracer("test").Start(context.TODO(), "test")
+	defer span.End()
+
+	require.NoError(t, service.SendWithContext(ctx, record))
+	require.Len(t, client.published, 1)
+
+	traceparent := aws.ToString(client.published[0].MessageAttributes["traceparent"].StringValue)
+	assert.Contains(t, traceparent, span.SpanContext().TraceID().String())
+	assert.Contains(t, traceparent, span.SpanContext().SpanID().String())
+}
+
+func TestSendBatchWithContext(t *testing.T) {
+	t.Parallel()
+
+	other := record
+	other.SequenceID = "other"
+
+	client := &fakeClient{
+		failed: []types.BatchResultErrorEntry{{Id: aws.String("1"), Code: aws.String("Throttled"), Message: aws.String("slow down")}},
+	}
+	service := notification.NewWithClient(topicARN, client, notification.WithFIFO())
+
+	batchService, ok := service.(eventsource.BatchNotificationService)
+	require.True(t, ok)
+
+	err := batchService.SendBatchWithContext(context.TODO(), []eventsource.Record{record, other})
+
+	var notificationErr *eventsource.NotificationError
+	require.ErrorAs(t, err, &notificationErr)
+	require.Len(t, notificationErr.Failures, 1)
+	assert.Equal(t, other, notificationErr.Failures[0].Record)
+	assert.EqualError(t, notificationErr.Failures[0].Err, "Throttled: slow down")
+
+	require.Len(t, client.batches, 1)
+
+	entries := client.batches[0].PublishBatchRequestEntries
+	require.Len(t, entries, 2)
+	assert.Equal(t, "aggregate", aws.ToString(entries[1].MessageGroupId))
+	assert.Equal(t, "other", aws.ToString(entries[1].MessageDeduplicationId))
+}
+
+func TestSendBatchWithContext_SplitsBatches(t *testing.T) {
+	t.Parallel()
+
+	records := make([]eventsource.Record, 25)
+	for i := range records {
+		records[i] = record
+		records[i].SequenceID = fmt.Sprintf("%02d", i)
+	}
+
+	for _, test := range []struct {
+		name     string
+		opts     []notification.Option
+		batches  int
+		failures int
+	}{
+		{name: "standard", batches: 3, failures: 3},
+		{name: "FIFO stops at the first failed batch", opts: []notification.Option{notification.WithFIFO()}, batches: 1, failures: 16},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			client := &fakeClient{
+				failed: []types.BatchResultErrorEntry{{Id: aws.String("1"), Code: aws.String("Throttled"), Message: aws.String("slow down")}},
+			}
+			service := notification.NewWithClient(topicARN, client, test.opts...)
+
+			err := service.(eventsource.BatchNotificationService).SendBatchWithContext(context.TODO(), records) // nolint:forcetypeassert
+
+			var notificationErr *eventsource.NotificationError
+			require.ErrorAs(t, err, &notificationErr)
+			assert.Len(t, notificationErr.Failures, test.failures)
+			assert.Equal(t, "01", notificationErr.Failures[0].Record.SequenceID)
+			require.Len(t, client.batches, test.batches)
+
+			for _, batch := range client.batches {
+				assert.LessOrEqual(t, len(batch.PublishBatchRequestEntries), 10)
+			}
+		})
+	}
+}
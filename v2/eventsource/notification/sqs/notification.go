@@ -0,0 +1,192 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+
+	"github.com/SKF/go-eventsource/v2/eventsource"
+)
+
+// Client is the part of the SQS API used for sending, implemented by *sqs.Client
+type Client interface {
+	SendMessage(ctx context.Context, params *sqs.SendMessageInput, optFns ...func(*sqs.Options)) (*sqs.SendMessageOutput, error)
+	SendMessageBatch(ctx context.Context, params *sqs.SendMessageBatchInput, optFns ...func(*sqs.Options)) (*sqs.SendMessageBatchOutput, error)
+}
+
+// AttributeFunc returns the value of a message attribute for the record, or an
+// empty string to leave out the attribute
+type AttributeFunc func(record eventsource.Record) string
+
+// maxBatchSize is the number of messages SQS accepts in one SendMessageBatch call
+const maxBatchSize = 10
+
+type sqsNotification struct {
+	sqs        Client
+	queueURL   string
+	fifo       bool
+	attributes map[string]AttributeFunc
+}
+
+// Option is used for configuring the notification service
+type Option func(sn *sqsNotification)
+
+// WithFIFO is used for FIFO queues. The records are sent with the aggregate ID as
+// message group ID, so the records of each aggregate are received in order, and with the
+// sequence ID as message deduplication ID.
+func WithFIFO() Option {
+	return func(sn *sqsNotification) {
+		sn.fifo = true
+	}
+}
+
+// WithAttribute adds a string message attribute, besides SKF.Hierarchy.EventType and
+// SKF.Hierarchy.Aggregate, to the sent records. The event type is sent without its
+// schema version, which is sent as SKF.Hierarchy.SchemaVersion when above 1.
+func WithAttribute(name string, value AttributeFunc) Option {
+	return func(sn *sqsNotification) {
+		sn.attributes[name] = value
+	}
+}
+
+// NewWithClient sends the records to the given SQS queue URL, using the provided SQS client.
+func NewWithClient(queueURL string, client Client, opts ...Option) eventsource.NotificationService {
+	sn := &sqsNotification{
+		queueURL:   queueURL,
+		sqs:        client,
+		attributes: map[string]AttributeFunc{},
+	}
+
+	for _, opt := range opts {
+		opt(sn)
+	}
+
+	return sn
+}
+
+func (sn *sqsNotification) Send(record eventsource.Record) error {
+	return sn.SendWithContext(context.Background(), record)
+}
+
+func (sn *sqsNotification) SendWithContext(ctx context.Context, record eventsource.Record) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	input := sqs.SendMessageInput{
+		QueueUrl:          &sn.queueURL,
+		MessageBody:       aws.String(string(data)),
+		MessageAttributes: sn.messageAttributes(record),
+	}
+
+	if sn.fifo {
+		input.MessageGroupId = aws.String(record.AggregateID)
+		input.MessageDeduplicationId = aws.String(record.SequenceID)
+	}
+
+	_, err = sn.sqs.SendMessage(ctx, &input)
+
+	return err
+}
+
+// SendBatchWithContext sends the records in SendMessageBatch calls of up to 10 records,
+// returning an *eventsource.NotificationError with the records that failed. For FIFO
+// queues the records after a failed call aren't sent.
+func (sn *sqsNotification) SendBatchWithContext(ctx context.Context, records []eventsource.Record) error {
+	return eventsource.SendInBatches(ctx, sn, records, maxBatchSize, sn.fifo, sn.sendBatch)
+}
+
+// Ordered reports if the records have to be sent in order, which they have for FIFO queues
+func (sn *sqsNotification) Ordered() bool {
+	return sn.fifo
+}
+
+// sendBatch sends up to 10 records in one SendMessageBatch call
+func (sn *sqsNotification) sendBatch(ctx context.Context, records []eventsource.Record) error {
+	entries := make([]types.SendMessageBatchRequestEntry, len(records))
+
+	for i, record := range records {
+		data, err := json.Marshal(record)
+		if err != nil {
+			return err
+		}
+
+		entries[i] = types.SendMessageBatchRequestEntry{
+			Id:                aws.String(strconv.Itoa(i)),
+			MessageBody:       aws.String(string(data)),
+			MessageAttributes: sn.messageAttributes(record),
+		}
+
+		if sn.fifo {
+			entries[i].MessageGroupId = aws.String(record.AggregateID)
+			entries[i].MessageDeduplicationId = aws.String(record.SequenceID)
+		}
+	}
+
+	output, err := sn.sqs.SendMessageBatch(ctx, &sqs.SendMessageBatchInput{
+		QueueUrl: &sn.queueURL,
+		Entries:  entries,
+	})
+	if err != nil {
+		return err
+	}
+
+	if len(output.Failed) == 0 {
+		return nil
+	}
+
+	failures := make([]eventsource.NotificationFailure, 0, len(output.Failed))
+
+	for _, failed := range output.Failed {
+		i, err := strconv.Atoi(aws.ToString(failed.Id))
+		if err != nil || i < 0 || i >= len(records) {
+			return fmt.Errorf("unexpected id in send message batch response: %s", aws.ToString(failed.Id))
+		}
+
+		failures = append(failures, eventsource.NotificationFailure{
+			Service: sn,
+			Record:  records[i],
+			Err:     fmt.Errorf("%s: %s", aws.ToString(failed.Code), aws.ToString(failed.Message)),
+		})
+	}
+
+	return &eventsource.NotificationError{Failures: failures}
+}
+
+func (sn *sqsNotification) messageAttributes(record eventsource.Record) map[string]types.MessageAttributeValue {
+	eventType, version := eventsource.ParseRecordType(record.Type)
+	attributes := map[string]types.MessageAttributeValue{
+		"SKF.Hierarchy.EventType": {
+			DataType:    aws.String("String"),
+			StringValue: aws.String(eventType),
+		},
+		"SKF.Hierarchy.Aggregate": {
+			DataType:    aws.String("String"),
+			StringValue: aws.String(record.AggregateID),
+		},
+	}
+
+	if version > 1 {
+		attributes["SKF.Hierarchy.SchemaVersion"] = types.MessageAttributeValue{
+			DataType:    aws.String("String"),
+			StringValue: aws.String(strconv.Itoa(version)),
+		}
+	}
+
+	for name, value := range sn.attributes {
+		if v := value(record); v != "" {
+			attributes[name] = types.MessageAttributeValue{
+				DataType:    aws.String("String"),
+				StringValue: aws.String(v),
+			}
+		}
+	}
+
+	return attributes
+}
@@ -0,0 +1,128 @@
+package notification_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/SKF/go-eventsource/v2/eventsource"
+	notification "github.com/SKF/go-eventsource/v2/eventsource/notification/sqs"
+)
+
+const queueURL = "https://sqs.eu-west-1.amazonaws.com/123456789012/events.fifo"
+
+type fakeClient struct {
+	sent    []*sqs.SendMessageInput
+	batches []*sqs.SendMessageBatchInput
+	failed  []types.BatchResultErrorEntry
+}
+
+func (f *fakeClient) SendMessage(_ context.Context, params *sqs.SendMessageInput, _ ...func(*sqs.Options)) (*sqs.SendMessageOutput, error) {
+	f.sent = append(f.sent, params)
+
+	return &sqs.SendMessageOutput{}, nil
+}
+
+func (f *fakeClient) SendMessageBatch(_ context.Context, params *sqs.SendMessageBatchInput, _ ...func(*sqs.Options)) (*sqs.SendMessageBatchOutput, error) {
+	f.batches = append(f.batches, params)
+
+	return &sqs.SendMessageBatchOutput{Failed: f.failed}, nil
+}
+
+var record = eventsource.Record{
+	AggregateID: "aggregate",
+	SequenceID:  "sequence",
+	Type:        "Created",
+	UserID:      "user",
+}
+
+func TestSendWithContext(t *testing.T) {
+	t.Parallel()
+
+	client := &fakeClient{}
+	service := notification.NewWithClient(queueURL, client)
+
+	require.NoError(t, service.SendWithContext(context.TODO(), record))
+	require.Len(t, client.sent, 1)
+
+	input := client.sent[0]
+	assert.Equal(t, queueURL, aws.ToString(input.QueueUrl))
+	assert.Nil(t, input.MessageGroupId)
+	assert.Nil(t, input.MessageDeduplicationId)
+	assert.Equal(t, "Created", aws.ToString(input.MessageAttributes["SKF.Hierarchy.EventType"].StringValue))
+	assert.Equal(t, "aggregate", aws.ToString(input.MessageAttributes["SKF.Hierarchy.Aggregate"].StringValue))
+}
+
+func TestSendWithContext_SchemaVersion(t *testing.T) {
+	t.Parallel()
+
+	client := &fakeClient{}
+	service := notification.NewWithClient(queueURL, client)
+
+	versioned := record
+	versioned.Type = eventsource.RecordType("Created", 2)
+
+	require.NoError(t, service.SendWithContext(context.TODO(), versioned))
+	require.Len(t, client.sent, 1)
+
+	attributes := client.sent[0].MessageAttributes
+	assert.Equal(t, "Created", aws.ToString(attributes["SKF.Hierarchy.EventType"].StringValue))
+	assert.Equal(t, "2", aws.ToString(attributes["SKF.Hierarchy.SchemaVersion"].StringValue))
+}
+
+func TestSendWithContext_FIFOAndAttributes(t *testing.T) {
+	t.Parallel()
+
+	client := &fakeClient{}
+	service := notification.NewWithClient(queueURL, client,
+		notification.WithFIFO(),
+		notification.WithAttribute("SKF.Hierarchy.User", func(r eventsource.Record) string { return r.UserID }),
+		notification.WithAttribute("Empty", func(eventsource.Record) string { return "" }),
+	)
+
+	require.NoError(t, service.SendWithContext(context.TODO(), record))
+	require.Len(t, client.sent, 1)
+
+	input := client.sent[0]
+	assert.Equal(t, "aggregate", aws.ToString(input.MessageGroupId))
+	assert.Equal(t, "sequence", aws.ToString(input.MessageDeduplicationId))
+	assert.Equal(t, "user", aws.ToString(input.MessageAttributes["SKF.Hierarchy.User"].StringValue))
+	assert.Equal(t, "String", aws.ToString(input.MessageAttributes["SKF.Hierarchy.User"].DataType))
+	assert.NotContains(t, input.MessageAttributes, "Empty")
+	assert.Len(t, input.MessageAttributes, 3)
+}
+
+func TestSendBatchWithContext(t *testing.T) {
+	t.Parallel()
+
+	other := record
+	other.SequenceID = "other"
+
+	client := &fakeClient{
+		failed: []types.BatchResultErrorEntry{{Id: aws.String("1"), Code: aws.String("Throttled"), Message: aws.String("slow down")}},
+	}
+	service := notification.NewWithClient(queueURL, client, notification.WithFIFO())
+
+	batchService, ok := service.(eventsource.BatchNotificationService)
+	require.True(t, ok)
+
+	err := batchService.SendBatchWithContext(context.TODO(), []eventsource.Record{record, other})
+
+	var notificationErr *eventsource.NotificationError
+	require.ErrorAs(t, err, &notificationErr)
+	require.Len(t, notificationErr.Failures, 1)
+	assert.Equal(t, other, notificationErr.Failures[0].Record)
+	assert.EqualError(t, notificationErr.Failures[0].Err, "Throttled: slow down")
+
+	require.Len(t, client.batches, 1)
+
+	entries := client.batches[0].Entries
+	require.Len(t, entries, 2)
+	assert.Equal(t, "aggregate", aws.ToString(entries[1].MessageGroupId))
+	assert.Equal(t, "other", aws.ToString(entries[1].MessageDeduplicationId))
+}
@@ -0,0 +1,279 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/SKF/go-eventsource/v2/eventsource"
+)
+
+const (
+	// SignatureHeader is the default header carrying the HMAC-SHA256 signature of the timestamp and body
+	SignatureHeader = "X-Signature-256"
+	// TimestampHeader carries the time the request was signed, in Unix seconds
+	TimestampHeader = "X-Webhook-Timestamp"
+
+	defaultTimeout = 10 * time.Second
+	// maxPartialDeliveries is the number of records whose delivered URLs are remembered
+	maxPartialDeliveries = 1000
+)
+
+// ErrInvalidSignature is returned by Verify when the signature doesn't match or has expired
+var ErrInvalidSignature = errors.New("invalid signature")
+
+// HTTPClient sends the requests, implemented by *http.Client
+type HTTPClient interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// EnvelopeFunc returns the body posted for the record
+type EnvelopeFunc func(record eventsource.Record) ([]byte, error)
+
+type webhookNotification struct {
+	client          HTTPClient
+	urls            []string
+	secret          []byte
+	signatureHeader string
+	envelope        EnvelopeFunc
+	timeout         time.Duration
+	retry           eventsource.RetryPolicy
+	partial         partialDeliveries
+}
+
+// partialDeliveries remembers the URLs records have been delivered to while other URLs
+// failed, so they aren't posted again when the record is sent again
+type partialDeliveries struct {
+	mutex     sync.Mutex
+	delivered map[string]map[string]bool
+	order     []string
+}
+
+func (p *partialDeliveries) get(sequenceID string) map[string]bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return p.delivered[sequenceID]
+}
+
+// set remembers the URLs the record has been delivered to, or forgets the record if nil.
+// Only the latest records are remembered.
+func (p *partialDeliveries) set(sequenceID string, urls map[string]bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if _, ok := p.delivered[sequenceID]; ok {
+		delete(p.delivered, sequenceID)
+		p.order = slices.DeleteFunc(p.order, func(id string) bool { return id == sequenceID })
+	}
+
+	if urls == nil {
+		return
+	}
+
+	p.delivered[sequenceID] = urls
+	p.order = append(p.order, sequenceID)
+
+	if len(p.order) > maxPartialDeliveries {
+		delete(p.delivered, p.order[0])
+		p.order = p.order[1:]
+	}
+}
+
+// Option is used for configuring the notification service
+type Option func(wn *webhookNotification)
+
+// WithHTTPClient sets the client sending the requests, http.DefaultClient by default
+func WithHTTPClient(client HTTPClient) Option {
+	return func(wn *webhookNotification) {
+		wn.client = client
+	}
+}
+
+// WithEnvelope sets how the body is created for a record, by default the record as JSON
+func WithEnvelope(envelope EnvelopeFunc) Option {
+	return func(wn *webhookNotification) {
+		wn.envelope = envelope
+	}
+}
+
+// WithSignatureHeader sets the name of the header carrying the signature, X-Signature-256 by default
+func WithSignatureHeader(name string) Option {
+	return func(wn *webhookNotification) {
+		wn.signatureHeader = name
+	}
+}
+
+// WithTimeout sets the timeout of each request, 10 seconds by default
+func WithTimeout(timeout time.Duration) Option {
+	return func(wn *webhookNotification) {
+		wn.timeout = timeout
+	}
+}
+
+// WithRetryPolicy makes failed requests be retried, by default they are only attempted once
+func WithRetryPolicy(policy eventsource.RetryPolicy) Option {
+	return func(wn *webhookNotification) {
+		wn.retry = policy
+	}
+}
+
+// New posts the records to the URLs. The timestamp and body are signed with HMAC-SHA256
+// using the secret, sent as "sha256=<hex>" in the signature header, and any response but
+// 2xx is a failure. When a record is sent again after some URLs failed, it is only posted
+// to the URLs which haven't received it.
+func New(urls []string, secret []byte, opts ...Option) eventsource.NotificationService {
+	wn := &webhookNotification{
+		client:          http.DefaultClient,
+		urls:            urls,
+		secret:          secret,
+		signatureHeader: SignatureHeader,
+		envelope:        marshalRecord,
+		timeout:         defaultTimeout,
+		retry:           eventsource.RetryPolicy{MaxAttempts: 1},
+		partial:         partialDeliveries{delivered: map[string]map[string]bool{}},
+	}
+
+	for _, opt := range opts {
+		opt(wn)
+	}
+
+	return wn
+}
+
+func marshalRecord(record eventsource.Record) ([]byte, error) {
+	return json.Marshal(record)
+}
+
+// Sign returns the signature of the timestamp, as sent in the timestamp header, and the
+// body, as sent in the signature header. The signed content is "<timestamp>.<body>".
+func Sign(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks the signature and timestamp headers of a received request against the
+// body. Requests signed more than tolerance ago, or in the future, are rejected so that
+// captured requests can't be replayed later.
+func Verify(secret []byte, signature, timestamp string, body []byte, tolerance time.Duration) error {
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: malformed timestamp", ErrInvalidSignature)
+	}
+
+	if age := time.Since(time.Unix(seconds, 0)); age > tolerance || age < -tolerance {
+		return fmt.Errorf("%w: timestamp outside tolerance", ErrInvalidSignature)
+	}
+
+	if !hmac.Equal([]byte(signature), []byte(Sign(secret, timestamp, body))) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+func (wn *webhookNotification) Send(record eventsource.Record) error {
+	return wn.SendWithContext(context.Background(), record)
+}
+
+// SendWithContext posts the record to all URLs it hasn't been delivered to, even if some of them fail
+func (wn *webhookNotification) SendWithContext(ctx context.Context, record eventsource.Record) error {
+	body, err := wn.envelope(record)
+	if err != nil {
+		return fmt.Errorf("failed to create body: %w", err)
+	}
+
+	var errs []error
+
+	delivered := map[string]bool{}
+	for url := range wn.partial.get(record.SequenceID) {
+		delivered[url] = true
+	}
+
+	for _, url := range wn.urls {
+		if delivered[url] {
+			continue
+		}
+
+		if err = wn.postWithRetries(ctx, url, record, body); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", url, err))
+		} else {
+			delivered[url] = true
+		}
+	}
+
+	if len(errs) == 0 {
+		wn.partial.set(record.SequenceID, nil)
+	} else {
+		wn.partial.set(record.SequenceID, delivered)
+	}
+
+	return errors.Join(errs...)
+}
+
+func (wn *webhookNotification) postWithRetries(ctx context.Context, url string, record eventsource.Record, body []byte) error {
+	err := wn.post(ctx, url, record, body)
+
+	for retry := 1; retry < wn.retry.MaxAttempts && err != nil; retry++ {
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(wn.retry.Backoff(retry)):
+		}
+
+		err = wn.post(ctx, url, record, body)
+	}
+
+	return err
+}
+
+func (wn *webhookNotification) post(ctx context.Context, url string, record eventsource.Record, body []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, wn.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(wn.signatureHeader, Sign(wn.secret, timestamp, body))
+	req.Header.Set("X-Sequence-Id", record.SequenceID)
+
+	eventType, version := eventsource.ParseRecordType(record.Type)
+	req.Header.Set("X-Event-Type", eventType)
+
+	if version > 1 {
+		req.Header.Set("X-Schema-Version", strconv.Itoa(version))
+	}
+
+	resp, err := wn.client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	return nil
+}
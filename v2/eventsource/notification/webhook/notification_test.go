@@ -0,0 +1,190 @@
+package notification_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/SKF/go-eventsource/v2/eventsource"
+	notification "github.com/SKF/go-eventsource/v2/eventsource/notification/webhook"
+)
+
+var (
+	secret = []byte("secret")
+	record = eventsource.Record{
+		AggregateID: "aggregate",
+		SequenceID:  "sequence",
+		Type:        "Created",
+		UserID:      "user",
+	}
+)
+
+func TestSendWithContext(t *testing.T) {
+	t.Parallel()
+
+	var (
+		body      []byte
+		signature string
+		timestamp string
+		eventType string
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		signature = r.Header.Get(notification.SignatureHeader)
+		timestamp = r.Header.Get(notification.TimestampHeader)
+		eventType = r.Header.Get("X-Event-Type")
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	service := notification.New([]string{server.URL}, secret)
+	require.NoError(t, service.SendWithContext(context.TODO(), record))
+
+	var received eventsource.Record
+	require.NoError(t, json.Unmarshal(body, &received))
+	assert.Equal(t, record, received)
+	assert.Equal(t, notification.Sign(secret, timestamp, body), signature)
+	require.NoError(t, notification.Verify(secret, signature, timestamp, body, time.Minute))
+	assert.Equal(t, "Created", eventType)
+}
+
+func TestSendWithContext_SchemaVersion(t *testing.T) {
+	t.Parallel()
+
+	var eventType, schemaVersion string
+
+	server := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		eventType = r.Header.Get("X-Event-Type")
+		schemaVersion = r.Header.Get("X-Schema-Version")
+	}))
+	defer server.Close()
+
+	versioned := record
+	versioned.Type = eventsource.RecordType("Created", 2)
+
+	service := notification.New([]string{server.URL}, secret)
+	require.NoError(t, service.SendWithContext(context.TODO(), versioned))
+
+	assert.Equal(t, "Created", eventType)
+	assert.Equal(t, "2", schemaVersion)
+}
+
+func TestSendWithContext_Envelope(t *testing.T) {
+	t.Parallel()
+
+	var body []byte
+
+	server := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer server.Close()
+
+	service := notification.New([]string{server.URL}, secret, notification.WithEnvelope(func(r eventsource.Record) ([]byte, error) {
+		return json.Marshal(map[string]string{"event": r.Type})
+	}))
+	require.NoError(t, service.SendWithContext(context.TODO(), record))
+
+	assert.JSONEq(t, `{"event":"Created"}`, string(body))
+}
+
+func TestSendWithContext_RetriesFailures(t *testing.T) {
+	t.Parallel()
+
+	var attempts, otherAttempts atomic.Int32
+
+	failingOnce := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if attempts.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer failingOnce.Close()
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		otherAttempts.Add(1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer failing.Close()
+
+	service := notification.New([]string{failing.URL, failingOnce.URL}, secret,
+		notification.WithRetryPolicy(eventsource.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond}))
+
+	err := service.SendWithContext(context.TODO(), record)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), failing.URL+": unexpected status 400 Bad Request")
+	assert.NotContains(t, err.Error(), failingOnce.URL)
+	assert.Equal(t, int32(3), otherAttempts.Load())
+	assert.Equal(t, int32(2), attempts.Load())
+}
+
+func TestSendWithContext_Timeout(t *testing.T) {
+	t.Parallel()
+
+	release := make(chan struct{})
+
+	server := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+
+	service := notification.New([]string{server.URL}, secret, notification.WithTimeout(10*time.Millisecond))
+
+	assert.ErrorIs(t, service.SendWithContext(context.TODO(), record), context.DeadlineExceeded)
+}
+
+func TestSendWithContext_OnlyResendsToFailedURLs(t *testing.T) {
+	t.Parallel()
+
+	var succeeding, failing atomic.Int32
+
+	succeedingServer := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		succeeding.Add(1)
+	}))
+	defer succeedingServer.Close()
+
+	failingServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if failing.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer failingServer.Close()
+
+	service := notification.New([]string{succeedingServer.URL, failingServer.URL}, secret)
+
+	require.Error(t, service.SendWithContext(context.TODO(), record))
+	require.NoError(t, service.SendWithContext(context.TODO(), record))
+	assert.Equal(t, int32(1), succeeding.Load())
+	assert.Equal(t, int32(2), failing.Load())
+
+	require.NoError(t, service.SendWithContext(context.TODO(), record), "A delivered record is sent to all URLs again")
+	assert.Equal(t, int32(2), succeeding.Load())
+}
+
+func TestVerify(t *testing.T) {
+	t.Parallel()
+
+	body := []byte(`{"type":"Created"}`)
+	now := strconv.FormatInt(time.Now().Unix(), 10)
+	old := strconv.FormatInt(time.Now().Add(-time.Hour).Unix(), 10)
+
+	require.NoError(t, notification.Verify(secret, notification.Sign(secret, now, body), now, body, time.Minute))
+
+	err := notification.Verify(secret, notification.Sign(secret, old, body), old, body, time.Minute)
+	require.ErrorIs(t, err, notification.ErrInvalidSignature, "Replayed requests are rejected")
+
+	err = notification.Verify(secret, notification.Sign(secret, now, body), now, []byte("{}"), time.Minute)
+	require.ErrorIs(t, err, notification.ErrInvalidSignature)
+
+	err = notification.Verify(secret, notification.Sign(secret, old, body), now, body, time.Minute)
+	require.ErrorIs(t, err, notification.ErrInvalidSignature, "The timestamp is signed")
+}
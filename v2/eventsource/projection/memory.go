@@ -0,0 +1,34 @@
+package projection
+
+import (
+	"context"
+	"sync"
+)
+
+type memoryCheckpointStore struct {
+	Data  map[string]string
+	mutex sync.RWMutex
+}
+
+// NewMemoryCheckpointStore creates a new checkpoint store keeping the checkpoints in memory
+func NewMemoryCheckpointStore() CheckpointStore {
+	return &memoryCheckpointStore{
+		Data: map[string]string{},
+	}
+}
+
+func (mem *memoryCheckpointStore) LoadCheckpoint(_ context.Context, name string) (string, error) {
+	mem.mutex.RLock()
+	defer mem.mutex.RUnlock()
+
+	return mem.Data[name], nil
+}
+
+func (mem *memoryCheckpointStore) SaveCheckpoint(_ context.Context, name string, sequenceID string) error {
+	mem.mutex.Lock()
+	defer mem.mutex.Unlock()
+
+	mem.Data[name] = sequenceID
+
+	return nil
+}
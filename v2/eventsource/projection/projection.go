@@ -0,0 +1,127 @@
+package projection
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/SKF/go-eventsource/v2/eventsource"
+)
+
+// Projection builds a read model from the events of the event source
+type Projection interface {
+	// Name identifies the checkpoint of the projection, so it has to be unique and stable
+	Name() string
+	// Handle applies the event to the read model
+	Handle(ctx context.Context, event eventsource.Event) error
+}
+
+// Resetter is implemented by projections which have to clear their read model before
+// being rebuilt
+type Resetter interface {
+	Reset(ctx context.Context) error
+}
+
+// CheckpointStore records the sequence ID of the last event handled by each projection
+type CheckpointStore interface {
+	// Load the sequence ID of the last event handled by the projection, or an empty string if there is none
+	LoadCheckpoint(ctx context.Context, name string) (sequenceID string, err error)
+	// Save the sequence ID of the last event handled by the projection
+	SaveCheckpoint(ctx context.Context, name string, sequenceID string) error
+}
+
+// Runner drives projections with the events of a repository, resuming each
+// projection from its checkpoint
+type Runner struct {
+	repo        eventsource.Repository
+	checkpoints CheckpointStore
+	opts        []eventsource.SubscribeOption
+}
+
+// NewRunner creates a new runner. The subscribe options, like eventsource.WithPollInterval,
+// are used when running the projections.
+func NewRunner(repo eventsource.Repository, checkpoints CheckpointStore, opts ...eventsource.SubscribeOption) *Runner {
+	return &Runner{
+		repo:        repo,
+		checkpoints: checkpoints,
+		opts:        opts,
+	}
+}
+
+// Run handles all events after the checkpoint of each projection, and then keeps handling
+// new events as they are saved. It blocks until the context is done, returning nil, or
+// until one of the projections fails, when all projections are stopped and the error
+// is returned.
+func (r *Runner) Run(ctx context.Context, projections ...Projection) error {
+	return r.run(ctx, projections, r.opts...)
+}
+
+// CatchUp handles all events after the checkpoint of each projection, and then returns
+func (r *Runner) CatchUp(ctx context.Context, projections ...Projection) error {
+	return r.run(ctx, projections, slices.Concat(r.opts, []eventsource.SubscribeOption{eventsource.UntilCaughtUp()})...)
+}
+
+// Rebuild resets the projection, if it implements Resetter, and its checkpoint, and then
+// replays all events from the beginning
+func (r *Runner) Rebuild(ctx context.Context, projection Projection) error {
+	if resetter, ok := projection.(Resetter); ok {
+		if err := resetter.Reset(ctx); err != nil {
+			return errors.Wrapf(err, "failed to reset projection %s", projection.Name())
+		}
+	}
+
+	if err := r.checkpoints.SaveCheckpoint(ctx, projection.Name(), ""); err != nil {
+		return errors.Wrapf(err, "failed to reset checkpoint of projection %s", projection.Name())
+	}
+
+	return r.CatchUp(ctx, projection)
+}
+
+func (r *Runner) run(ctx context.Context, projections []Projection, opts ...eventsource.SubscribeOption) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+
+	for _, projection := range projections {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			withCheckpoint := eventsource.WithCheckpoint(&checkpoint{store: r.checkpoints, name: projection.Name()})
+
+			err := r.repo.Subscribe(ctx, "", projection.Handle, slices.Concat(opts, []eventsource.SubscribeOption{withCheckpoint})...)
+			if err != nil {
+				once.Do(func() {
+					firstErr = errors.Wrapf(err, "projection %s failed", projection.Name())
+					cancel()
+				})
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	return firstErr
+}
+
+// checkpoint is the checkpoint of a projection in the checkpoint store
+type checkpoint struct {
+	store CheckpointStore
+	name  string
+}
+
+func (c *checkpoint) LoadCheckpoint(ctx context.Context) (string, error) {
+	return c.store.LoadCheckpoint(ctx, c.name) // nolint:wrapcheck
+}
+
+func (c *checkpoint) SaveCheckpoint(ctx context.Context, sequenceID string) error {
+	return c.store.SaveCheckpoint(ctx, c.name, sequenceID) // nolint:wrapcheck
+}
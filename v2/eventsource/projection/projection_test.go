@@ -0,0 +1,148 @@
+package projection
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/SKF/go-eventsource/v2/eventsource"
+	"github.com/SKF/go-eventsource/v2/eventsource/serializers/json"
+	"github.com/SKF/go-eventsource/v2/eventsource/stores/memorystore"
+)
+
+type TestEvent struct {
+	*eventsource.BaseEvent
+	Value string `json:"value"`
+}
+
+type testProjection struct {
+	name   string
+	mutex  sync.Mutex
+	values []string
+	resets int
+	err    error
+}
+
+func (p *testProjection) Name() string { return p.name }
+
+func (p *testProjection) Handle(_ context.Context, event eventsource.Event) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.err != nil {
+		return p.err
+	}
+
+	p.values = append(p.values, event.(TestEvent).Value) // nolint:forcetypeassert
+
+	return nil
+}
+
+func (p *testProjection) Reset(_ context.Context) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.values = nil
+	p.resets++
+
+	return nil
+}
+
+func (p *testProjection) Values() []string {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return append([]string{}, p.values...)
+}
+
+func setup(t *testing.T, values ...string) (eventsource.Repository, CheckpointStore, *Runner) {
+	t.Helper()
+
+	repo := eventsource.NewRepository(memorystore.New(), json.NewSerializer(TestEvent{}))
+	save(t, repo, values...)
+
+	checkpoints := NewMemoryCheckpointStore()
+
+	return repo, checkpoints, NewRunner(repo, checkpoints, eventsource.WithPollInterval(time.Millisecond, 10*time.Millisecond))
+}
+
+func save(t *testing.T, repo eventsource.Repository, values ...string) {
+	t.Helper()
+
+	for _, value := range values {
+		require.NoError(t, repo.Save(context.Background(), TestEvent{BaseEvent: &eventsource.BaseEvent{AggregateID: "A"}, Value: value}))
+	}
+}
+
+func Test_CatchUp(t *testing.T) {
+	ctx := context.Background()
+	repo, checkpoints, runner := setup(t, "a", "b")
+	first, second := &testProjection{name: "first"}, &testProjection{name: "second"}
+
+	require.NoError(t, runner.CatchUp(ctx, first))
+	assert.Equal(t, []string{"a", "b"}, first.Values())
+
+	save(t, repo, "c")
+
+	require.NoError(t, runner.CatchUp(ctx, first, second))
+	assert.Equal(t, []string{"a", "b", "c"}, first.Values())
+	assert.Equal(t, []string{"a", "b", "c"}, second.Values())
+
+	events, err := repo.LoadEvents(ctx)
+	require.NoError(t, err)
+
+	checkpoint, err := checkpoints.LoadCheckpoint(ctx, "first")
+	require.NoError(t, err)
+	assert.Equal(t, events[2].GetSequenceID(), checkpoint)
+}
+
+func Test_Run(t *testing.T) {
+	repo, _, runner := setup(t, "a")
+	projection := &testProjection{name: "projection"}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+
+	go func() {
+		done <- runner.Run(ctx, projection)
+	}()
+
+	save(t, repo, "b")
+
+	assert.Eventually(t, func() bool {
+		return len(projection.Values()) == 2
+	}, time.Second, time.Millisecond)
+
+	cancel()
+	assert.NoError(t, <-done)
+	assert.Equal(t, []string{"a", "b"}, projection.Values())
+}
+
+func Test_RunFails(t *testing.T) {
+	_, _, runner := setup(t, "a")
+	failing := &testProjection{name: "failing", err: errors.New("broken")}
+	working := &testProjection{name: "working"}
+
+	err := runner.Run(context.Background(), failing, working)
+
+	require.Error(t, err)
+	assert.ErrorIs(t, err, failing.err)
+	assert.Contains(t, err.Error(), "projection failing failed")
+}
+
+func Test_Rebuild(t *testing.T) {
+	ctx := context.Background()
+	_, _, runner := setup(t, "a", "b")
+	projection := &testProjection{name: "projection"}
+
+	require.NoError(t, runner.CatchUp(ctx, projection))
+	require.NoError(t, runner.Rebuild(ctx, projection))
+
+	assert.Equal(t, 1, projection.resets)
+	assert.Equal(t, []string{"a", "b"}, projection.Values())
+}
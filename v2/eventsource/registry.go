@@ -0,0 +1,116 @@
+package eventsource
+
+import (
+	"reflect"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// ErrDuplicateType is returned by TypeRegistry.Register() when the event type, name or alias is already registered
+	ErrDuplicateType = errors.New("event type already registered")
+	// ErrUnregisteredType is returned when an event type or name isn't registered in the TypeRegistry
+	ErrUnregisteredType = errors.New("event type not registered")
+)
+
+// TypeRegistry maps event types to explicit, stable names, which are stored in Record.Type
+// instead of the Go type names. Renaming or moving an event struct then doesn't affect the
+// stored records, and structs with the same name in different packages don't collide.
+type TypeRegistry struct {
+	mutex sync.RWMutex
+	names map[reflect.Type]string
+	types map[string]reflect.Type
+}
+
+// NewTypeRegistry creates an empty registry
+func NewTypeRegistry() *TypeRegistry {
+	return &TypeRegistry{
+		names: map[reflect.Type]string{},
+		types: map[string]reflect.Type{},
+	}
+}
+
+// Register the event type with the name. Records with the aliases, like the names used
+// before the type was registered, are also unmarshalled to the event type.
+func (r *TypeRegistry) Register(name string, event Event, aliases ...string) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	eventType := GetType(event)
+
+	if registered, ok := r.names[eventType]; ok {
+		return errors.Wrapf(ErrDuplicateType, "%s is registered as %s", eventType, registered)
+	}
+
+	for _, n := range append([]string{name}, aliases...) {
+		if registered, ok := r.types[n]; ok {
+			return errors.Wrapf(ErrDuplicateType, "%s is registered for %s", n, registered)
+		}
+	}
+
+	r.names[eventType] = name
+	for _, n := range append([]string{name}, aliases...) {
+		r.types[n] = eventType
+	}
+
+	return nil
+}
+
+// MustRegister is like Register, but panics if the registration fails
+func (r *TypeRegistry) MustRegister(name string, event Event, aliases ...string) *TypeRegistry {
+	if err := r.Register(name, event, aliases...); err != nil {
+		panic(err)
+	}
+
+	return r
+}
+
+// Name returns the registered name of the event type
+func (r *TypeRegistry) Name(event Event) (string, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	eventType := GetType(event)
+
+	name, ok := r.names[eventType]
+	if !ok {
+		return "", errors.Wrapf(ErrUnregisteredType, "%s", eventType)
+	}
+
+	return name, nil
+}
+
+// Type returns the event type registered with the name or alias
+func (r *TypeRegistry) Type(name string) (reflect.Type, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	eventType, ok := r.types[name]
+	if !ok {
+		return nil, errors.Wrapf(ErrUnregisteredType, "%s", name)
+	}
+
+	return eventType, nil
+}
+
+// Types returns the registered event types
+func (r *TypeRegistry) Types() []reflect.Type {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	types := make([]reflect.Type, 0, len(r.names))
+	for eventType := range r.names {
+		types = append(types, eventType)
+	}
+
+	return types
+}
+
+// WithTypeRegistry makes the repository save the records with the names of the event
+// types in the registry. Saving events of unregistered types fails.
+func WithTypeRegistry(registry *TypeRegistry) RepositoryOption {
+	return func(repo *repository) {
+		repo.typeRegistry = registry
+	}
+}
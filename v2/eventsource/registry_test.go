@@ -0,0 +1,68 @@
+package eventsource
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_TypeRegistry(t *testing.T) {
+	t.Parallel()
+
+	registry := NewTypeRegistry().MustRegister("orders.OtherEvent", OtherEvent{}, "OtherEvent")
+
+	name, err := registry.Name(&OtherEvent{})
+	require.NoError(t, err)
+	assert.Equal(t, "orders.OtherEvent", name)
+
+	for _, n := range []string{"orders.OtherEvent", "OtherEvent"} {
+		eventType, err := registry.Type(n)
+		require.NoError(t, err)
+		assert.Equal(t, GetType(OtherEvent{}), eventType)
+	}
+
+	_, err = registry.Name(&BaseEvent{})
+	require.ErrorIs(t, err, ErrUnregisteredType)
+
+	_, err = registry.Type("BaseEvent")
+	require.ErrorIs(t, err, ErrUnregisteredType)
+}
+
+func Test_TypeRegistryDuplicates(t *testing.T) {
+	t.Parallel()
+
+	registry := NewTypeRegistry().MustRegister("orders.OtherEvent", OtherEvent{}, "OtherEvent")
+
+	require.ErrorIs(t, registry.Register("orders.Other", &OtherEvent{}), ErrDuplicateType, "Same type")
+	require.ErrorIs(t, registry.Register("orders.OtherEvent", &BaseEvent{}), ErrDuplicateType, "Same name")
+	require.ErrorIs(t, registry.Register("orders.BaseEvent", &BaseEvent{}, "OtherEvent"), ErrDuplicateType, "Same alias")
+
+	require.NoError(t, registry.Register("orders.BaseEvent", &BaseEvent{}), "Failed registrations are not kept")
+}
+
+func Test_RepoSaveWithTypeRegistry(t *testing.T) {
+	t.Parallel()
+
+	storeMock, storeTransactionMock, serializerMock, _ := setupMocks()
+	testEvent, testData := createMockDataForSave()
+	registry := NewTypeRegistry().MustRegister("orders.BaseEvent", &BaseEvent{})
+
+	ctx := context.TODO()
+	serializerMock.On("Marshal", testEvent).Return(testData, nil)
+	storeMock.On("NewTransaction", ctx, mock.MatchedBy(func(rs []Record) bool {
+		return len(rs) == 1 && rs[0].Type == "orders.BaseEvent"
+	})).Return(storeTransactionMock, nil).Once()
+	storeTransactionMock.On("Commit").Return(nil).Once()
+	storeTransactionMock.On("GetRecords").Return([]Record{}).Once()
+
+	repo := NewRepository(storeMock, serializerMock, WithTypeRegistry(registry))
+	require.NoError(t, repo.Save(ctx, testEvent))
+
+	storeMock.AssertExpectations(t)
+
+	err := repo.Save(ctx, &OtherEvent{BaseEvent: testEvent})
+	require.ErrorIs(t, err, ErrUnregisteredType)
+}
@@ -3,10 +3,13 @@ package eventsource
 import (
 	"context"
 	"crypto/rand"
-	"fmt"
+	"iter"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/SKF/go-utility/v2/log"
 	"github.com/oklog/ulid"
 	"github.com/pkg/errors"
 )
@@ -18,12 +21,49 @@ var (
 	ErrNoHistory = errors.New("no history found")
 	// ErrNotificationFailed is returned by Commit() if notification service fails
 	ErrNotificationFailed = errors.New("Failed to send notification")
+	// ErrConcurrencyConflict is returned when saving events with an expected version
+	// and the aggregate has been modified by someone else in the meantime
+	ErrConcurrencyConflict = errors.New("concurrency conflict")
 )
 
 // QueryOption is used for setting store specific options like limit or sorting
 // Can be found in any of the stores
 type QueryOption func(opt interface{})
 
+// BySequenceID is a QueryOption supported by all included stores, which only
+// returns records with a sequence ID greater than the given one. The options of a
+// store support it by implementing FilterBySequenceID(sequenceID string).
+func BySequenceID(sequenceID string) QueryOption {
+	return func(opt interface{}) {
+		if o, ok := opt.(interface{ FilterBySequenceID(sequenceID string) }); ok {
+			o.FilterBySequenceID(sequenceID)
+		}
+	}
+}
+
+// ByVersion is a QueryOption supported by all included stores, which only returns
+// records with a version greater than the given one. The options of a store support
+// it by implementing FilterByVersion(version int64).
+func ByVersion(version int64) QueryOption {
+	return func(opt interface{}) {
+		if o, ok := opt.(interface{ FilterByVersion(version int64) }); ok {
+			o.FilterByVersion(version)
+		}
+	}
+}
+
+// WithLimit is a QueryOption supported by the included sql and memory stores, which
+// return at most limit records. As it only makes sense for stores returning the records
+// in sequence order, the options of a store support it by implementing LimitRecords(limit int)
+// only if they do.
+func WithLimit(limit int) QueryOption {
+	return func(opt interface{}) {
+		if o, ok := opt.(interface{ LimitRecords(limit int) }); ok {
+			o.LimitRecords(limit)
+		}
+	}
+}
+
 // Store is the interface implemented by the data stores that can be used as back end for
 // the event source.
 type Store interface {
@@ -63,6 +103,14 @@ type Serializer interface {
 	Marshal(event Event) (data []byte, err error)
 }
 
+// UserIDSerializer is implemented by serializers which keep the user ID of the events
+// out of the stored records, like serializers encrypting personal data
+type UserIDSerializer interface {
+	Serializer
+	// RecordUserID returns the Record.UserID to store for the event
+	RecordUserID(event Event) string
+}
+
 // NotificationService represents a service which can emit notifications
 // when records are saved to the event source
 type NotificationService interface {
@@ -70,6 +118,51 @@ type NotificationService interface {
 	SendWithContext(ctx context.Context, record Record) error
 }
 
+// RelayingStore is implemented by stores which publish the records themselves, like the
+// sql store WithOutbox. The repository doesn't send the records saved to such a store to
+// its notification services, as they would be sent twice.
+type RelayingStore interface {
+	Store
+	// Relays returns true if the records are published by the store
+	Relays() bool
+}
+
+// WrappingStore is implemented by stores wrapping another store, like the one returned by
+// WrapStore, so the optional interfaces of the wrapped store can be found
+type WrappingStore interface {
+	Store
+	// Unwrap returns the wrapped store
+	Unwrap() Store
+}
+
+// findStore returns the store, or the first store wrapped by it, implementing T
+func findStore[T any](store Store) (T, bool) {
+	for store != nil {
+		if found, ok := store.(T); ok {
+			return found, true
+		}
+
+		wrapping, ok := store.(WrappingStore)
+		if !ok {
+			break
+		}
+
+		store = wrapping.Unwrap()
+	}
+
+	var none T
+
+	return none, false
+}
+
+// StoreRelays returns true if the records saved to the store, or to the store wrapped by it,
+// are published by the store, see RelayingStore
+func StoreRelays(store Store) bool {
+	relaying, ok := findStore[RelayingStore](store)
+
+	return ok && relaying.Relays()
+}
+
 // Repository is an interface representing the actual event source.
 type Repository interface {
 	// Return store
@@ -81,6 +174,12 @@ type Repository interface {
 	// Save one or more events to the repository, within a transaction
 	SaveTransaction(ctx context.Context, events ...Event) (StoreTransaction, error)
 
+	// Save one or more events to the given aggregate, but only if the aggregate
+	// currently is at expectedVersion, i.e. it has exactly that many events stored.
+	// ErrConcurrencyConflict is returned if the aggregate has been modified. Without
+	// events, it only checks that the aggregate is at expectedVersion.
+	SaveWithExpectedVersion(ctx context.Context, aggregateID string, expectedVersion int64, events ...Event) error
+
 	// Load events from repository for the given aggregate ID. For each event e,
 	// call aggr.On(e) to update the state of aggr. When done, aggr has been
 	// "fast forwarded" to the current state.
@@ -91,6 +190,10 @@ type Repository interface {
 	// or options like limit, offset
 	LoadEvents(ctx context.Context, opts ...QueryOption) (events []Event, err error)
 
+	// Same as LoadEvents, but the events are read lazily while iterating, so all of them
+	// don't have to fit in memory. The iteration stops after the first error.
+	IterEvents(ctx context.Context, opts ...QueryOption) iter.Seq2[Event, error]
+
 	// Deprecated: Use LoadEvents(ctx, store.BySequenceId(...))
 	// Get all events with sequence ID newer than the given ID (see https://github.com/oklog/ulid)
 	// Return at most limit records. If limit is 0, don't limit the number of records returned.
@@ -98,7 +201,9 @@ type Repository interface {
 
 	// Deprecated: Use LoadEvents(ctx, store.BySequenceId(...), store.ByType(...))
 	// Same as GetEventsBySequenceID, but only returns events of the same type
-	// as the one provided in the eventType parameter.
+	// as the one provided in the eventType parameter, including the records stored
+	// with older schema versions or types which are renamed to it. The options
+	// apply to the query of each stored type, and a limit to the merged records.
 	GetEventsBySequenceIDAndType(ctx context.Context, sequenceID string, eventType Event, opts ...QueryOption) (events []Event, err error)
 
 	// Deprecated: Use LoadEvents(ctx, store.ByTimestamp(...))
@@ -106,6 +211,10 @@ type Repository interface {
 	// Return at most limit records. If limit is 0, don't limit the number of records returned.
 	GetEventsByTimestamp(ctx context.Context, timestamp int64, opts ...QueryOption) (events []Event, err error)
 
+	// Deliver all events with a sequence ID greater than fromSequenceID to the handler,
+	// in sequence order, and keep delivering new events until the context is done
+	Subscribe(ctx context.Context, fromSequenceID string, handler EventHandler, opts ...SubscribeOption) error
+
 	// Add notification service
 	AddNotificationService(service NotificationService)
 
@@ -113,21 +222,39 @@ type Repository interface {
 	UnmarshalRecords(records []Record) (events []Event, err error)
 }
 
+// RepositoryOption is used for configuring optional features of the repository
+type RepositoryOption func(repo *repository)
+
 // NewRepository returns a new repository
-func NewRepository(store Store, serializer Serializer) Repository {
-	return &repository{
+func NewRepository(store Store, serializer Serializer, opts ...RepositoryOption) Repository {
+	repo := &repository{
 		store:                store,
 		serializer:           serializer,
 		notificationServices: []NotificationService{},
+		dispatcher:           NewDispatcher(),
+	}
+
+	for _, opt := range opts {
+		opt(repo)
 	}
+
+	return repo
 }
 
+// AddNotificationService adds a service which the saved records are sent to, unless the
+// store relays them itself, see RelayingStore
 func (repo *repository) AddNotificationService(service NotificationService) {
+	if StoreRelays(repo.store) {
+		log.Warnf("Notification service %T isn't used, the records are published by the store", service)
+	}
+
 	repo.notificationServices = append(repo.notificationServices, service)
 }
 
 // Record is a store row. The Data field contains the marshalled Event, and
 // Type is the type of event retrieved by reflect.TypeOf(event).
+// Version is the position of the record in the stream of its aggregate,
+// starting at 1, and is zero for records saved without a version.
 type Record struct {
 	AggregateID string `json:"aggregateId" dynamodbav:"aggregateId"`
 	SequenceID  string `json:"sequenceId" dynamodbav:"sequenceId"`
@@ -135,47 +262,100 @@ type Record struct {
 	UserID      string `json:"userId" dynamodbav:"userId"`
 	Data        []byte `json:"data" dynamodbav:"data"`
 	Timestamp   int64  `json:"timestamp" dynamodbav:"timestamp"`
+	Version     int64  `json:"version,omitempty" dynamodbav:"version,omitempty"`
+	// Metadata of the record, like the correlation ID, see WithMetadata
+	Metadata Metadata `json:"metadata,omitempty" dynamodbav:"metadata,omitempty"`
 }
 
 type repository struct {
 	store                Store
 	serializer           Serializer
 	notificationServices []NotificationService
+	dispatcher           *Dispatcher
+	upcasters            *Upcasters
+	typeRegistry         *TypeRegistry
+	snapshotStore        SnapshotStore
+	snapshotPolicy       SnapshotPolicy
+	newSnapshotter       func(aggregateID string) Snapshotter
+	cache                *aggregateCache
 }
 
 type transactionWrapper struct {
 	ctx                  context.Context
 	transaction          StoreTransaction
+	events               []Event
 	notificationServices []NotificationService
+	dispatcher           *Dispatcher
+	cache                *aggregateCache
+	saveSnapshots        func(ctx context.Context, records []Record)
 }
 
-func newTransactionWrapper(ctx context.Context, store Store, records []Record, ns []NotificationService) (StoreTransaction, error) {
-	transaction, err := store.NewTransaction(ctx, records...)
+func (repo *repository) newTransactionWrapper(ctx context.Context, events []Event, records []Record) (StoreTransaction, error) {
+	transaction, err := repo.store.NewTransaction(ctx, records...)
 	if err != nil {
 		return nil, err
 	}
 
-	return &transactionWrapper{ctx, transaction, ns}, nil
+	var saveSnapshots func(ctx context.Context, records []Record)
+	if repo.snapshotStore != nil && repo.newSnapshotter != nil {
+		saveSnapshots = repo.saveSnapshots
+	}
+
+	notificationServices := repo.notificationServices
+	if StoreRelays(repo.store) {
+		notificationServices = nil
+	}
+
+	return &transactionWrapper{ctx, transaction, events, notificationServices, repo.dispatcher, repo.cache, saveSnapshots}, nil
 }
 
-// Commit transaction to underlying store and, if configured, publish the records to a
-// notification service. If ErrNotificationFailed is returned, the data has been successfully
-// committed to the store, but the notification service failed.
+// Commit transaction to underlying store and, if configured, publish the records to the
+// notification services and save snapshots. If ErrNotificationFailed is returned, the data
+// has been successfully committed to the store, but some notifications failed, see NotificationError.
 func (transWrap *transactionWrapper) Commit() error {
 	err := transWrap.transaction.Commit()
 	if err != nil {
 		return err
 	}
 
-	for _, service := range transWrap.notificationServices {
-		for _, r := range transWrap.transaction.GetRecords() {
-			if err = service.SendWithContext(transWrap.ctx, r); err != nil {
-				return fmt.Errorf("%w: %s", ErrNotificationFailed, err)
-			}
+	records := transWrap.transaction.GetRecords()
+	transWrap.setVersions(records)
+	transWrap.invalidateCache(records)
+
+	err = transWrap.dispatcher.Dispatch(transWrap.ctx, transWrap.notificationServices, records)
+
+	if transWrap.saveSnapshots != nil {
+		transWrap.saveSnapshots(transWrap.ctx, records)
+	}
+
+	return err
+}
+
+// setVersions updates the saved events with the versions assigned by the store
+func (transWrap *transactionWrapper) setVersions(records []Record) {
+	if len(records) != len(transWrap.events) {
+		return
+	}
+
+	for i, record := range records {
+		if record.Version != 0 {
+			transWrap.events[i].SetVersion(record.Version)
 		}
 	}
+}
 
-	return nil
+// invalidateCache removes the aggregates of the saved records from the aggregate cache
+func (transWrap *transactionWrapper) invalidateCache(records []Record) {
+	if transWrap.cache == nil {
+		return
+	}
+
+	aggregateIDs := make([]string, len(records))
+	for i, record := range records {
+		aggregateIDs[i] = record.AggregateID
+	}
+
+	transWrap.cache.invalidate(aggregateIDs...)
 }
 
 func (transWrap *transactionWrapper) Rollback() error {
@@ -212,6 +392,50 @@ func (repo *repository) Save(ctx context.Context, events ...Event) error {
 		return err
 	}
 
+	return commit(tx)
+}
+
+// SaveWithExpectedVersion persists the events to the repo if the aggregate is at the expected version
+func (repo *repository) SaveWithExpectedVersion(ctx context.Context, aggregateID string, expectedVersion int64, events ...Event) error {
+	for _, event := range events {
+		if event.GetAggregateID() != aggregateID {
+			return errors.Errorf("event for aggregate %s can't be saved to aggregate %s", event.GetAggregateID(), aggregateID)
+		}
+	}
+
+	if len(events) == 0 {
+		return repo.checkVersion(ctx, aggregateID, expectedVersion)
+	}
+
+	records, err := repo.marshalRecords(ctx, events, expectedVersion+1)
+	if err != nil {
+		return err
+	}
+
+	tx, err := repo.newTransactionWrapper(ctx, events, records)
+	if err != nil {
+		return err
+	}
+
+	return commit(tx)
+}
+
+// checkVersion returns ErrConcurrencyConflict unless the aggregate is at the expected version.
+// There is no record to let the store check the version on commit, so the history is loaded.
+func (repo *repository) checkVersion(ctx context.Context, aggregateID string, expectedVersion int64) error {
+	history, err := repo.store.LoadByAggregate(ctx, aggregateID)
+	if err != nil {
+		return err
+	}
+
+	if _, version := recordsAfterVersion(history, 0); version != expectedVersion {
+		return ErrConcurrencyConflict
+	}
+
+	return nil
+}
+
+func commit(tx StoreTransaction) error {
 	if err := tx.Commit(); err != nil {
 		rollbackErr := tx.Rollback()
 		if rollbackErr != nil {
@@ -225,47 +449,151 @@ func (repo *repository) Save(ctx context.Context, events ...Event) error {
 }
 
 func (repo *repository) SaveTransaction(ctx context.Context, events ...Event) (StoreTransaction, error) {
+	records, err := repo.marshalRecords(ctx, events, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	return repo.newTransactionWrapper(ctx, events, records)
+}
+
+// marshalRecords creates the records to store for the events. The events are given versions
+// in sequence from firstVersion, or if firstVersion is zero, the store will assign the versions.
+// The records get the metadata of the context.
+func (repo *repository) marshalRecords(ctx context.Context, events []Event, firstVersion int64) ([]Record, error) {
 	records := []Record{}
+	metadata := MetadataFromContext(ctx)
+
+	for i, event := range events {
+		var version int64
+		if firstVersion > 0 {
+			version = firstVersion + int64(i)
+		}
 
-	for _, event := range events {
 		event.SetSequenceID(NewULID())
+		event.SetVersion(version)
 
 		if event.GetTimestamp() == 0 {
 			event.SetTimestamp(time.Now().UnixNano())
 		}
 
+		eventType, err := repo.typeName(event)
+		if err != nil {
+			return nil, err
+		}
+
 		data, err := repo.serializer.Marshal(event)
 		if err != nil {
 			return nil, err
 		}
 
+		userID := event.GetUserID()
+		if userIDSerializer, ok := repo.serializer.(UserIDSerializer); ok {
+			userID = userIDSerializer.RecordUserID(event)
+		}
+
 		records = append(records, Record{
 			AggregateID: event.GetAggregateID(),
 			SequenceID:  event.GetSequenceID(),
 			Timestamp:   event.GetTimestamp(),
-			Type:        GetTypeName(event),
+			Type:        eventType,
 			Data:        data,
-			UserID:      event.GetUserID(),
+			UserID:      userID,
+			Version:     version,
+			Metadata:    metadata,
 		})
 	}
 
-	return newTransactionWrapper(ctx, repo.store, records, repo.notificationServices)
+	return records, nil
+}
+
+// typeName returns the Record.Type of the event, using the registered name if the
+// repository has a type registry
+func (repo *repository) typeName(event Event) (string, error) {
+	name := GetTypeName(event)
+
+	if repo.typeRegistry != nil {
+		var err error
+		if name, err = repo.typeRegistry.Name(event); err != nil {
+			return "", err
+		}
+	}
+
+	return RecordType(name, schemaVersion(event)), nil
 }
 
 // Load rehydrates the repo
 func (repo repository) Load(ctx context.Context, aggregateID string, aggr Aggregate) (deleted bool, err error) {
-	history, err := repo.store.LoadByAggregate(ctx, aggregateID)
+	if cached, deleted, err := repo.loadCached(ctx, aggregateID, aggr); cached {
+		return deleted, err
+	}
+
+	loaded, deleted, err := repo.loadStored(ctx, aggregateID, aggr)
+	if deleted || err != nil {
+		return deleted, err
+	}
+
+	if repo.cache != nil {
+		repo.cache.put(aggregateID, aggr, loaded.version)
+	}
+
+	return false, nil
+}
+
+// loadedAggregate is an aggregate loaded from the store, from its latest snapshot if it has one
+type loadedAggregate struct {
+	// history is the records applied after the snapshot
+	history []Record
+	// version is the version of the aggregate after applying the history
+	version int64
+}
+
+// loadStored loads the aggregate from its latest snapshot, if any, and replays the records
+// with a later version. The version is used rather than the sequence ID, as the records
+// may be committed in another order than their sequence IDs were created.
+func (repo repository) loadStored(ctx context.Context, aggregateID string, aggr Aggregate) (loaded loadedAggregate, deleted bool, err error) {
+	snapshotter, snapshot, err := repo.loadSnapshot(ctx, aggregateID, aggr)
+	if err != nil {
+		return loaded, false, err
+	}
+
+	var opts []QueryOption
+	if snapshot != nil {
+		opts = append(opts, ByVersion(snapshot.Version))
+	}
+
+	history, err := repo.store.LoadByAggregate(ctx, aggregateID, opts...)
 	if err != nil {
-		return false, err
+		return loaded, false, err
 	}
 
-	if len(history) == 0 {
-		return false, ErrNoHistory
+	if len(history) == 0 && snapshot == nil {
+		return loaded, false, ErrNoHistory
 	}
 
 	aggr.SetAggregateID(aggregateID)
 
+	if snapshot != nil {
+		if err = snapshotter.UnmarshalSnapshot(snapshot.Data); err != nil {
+			return loaded, false, errors.Wrap(err, "failed to unmarshal snapshot")
+		}
+
+		loaded.version = snapshot.Version
+	}
+
+	loaded.history, loaded.version = recordsAfterVersion(history, loaded.version)
+	deleted, err = repo.replay(ctx, aggr, loaded.history)
+
+	return loaded, deleted, err
+}
+
+// replay applies the events of the records to the aggregate
+func (repo repository) replay(ctx context.Context, aggr Aggregate, history []Record) (deleted bool, err error) {
 	for _, record := range history {
+		if record, err = repo.upcast(record); err != nil {
+			return false, err
+		}
+
 		var event Event
 		event, err = repo.serializer.Unmarshal(record.Data, record.Type)
 
@@ -279,6 +607,10 @@ func (repo repository) Load(ctx context.Context, aggregateID string, aggr Aggreg
 			event.SetTimestamp(record.Timestamp)
 		}
 
+		if record.Version != 0 {
+			event.SetVersion(record.Version)
+		}
+
 		err = aggr.On(ctx, event)
 
 		if errors.Is(err, ErrDeleted) {
@@ -293,16 +625,52 @@ func (repo repository) Load(ctx context.Context, aggregateID string, aggr Aggreg
 	return false, nil
 }
 
+// recordsAfterVersion returns the records with a version greater than the given one, for
+// stores that don't support ByVersion, and the version of the aggregate after them. Records
+// stored by earlier releases have no version, and follow the version of the record before them.
+func recordsAfterVersion(records []Record, version int64) ([]Record, int64) {
+	var (
+		after   []Record
+		current int64
+		last    = version
+	)
+
+	for _, record := range records {
+		if record.Version != 0 {
+			current = record.Version
+		} else {
+			current++
+		}
+
+		if current > version {
+			after = append(after, record)
+			last = max(last, current)
+		}
+	}
+
+	return after, last
+}
+
+// recordsAfter skips records up to the given sequence ID, for stores that don't support BySequenceID
+func recordsAfter(records []Record, sequenceID string) []Record {
+	for i, record := range records {
+		if record.SequenceID > sequenceID {
+			return records[i:]
+		}
+	}
+
+	return nil
+}
+
 func (repo repository) UnmarshalRecords(records []Record) ([]Event, error) {
-	return unmarshalRecords(repo.serializer, records)
+	return repo.unmarshalRecords(records)
 }
 
-func unmarshalRecords(serializer Serializer, records []Record) (events []Event, err error) {
+func (repo repository) unmarshalRecords(records []Record) (events []Event, err error) {
 	for _, record := range records {
 		var event Event
 
-		if event, err = serializer.Unmarshal(record.Data, record.Type); err != nil {
-			err = errors.Wrap(err, "failed to unmarshal record")
+		if event, err = repo.unmarshalRecord(record); err != nil {
 			return
 		}
 
@@ -312,6 +680,39 @@ func unmarshalRecords(serializer Serializer, records []Record) (events []Event,
 	return
 }
 
+func (repo repository) unmarshalRecord(record Record) (Event, error) {
+	record, err := repo.upcast(record)
+	if err != nil {
+		return nil, err
+	}
+
+	event, err := repo.serializer.Unmarshal(record.Data, record.Type)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal record")
+	}
+
+	if record.Version != 0 {
+		event.SetVersion(record.Version)
+	}
+
+	return event, nil
+}
+
+// upcast upgrades the record to the current shape of its event, and strips the schema
+// version from its type, as expected by the serializer
+func (repo repository) upcast(record Record) (Record, error) {
+	if repo.upcasters != nil {
+		var err error
+		if record, err = repo.upcasters.Upcast(record); err != nil {
+			return record, err
+		}
+	}
+
+	record.Type, _ = ParseRecordType(record.Type)
+
+	return record, nil
+}
+
 func (repo repository) LoadEvents(ctx context.Context, opts ...QueryOption) (events []Event, err error) {
 	var records []Record
 
@@ -319,7 +720,23 @@ func (repo repository) LoadEvents(ctx context.Context, opts ...QueryOption) (eve
 		return
 	}
 
-	return unmarshalRecords(repo.serializer, records)
+	return repo.unmarshalRecords(records)
+}
+
+func (repo repository) IterEvents(ctx context.Context, opts ...QueryOption) iter.Seq2[Event, error] {
+	return func(yield func(Event, error) bool) {
+		for record, err := range IterRecords(ctx, repo.store, opts...) {
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+
+			event, err := repo.unmarshalRecord(record)
+			if !yield(event, err) || err != nil {
+				return
+			}
+		}
+	}
 }
 
 // Deprecated
@@ -330,18 +747,107 @@ func (repo repository) GetEventsBySequenceID(ctx context.Context, sequenceID str
 		return
 	}
 
-	return unmarshalRecords(repo.serializer, records)
+	return repo.unmarshalRecords(records)
 }
 
 // Deprecated
 func (repo repository) GetEventsBySequenceIDAndType(ctx context.Context, sequenceID string, eventType Event, opts ...QueryOption) (events []Event, err error) {
+	typeName, err := repo.typeName(eventType)
+	if err != nil {
+		return nil, err
+	}
+
+	name, version := ParseRecordType(typeName)
+	limit := queryLimit(opts)
+
 	var records []Record
 
-	if records, err = repo.store.LoadBySequenceIDAndType(ctx, sequenceID, GetTypeName(eventType), opts...); err != nil {
-		return
+	for _, recordType := range repo.storedTypes(name, version) {
+		stored, err := repo.store.LoadBySequenceIDAndType(ctx, sequenceID, recordType, opts...)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, record := range stored {
+			if record, err = repo.upcastTo(record, name, version); err != nil {
+				return nil, err
+			}
+
+			if record.Type != "" {
+				records = append(records, record)
+			}
+		}
+	}
+
+	slices.SortFunc(records, func(a, b Record) int {
+		return strings.Compare(a.SequenceID, b.SequenceID)
+	})
+
+	// Each type is loaded up to the limit, so a record which wasn't loaded comes after limit
+	// records of its type, and the first limit records are complete for the next page to follow
+	if limit > 0 && len(records) > limit {
+		records = records[:limit]
+	}
+
+	return repo.unmarshalRecords(records)
+}
+
+// storedTypes returns the record types which may be upcasted to the event type at the
+// schema version, which are its older schema versions and the types renamed to it
+func (repo repository) storedTypes(eventType string, version int) []string {
+	types := []string{}
+	for v := 1; v <= version; v++ {
+		types = append(types, RecordType(eventType, v))
+	}
+
+	if repo.upcasters != nil {
+		types = append(types, repo.upcasters.renamedFrom(eventType)...)
+	}
+
+	slices.Sort(types)
+
+	return slices.Compact(types)
+}
+
+// limitProbe finds the limit set by WithLimit
+type limitProbe struct {
+	limit int
+}
+
+func (p *limitProbe) LimitRecords(limit int) {
+	p.limit = limit
+}
+
+// queryLimit returns the limit set by the options, or zero if they don't limit the records
+func queryLimit(opts []QueryOption) int {
+	probe := &limitProbe{}
+	for _, opt := range opts {
+		opt(probe)
+	}
+
+	return probe.limit
+}
+
+// upcastTo upcasts the record, returning it if it is of the event type afterwards and
+// an empty record if not. It fails if the record isn't upcasted to the schema version.
+func (repo repository) upcastTo(record Record, eventType string, version int) (Record, error) {
+	if repo.upcasters != nil {
+		var err error
+		if record, err = repo.upcasters.Upcast(record); err != nil {
+			return Record{}, err
+		}
+	}
+
+	upcastedType, upcastedVersion := ParseRecordType(record.Type)
+	if upcastedType != eventType {
+		return Record{}, nil
+	}
+
+	if upcastedVersion != version {
+		return Record{}, errors.Errorf("record %s of type %s isn't upcasted to schema version %d", record.SequenceID, record.Type, version)
 	}
 
-	return unmarshalRecords(repo.serializer, records)
+	return record, nil
 }
 
 // Deprecated
@@ -352,5 +858,5 @@ func (repo repository) GetEventsByTimestamp(ctx context.Context, timestamp int64
 		return
 	}
 
-	return unmarshalRecords(repo.serializer, records)
+	return repo.unmarshalRecords(records)
 }
@@ -235,6 +235,7 @@ func Test_RepoSaveSuccess(t *testing.T) {
 		return len(rs) == 1 && matchRecord(rs[0], testEvent, testData)
 	})).Return(storeTransactionMock, nil).Once()
 	storeTransactionMock.On("Commit").Return(nil).Once()
+	storeTransactionMock.On("GetRecords").Return([]Record{{AggregateID: testEvent.AggregateID, Version: 7}}).Once()
 
 	repo := NewRepository(storeMock, serializerMock)
 	err := repo.Save(ctx, testEvent)
@@ -243,6 +244,7 @@ func Test_RepoSaveSuccess(t *testing.T) {
 	storeMock.AssertExpectations(t)
 	storeTransactionMock.AssertExpectations(t)
 	assert.Nil(t, err)
+	assert.Equal(t, int64(7), testEvent.Version)
 }
 
 func Test_RepoSaveSuccessNotification(t *testing.T) {
@@ -256,7 +258,7 @@ func Test_RepoSaveSuccessNotification(t *testing.T) {
 	storeMock.On("NewTransaction", ctx, mock.MatchedBy(func(rs []Record) bool {
 		return len(rs) == 1 && matchRecord(rs[0], testEvent, testData)
 	})).Return(storeTransactionMock, nil).Once()
-	storeTransactionMock.On("GetRecords").Return([]Record{{UserID: testEvent.UserID, AggregateID: testEvent.AggregateID, Data: testData}}).Twice()
+	storeTransactionMock.On("GetRecords").Return([]Record{{UserID: testEvent.UserID, AggregateID: testEvent.AggregateID, Data: testData}}).Once()
 	storeTransactionMock.On("Commit").Return(nil).Once()
 	notificationService.On("SendWithContext", ctx, mock.MatchedBy(func(r Record) bool {
 		return matchRecord(r, testEvent, testData)
@@ -274,6 +276,35 @@ func Test_RepoSaveSuccessNotification(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+type relayingStore struct {
+	*StoreMock
+}
+
+func (relayingStore) Relays() bool {
+	return true
+}
+
+func Test_RepoSaveRelayingStore(t *testing.T) {
+	storeMock, storeTransactionMock, serializerMock, _ := setupMocks()
+	testEvent, testData := createMockDataForSave()
+	notificationService := CreateNotificationServiceMock()
+
+	ctx := context.TODO()
+
+	serializerMock.On("Marshal", testEvent).Return(testData, nil)
+	storeMock.On("NewTransaction", ctx, mock.Anything).Return(storeTransactionMock, nil).Once()
+	storeTransactionMock.On("GetRecords").Return([]Record{{AggregateID: testEvent.AggregateID, Data: testData}})
+	storeTransactionMock.On("Commit").Return(nil).Once()
+
+	repo := NewRepository(WrapStore(relayingStore{storeMock}), serializerMock)
+	repo.AddNotificationService(notificationService)
+	err := repo.Save(ctx, testEvent)
+
+	assert.NoError(t, err)
+	storeTransactionMock.AssertExpectations(t)
+	notificationService.AssertNotCalled(t, "SendWithContext", mock.Anything, mock.Anything)
+}
+
 func Test_RepoSaveFailNoNotification(t *testing.T) {
 	storeMock, storeTransactionMock, serializerMock, _ := setupMocks()
 	testEvent, testData := createMockDataForSave()
@@ -409,3 +440,142 @@ func TestSaveTransaction_TimestampZero(t *testing.T) {
 	assert.NoError(t, err)
 	assert.NotEqual(t, 0, event.Timestamp)
 }
+
+func Test_RepoSaveWithExpectedVersion(t *testing.T) {
+	storeMock, storeTransactionMock, serializerMock, _ := setupMocks()
+	testEvent, testData := createMockDataForSave()
+	otherEvent := &BaseEvent{AggregateID: testEvent.AggregateID, UserID: testEvent.UserID}
+
+	ctx := context.TODO()
+
+	serializerMock.On("Marshal", mock.Anything).Return(testData, nil)
+	storeMock.On("NewTransaction", ctx, mock.MatchedBy(func(rs []Record) bool {
+		return len(rs) == 2 && rs[0].Version == 4 && rs[1].Version == 5
+	})).Return(storeTransactionMock, nil).Once()
+	storeTransactionMock.On("Commit").Return(nil).Once()
+	storeTransactionMock.On("GetRecords").Return([]Record{{Version: 4}, {Version: 5}}).Once()
+
+	repo := NewRepository(storeMock, serializerMock)
+	err := repo.SaveWithExpectedVersion(ctx, testEvent.AggregateID, 3, testEvent, otherEvent)
+
+	serializerMock.AssertExpectations(t)
+	storeMock.AssertExpectations(t)
+	storeTransactionMock.AssertExpectations(t)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), testEvent.Version)
+	assert.Equal(t, int64(5), otherEvent.Version)
+}
+
+func Test_RepoSaveWithExpectedVersion_OtherAggregate(t *testing.T) {
+	storeMock, _, serializerMock, _ := setupMocks()
+	testEvent, _ := createMockDataForSave()
+
+	repo := NewRepository(storeMock, serializerMock)
+	err := repo.SaveWithExpectedVersion(context.TODO(), "other", 0, testEvent)
+
+	serializerMock.AssertExpectations(t)
+	storeMock.AssertExpectations(t)
+	assert.EqualError(t, err, "event for aggregate 123 can't be saved to aggregate other")
+}
+
+func Test_RepoSaveWithExpectedVersion_Conflict(t *testing.T) {
+	storeMock, storeTransactionMock, serializerMock, _ := setupMocks()
+	testEvent, testData := createMockDataForSave()
+
+	ctx := context.TODO()
+
+	serializerMock.On("Marshal", testEvent).Return(testData, nil)
+	storeMock.On("NewTransaction", ctx, mock.Anything).Return(storeTransactionMock, nil).Once()
+	storeTransactionMock.On("Commit").Return(ErrConcurrencyConflict).Once()
+	storeTransactionMock.On("Rollback").Return(nil).Once()
+
+	repo := NewRepository(storeMock, serializerMock)
+	err := repo.SaveWithExpectedVersion(ctx, testEvent.AggregateID, 0, testEvent)
+
+	storeTransactionMock.AssertExpectations(t)
+	assert.ErrorIs(t, err, ErrConcurrencyConflict)
+}
+
+func Test_RepoSaveWithExpectedVersion_NoEvents(t *testing.T) {
+	storeMock, _, serializerMock, _ := setupMocks()
+
+	ctx := context.TODO()
+	id := "aggregate"
+	history := []Record{{AggregateID: id, Version: 1}, {AggregateID: id, Version: 2}}
+
+	storeMock.On("LoadByAggregate", ctx, id, []QueryOption(nil)).Return(history, nil)
+
+	repo := NewRepository(storeMock, serializerMock)
+
+	assert.NoError(t, repo.SaveWithExpectedVersion(ctx, id, 2))
+	assert.ErrorIs(t, repo.SaveWithExpectedVersion(ctx, id, 1), ErrConcurrencyConflict)
+	assert.ErrorIs(t, repo.SaveWithExpectedVersion(ctx, id, 3), ErrConcurrencyConflict)
+
+	storeMock.AssertNotCalled(t, "NewTransaction", mock.Anything, mock.Anything)
+}
+
+func Test_RepoIterEvents(t *testing.T) {
+	storeMock, _, serializerMock, _ := setupMocks()
+
+	history, baseEvent, _ := createMockDataForLoadAggregate()
+	otherEvent := &OtherEvent{BaseEvent: baseEvent, OtherEventField: 42}
+
+	ctx := context.TODO()
+	storeMock.On("Load", ctx, []QueryOption(nil)).Return(history, nil)
+	serializerMock.On("Unmarshal", []byte{byte(2)}, "BaseEvent").Return(baseEvent, nil).Once()
+	serializerMock.On("Unmarshal", []byte{byte(0)}, "BaseEvent").Return(otherEvent, nil).Once()
+
+	repo := NewRepository(storeMock, serializerMock)
+
+	var events []Event
+	for event, err := range repo.IterEvents(ctx) {
+		assert.NoError(t, err)
+
+		if events = append(events, event); len(events) == 2 {
+			break
+		}
+	}
+
+	serializerMock.AssertExpectations(t)
+	storeMock.AssertExpectations(t)
+	assert.Equal(t, []Event{baseEvent, otherEvent}, events)
+}
+
+func Test_RepoIterEvents_UnmarshalErr(t *testing.T) {
+	storeMock, _, serializerMock, _ := setupMocks()
+
+	history, _, _ := createMockDataForLoadAggregate()
+
+	ctx := context.TODO()
+	storeMock.On("Load", ctx, []QueryOption(nil)).Return(history, nil)
+	serializerMock.On("Unmarshal", []byte{byte(2)}, "BaseEvent").Return(&BaseEvent{}, errors.New("bad data")).Once()
+
+	repo := NewRepository(storeMock, serializerMock)
+
+	var errs []error
+	for _, err := range repo.IterEvents(ctx) {
+		errs = append(errs, err)
+	}
+
+	serializerMock.AssertExpectations(t)
+	assert.Len(t, errs, 1)
+	assert.EqualError(t, errs[0], "failed to unmarshal record: bad data")
+}
+
+func Test_CollectRecords(t *testing.T) {
+	history := createMockHistory()
+	testErr := errors.New("failed")
+
+	records, err := CollectRecords(func(yield func(Record, error) bool) {
+		for _, record := range history[:2] {
+			if !yield(record, nil) {
+				return
+			}
+		}
+
+		yield(Record{}, testErr)
+	})
+
+	assert.Equal(t, testErr, err)
+	assert.Equal(t, history[:2], records)
+}
@@ -0,0 +1,137 @@
+package avro
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/hamba/avro/v2"
+	"github.com/pkg/errors"
+)
+
+const schemaFileExtension = ".avsc"
+
+// ErrUnknownSchema is returned by SchemaRegistry.Schema() when no schema is registered with the fingerprint
+var ErrUnknownSchema = errors.New("unknown schema fingerprint")
+
+// SchemaRegistry stores the schemas the events were written with, so they can be read
+// after the events have changed
+type SchemaRegistry interface {
+	// Register the schema and return its CRC-64-AVRO fingerprint
+	Register(schema avro.Schema) (uint64, error)
+	// Schema returns the schema registered with the fingerprint
+	Schema(fingerprint uint64) (avro.Schema, error)
+}
+
+// Fingerprint returns the CRC-64-AVRO fingerprint of the schema
+func Fingerprint(schema avro.Schema) (uint64, error) {
+	fingerprint, err := schema.FingerprintUsing(avro.CRC64Avro)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to fingerprint schema")
+	}
+
+	return binary.BigEndian.Uint64(fingerprint), nil
+}
+
+type memoryRegistry struct {
+	mutex   sync.RWMutex
+	schemas map[uint64]avro.Schema
+}
+
+// NewMemoryRegistry creates a schema registry which only keeps the schemas in memory
+func NewMemoryRegistry() SchemaRegistry {
+	return &memoryRegistry{schemas: map[uint64]avro.Schema{}}
+}
+
+func (r *memoryRegistry) Register(schema avro.Schema) (uint64, error) {
+	fingerprint, err := Fingerprint(schema)
+	if err != nil {
+		return 0, err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.schemas[fingerprint] = schema
+
+	return fingerprint, nil
+}
+
+func (r *memoryRegistry) Schema(fingerprint uint64) (avro.Schema, error) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	schema, ok := r.schemas[fingerprint]
+	if !ok {
+		return nil, errors.Wrapf(ErrUnknownSchema, "%016x", fingerprint)
+	}
+
+	return schema, nil
+}
+
+type fileRegistry struct {
+	memoryRegistry
+	dir string
+}
+
+// NewFileRegistry creates a schema registry which stores the schemas as .avsc files in
+// the directory, named by their fingerprints. The schemas already in the directory are loaded.
+func NewFileRegistry(dir string) (SchemaRegistry, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil { // nolint:gosec,mnd
+		return nil, errors.Wrap(err, "failed to create schema directory")
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*"+schemaFileExtension))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to list schema files")
+	}
+
+	registry := &fileRegistry{
+		memoryRegistry: memoryRegistry{schemas: map[uint64]avro.Schema{}},
+		dir:            dir,
+	}
+
+	for _, file := range files {
+		data, err := os.ReadFile(file) // nolint:gosec
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read schema file %s", file)
+		}
+
+		schema, err := avro.ParseBytes(data)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse schema file %s", file)
+		}
+
+		if _, err = registry.memoryRegistry.Register(schema); err != nil {
+			return nil, err
+		}
+	}
+
+	return registry, nil
+}
+
+func (r *fileRegistry) Register(schema avro.Schema) (uint64, error) {
+	fingerprint, err := Fingerprint(schema)
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err = r.memoryRegistry.Schema(fingerprint); err == nil {
+		return fingerprint, nil
+	}
+
+	data, err := json.Marshal(schema)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to marshal schema")
+	}
+
+	file := filepath.Join(r.dir, fmt.Sprintf("%016x%s", fingerprint, schemaFileExtension))
+	if err = os.WriteFile(file, data, 0o644); err != nil { // nolint:gosec,mnd
+		return 0, errors.Wrapf(err, "failed to write schema file %s", file)
+	}
+
+	return r.memoryRegistry.Register(schema)
+}
@@ -0,0 +1,214 @@
+package avro
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"sync"
+
+	"github.com/hamba/avro/v2"
+	"github.com/pkg/errors"
+
+	"github.com/SKF/go-eventsource/v2/eventsource"
+)
+
+// magic is the marker of the Avro single object encoding, followed by the
+// little-endian CRC-64-AVRO fingerprint of the writer schema and the payload
+var magic = []byte{0xc3, 0x01}
+
+const headerSize = 10
+
+// Event is an event with the Avro schema of its current shape. The fields of the schema
+// are matched with the `avro` struct tags or the field names.
+type Event interface {
+	eventsource.Event
+	AvroSchema() string
+}
+
+type eventSchema struct {
+	schema      avro.Schema
+	fingerprint uint64
+}
+
+type resolveKey struct {
+	eventType   reflect.Type
+	fingerprint uint64
+}
+
+// serializer marshals events with their Avro schemas, prefixed with the schema fingerprints
+type serializer struct {
+	schemas      SchemaRegistry
+	eventTypes   map[string]reflect.Type
+	typeRegistry *eventsource.TypeRegistry
+
+	mutex    sync.RWMutex
+	readers  map[reflect.Type]eventSchema
+	resolved map[resolveKey]avro.Schema
+}
+
+// NewSerializer returns a serializer for the events, registering their schemas in the registry
+func NewSerializer(schemas SchemaRegistry, events ...Event) (eventsource.Serializer, error) {
+	s := newSerializer(schemas)
+
+	for _, event := range events {
+		eventType := eventsource.GetType(event)
+		if _, err := s.schema(eventType); err != nil {
+			return nil, err
+		}
+
+		s.eventTypes[eventType.Name()] = eventType
+	}
+
+	return s, nil
+}
+
+// NewSerializerWithRegistry returns a serializer looking up the event types by their
+// names and aliases in the type registry. The event types have to implement Event.
+func NewSerializerWithRegistry(schemas SchemaRegistry, registry *eventsource.TypeRegistry) eventsource.Serializer {
+	s := newSerializer(schemas)
+	s.typeRegistry = registry
+
+	return s
+}
+
+func newSerializer(schemas SchemaRegistry) *serializer {
+	return &serializer{
+		schemas:    schemas,
+		eventTypes: map[string]reflect.Type{},
+		readers:    map[reflect.Type]eventSchema{},
+		resolved:   map[resolveKey]avro.Schema{},
+	}
+}
+
+// Unmarshal decodes the data with the schema it was written with, resolved to the
+// current schema of the event type
+func (s *serializer) Unmarshal(data []byte, eventType string) (eventsource.Event, error) {
+	recordType, err := s.lookup(eventType)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < headerSize || !bytes.HasPrefix(data, magic) {
+		return nil, errors.New("data isn't Avro single object encoded")
+	}
+
+	schema, err := s.resolve(recordType, binary.LittleEndian.Uint64(data[len(magic):headerSize]))
+	if err != nil {
+		return nil, err
+	}
+
+	event := reflect.New(recordType)
+	if err = avro.Unmarshal(schema, data[headerSize:], event.Interface()); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal event")
+	}
+
+	out, ok := event.Elem().Interface().(eventsource.Event)
+	if !ok {
+		return nil, errors.New("event doesn't implement struct Event")
+	}
+
+	return out, nil
+}
+
+// Marshal encodes the event with its schema
+func (s *serializer) Marshal(event eventsource.Event) ([]byte, error) {
+	writer, err := s.schema(eventsource.GetType(event))
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := avro.Marshal(writer.schema, event)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal event")
+	}
+
+	data := make([]byte, headerSize, headerSize+len(payload))
+	copy(data, magic)
+	binary.LittleEndian.PutUint64(data[len(magic):], writer.fingerprint)
+
+	return append(data, payload...), nil
+}
+
+func (s *serializer) lookup(eventType string) (reflect.Type, error) {
+	if s.typeRegistry != nil {
+		return s.typeRegistry.Type(eventType) // nolint:wrapcheck
+	}
+
+	recordType, ok := s.eventTypes[eventType]
+	if !ok {
+		return nil, errors.Errorf("Unmarshal error, unbound event type, %v", eventType)
+	}
+
+	return recordType, nil
+}
+
+// schema returns the current schema of the event type, parsing and registering it the first time
+func (s *serializer) schema(eventType reflect.Type) (eventSchema, error) {
+	s.mutex.RLock()
+	reader, ok := s.readers[eventType]
+	s.mutex.RUnlock()
+
+	if ok {
+		return reader, nil
+	}
+
+	event, ok := reflect.New(eventType).Interface().(Event)
+	if !ok {
+		return eventSchema{}, errors.Errorf("event %s doesn't have an Avro schema", eventType.Name())
+	}
+
+	schema, err := avro.Parse(event.AvroSchema())
+	if err != nil {
+		return eventSchema{}, errors.Wrapf(err, "failed to parse schema of event %s", eventType.Name())
+	}
+
+	fingerprint, err := s.schemas.Register(schema)
+	if err != nil {
+		return eventSchema{}, errors.Wrapf(err, "failed to register schema of event %s", eventType.Name())
+	}
+
+	reader = eventSchema{schema: schema, fingerprint: fingerprint}
+
+	s.mutex.Lock()
+	s.readers[eventType] = reader
+	s.mutex.Unlock()
+
+	return reader, nil
+}
+
+// resolve returns the schema reading data written with the fingerprinted schema into the event type
+func (s *serializer) resolve(eventType reflect.Type, fingerprint uint64) (avro.Schema, error) {
+	reader, err := s.schema(eventType)
+	if err != nil {
+		return nil, err
+	}
+
+	if reader.fingerprint == fingerprint {
+		return reader.schema, nil
+	}
+
+	key := resolveKey{eventType: eventType, fingerprint: fingerprint}
+
+	s.mutex.RLock()
+	resolved, ok := s.resolved[key]
+	s.mutex.RUnlock()
+
+	if ok {
+		return resolved, nil
+	}
+
+	writer, err := s.schemas.Schema(fingerprint)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get writer schema")
+	}
+
+	if resolved, err = avro.NewSchemaCompatibility().Resolve(reader.schema, writer); err != nil {
+		return nil, errors.Wrapf(err, "failed to resolve schema %016x of event %s", fingerprint, eventType.Name())
+	}
+
+	s.mutex.Lock()
+	s.resolved[key] = resolved
+	s.mutex.Unlock()
+
+	return resolved, nil
+}
@@ -0,0 +1,173 @@
+package avro_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/SKF/go-eventsource/v2/eventsource"
+	"github.com/SKF/go-eventsource/v2/eventsource/serializers/avro"
+)
+
+const baseEventFields = `
+	{"name": "aggregateId", "type": "string"},
+	{"name": "userId", "type": "string"},
+	{"name": "sequenceId", "type": "string"},
+	{"name": "timestamp", "type": "long"},
+	{"name": "version", "type": "long"}`
+
+// OrderCreatedV1 is the first shape of OrderCreated
+type OrderCreatedV1 struct {
+	*eventsource.BaseEvent
+	OrderID  string `avro:"orderId"`
+	Quantity int    `avro:"quantity"`
+}
+
+func (OrderCreatedV1) AvroSchema() string {
+	return `{"type": "record", "name": "OrderCreated", "fields": [` + baseEventFields + `,
+		{"name": "orderId", "type": "string"},
+		{"name": "quantity", "type": "int"}
+	]}`
+}
+
+// OrderCreated has the quantity widened to a long and a currency with a default added
+type OrderCreated struct {
+	*eventsource.BaseEvent
+	OrderID  string `avro:"orderId"`
+	Quantity int64  `avro:"quantity"`
+	Currency string `avro:"currency"`
+}
+
+func (OrderCreated) AvroSchema() string {
+	return `{"type": "record", "name": "OrderCreated", "fields": [` + baseEventFields + `,
+		{"name": "orderId", "type": "string"},
+		{"name": "quantity", "type": "long"},
+		{"name": "currency", "type": "string", "default": "SEK"}
+	]}`
+}
+
+type UntypedEvent struct {
+	*eventsource.BaseEvent
+}
+
+func createBaseEvent() *eventsource.BaseEvent {
+	return &eventsource.BaseEvent{
+		AggregateID: "aggregate",
+		UserID:      "user",
+		SequenceID:  "sequence",
+		Timestamp:   1700000000000000000,
+		Version:     3,
+	}
+}
+
+func TestSerializer(t *testing.T) {
+	t.Parallel()
+
+	serializer, err := avro.NewSerializer(avro.NewMemoryRegistry(), OrderCreated{})
+	require.NoError(t, err)
+
+	event := OrderCreated{BaseEvent: createBaseEvent(), OrderID: "order", Quantity: 2, Currency: "EUR"}
+
+	data, err := serializer.Marshal(event)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0xc3, 0x01}, data[:2], "Single object encoding marker")
+
+	unmarshalled, err := serializer.Unmarshal(data, "OrderCreated")
+	require.NoError(t, err)
+	assert.Equal(t, event, unmarshalled)
+
+	_, err = serializer.Unmarshal(data, "OrderPlaced")
+	require.EqualError(t, err, "Unmarshal error, unbound event type, OrderPlaced")
+
+	_, err = serializer.Unmarshal([]byte(`{"orderId": "order"}`), "OrderCreated")
+	require.Error(t, err, "Legacy JSON data")
+}
+
+func TestSerializer_ReadsOldSchema(t *testing.T) {
+	t.Parallel()
+
+	schemas := avro.NewMemoryRegistry()
+
+	oldSerializer, err := avro.NewSerializer(schemas, OrderCreatedV1{})
+	require.NoError(t, err)
+
+	data, err := oldSerializer.Marshal(OrderCreatedV1{BaseEvent: createBaseEvent(), OrderID: "order", Quantity: 2})
+	require.NoError(t, err)
+
+	serializer, err := avro.NewSerializer(schemas, OrderCreated{})
+	require.NoError(t, err)
+
+	unmarshalled, err := serializer.Unmarshal(data, "OrderCreated")
+	require.NoError(t, err)
+	assert.Equal(t, OrderCreated{BaseEvent: createBaseEvent(), OrderID: "order", Quantity: 2, Currency: "SEK"}, unmarshalled)
+
+	_, err = serializer.Unmarshal(data, "OrderCreated")
+	require.NoError(t, err, "Resolved schemas are cached")
+}
+
+func TestSerializer_UnknownWriterSchema(t *testing.T) {
+	t.Parallel()
+
+	oldSerializer, err := avro.NewSerializer(avro.NewMemoryRegistry(), OrderCreatedV1{})
+	require.NoError(t, err)
+
+	data, err := oldSerializer.Marshal(OrderCreatedV1{BaseEvent: createBaseEvent(), OrderID: "order"})
+	require.NoError(t, err)
+
+	serializer, err := avro.NewSerializer(avro.NewMemoryRegistry(), OrderCreated{})
+	require.NoError(t, err)
+
+	_, err = serializer.Unmarshal(data, "OrderCreated")
+	require.ErrorIs(t, err, avro.ErrUnknownSchema)
+}
+
+func TestSerializer_WithoutSchema(t *testing.T) {
+	t.Parallel()
+
+	serializer, err := avro.NewSerializer(avro.NewMemoryRegistry())
+	require.NoError(t, err)
+
+	_, err = serializer.Marshal(UntypedEvent{BaseEvent: createBaseEvent()})
+	require.EqualError(t, err, "event UntypedEvent doesn't have an Avro schema")
+}
+
+func TestSerializerWithRegistry(t *testing.T) {
+	t.Parallel()
+
+	registry := eventsource.NewTypeRegistry().MustRegister("orders.OrderCreated", OrderCreated{})
+	serializer := avro.NewSerializerWithRegistry(avro.NewMemoryRegistry(), registry)
+	event := OrderCreated{BaseEvent: createBaseEvent(), OrderID: "order", Quantity: 2, Currency: "EUR"}
+
+	data, err := serializer.Marshal(event)
+	require.NoError(t, err)
+
+	unmarshalled, err := serializer.Unmarshal(data, "orders.OrderCreated")
+	require.NoError(t, err)
+	assert.Equal(t, event, unmarshalled)
+}
+
+func TestFileRegistry(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+
+	schemas, err := avro.NewFileRegistry(dir)
+	require.NoError(t, err)
+
+	oldSerializer, err := avro.NewSerializer(schemas, OrderCreatedV1{})
+	require.NoError(t, err)
+
+	data, err := oldSerializer.Marshal(OrderCreatedV1{BaseEvent: createBaseEvent(), OrderID: "order", Quantity: 2})
+	require.NoError(t, err)
+
+	reopened, err := avro.NewFileRegistry(dir)
+	require.NoError(t, err)
+
+	serializer, err := avro.NewSerializer(reopened, OrderCreated{})
+	require.NoError(t, err)
+
+	unmarshalled, err := serializer.Unmarshal(data, "OrderCreated")
+	require.NoError(t, err)
+	assert.Equal(t, OrderCreated{BaseEvent: createBaseEvent(), OrderID: "order", Quantity: 2, Currency: "SEK"}, unmarshalled)
+}
@@ -0,0 +1,216 @@
+package compression
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+
+	"github.com/klauspost/compress/s2"
+	"github.com/klauspost/compress/zstd"
+	"github.com/pkg/errors"
+
+	"github.com/SKF/go-eventsource/v2/eventsource"
+)
+
+// Codec is the compression algorithm of the data
+type Codec byte
+
+// The supported codecs, the values are stored in the header of the compressed data
+const (
+	Gzip Codec = iota + 1
+	Zstd
+	Snappy
+)
+
+const (
+	defaultThreshold = 1024
+	defaultMaxSize   = 16 << 20
+)
+
+// magic starts the header of compressed data, followed by the codec. JSON data never
+// starts with a zero byte, but binary formats like protobuf and Avro can, so the header
+// is four bytes, and the codec has to be known for the data to be read as compressed.
+// Any other data is read as is.
+var magic = []byte{0x00, 'Z', 'C', 0x01}
+
+const headerSize = 5
+
+// ErrTooLarge is returned by Unmarshal when the decompressed data would be larger than the maximum size
+var ErrTooLarge = errors.New("decompressed data too large")
+
+func (c Codec) String() string {
+	switch c {
+	case Gzip:
+		return "gzip"
+	case Zstd:
+		return "zstd"
+	case Snappy:
+		return "snappy"
+	default:
+		return "unknown"
+	}
+}
+
+type serializer struct {
+	serializer eventsource.Serializer
+	codec      Codec
+	threshold  int
+	maxSize    int
+
+	zstdEncoder *zstd.Encoder
+	zstdDecoder *zstd.Decoder
+}
+
+// Option is used to configure the compressing serializer
+type Option func(*serializer)
+
+// WithCodec sets the codec compressing the data, zstd by default. Data compressed with
+// any of the codecs can be decompressed regardless of this setting.
+func WithCodec(codec Codec) Option {
+	return func(s *serializer) {
+		s.codec = codec
+	}
+}
+
+// WithThreshold sets the size in bytes above which the data is compressed, 1 KB by default
+func WithThreshold(threshold int) Option {
+	return func(s *serializer) {
+		s.threshold = threshold
+	}
+}
+
+// WithMaxSize sets the maximum size in bytes of decompressed data, 16 MB by default.
+// Unmarshal returns ErrTooLarge for data which would decompress to more, rather than
+// allocating memory for it.
+func WithMaxSize(maxSize int) Option {
+	return func(s *serializer) {
+		s.maxSize = maxSize
+	}
+}
+
+// NewSerializer wraps the serializer, compressing the marshalled data larger than the
+// threshold. Unmarshal decompresses the data, and reads uncompressed data as is.
+func NewSerializer(wrapped eventsource.Serializer, opts ...Option) eventsource.Serializer {
+	s := &serializer{
+		serializer: wrapped,
+		codec:      Zstd,
+		threshold:  defaultThreshold,
+		maxSize:    defaultMaxSize,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	// the encoder and decoder can't fail with these options, and are safe for concurrent use
+	s.zstdEncoder, _ = zstd.NewWriter(nil)
+	s.zstdDecoder, _ = zstd.NewReader(nil, zstd.WithDecoderMaxMemory(uint64(s.maxSize))) // nolint:gosec
+
+	return s
+}
+
+// Unmarshal decompresses the data and unmarshals it with the wrapped serializer
+func (s *serializer) Unmarshal(data []byte, eventType string) (eventsource.Event, error) {
+	data, err := s.decompress(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.serializer.Unmarshal(data, eventType) // nolint:wrapcheck
+}
+
+// Marshal marshals the event with the wrapped serializer and compresses the data
+func (s *serializer) Marshal(event eventsource.Event) ([]byte, error) {
+	data, err := s.serializer.Marshal(event)
+	if err != nil {
+		return nil, err // nolint:wrapcheck
+	}
+
+	if len(data) <= s.threshold {
+		return data, nil
+	}
+
+	return s.compress(data)
+}
+
+// RecordUserID returns the user ID of the record chosen by the wrapped serializer
+func (s *serializer) RecordUserID(event eventsource.Event) string {
+	if userIDSerializer, ok := s.serializer.(eventsource.UserIDSerializer); ok {
+		return userIDSerializer.RecordUserID(event)
+	}
+
+	return event.GetUserID()
+}
+
+func (s *serializer) compress(data []byte) ([]byte, error) {
+	header := append(append([]byte{}, magic...), byte(s.codec))
+
+	switch s.codec {
+	case Gzip:
+		buffer := bytes.NewBuffer(header)
+		writer := gzip.NewWriter(buffer)
+
+		if _, err := writer.Write(data); err != nil {
+			return nil, errors.Wrap(err, "failed to gzip data")
+		}
+
+		if err := writer.Close(); err != nil {
+			return nil, errors.Wrap(err, "failed to gzip data")
+		}
+
+		return buffer.Bytes(), nil
+	case Zstd:
+		return s.zstdEncoder.EncodeAll(data, header), nil
+	case Snappy:
+		return append(header, s2.EncodeSnappy(nil, data)...), nil
+	default:
+		return nil, errors.Errorf("unsupported codec %d", s.codec)
+	}
+}
+
+func (s *serializer) decompress(data []byte) ([]byte, error) {
+	if len(data) < headerSize || !bytes.HasPrefix(data, magic) {
+		return data, nil
+	}
+
+	codec, compressed := Codec(data[len(magic)]), data[headerSize:]
+
+	switch codec {
+	case Gzip:
+		reader, err := gzip.NewReader(bytes.NewReader(compressed))
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to gunzip data")
+		}
+
+		if data, err = io.ReadAll(io.LimitReader(reader, int64(s.maxSize)+1)); err != nil {
+			return nil, errors.Wrap(err, "failed to gunzip data")
+		}
+
+		if len(data) > s.maxSize {
+			return nil, ErrTooLarge
+		}
+
+		return data, nil
+	case Zstd:
+		data, err := s.zstdDecoder.DecodeAll(compressed, nil)
+		if errors.Is(err, zstd.ErrDecoderSizeExceeded) || errors.Is(err, zstd.ErrWindowSizeExceeded) {
+			return nil, ErrTooLarge
+		}
+
+		return data, errors.Wrap(err, "failed to decompress zstd data")
+	case Snappy:
+		size, err := s2.DecodedLen(compressed)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to decompress snappy data")
+		}
+
+		if size > s.maxSize {
+			return nil, ErrTooLarge
+		}
+
+		data, err := s2.Decode(nil, compressed)
+		return data, errors.Wrap(err, "failed to decompress snappy data")
+	default:
+		return data, nil
+	}
+}
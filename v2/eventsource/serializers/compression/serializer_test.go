@@ -0,0 +1,133 @@
+package compression_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/SKF/go-eventsource/v2/eventsource"
+	"github.com/SKF/go-eventsource/v2/eventsource/serializers/compression"
+	"github.com/SKF/go-eventsource/v2/eventsource/serializers/json"
+)
+
+type ConfigurationUpdated struct {
+	*eventsource.BaseEvent
+	Configuration string `json:"configuration"`
+}
+
+func createEvent(size int) ConfigurationUpdated {
+	return ConfigurationUpdated{
+		BaseEvent:     &eventsource.BaseEvent{AggregateID: "aggregate"},
+		Configuration: strings.Repeat("threshold=0.5;", size/14),
+	}
+}
+
+func TestSerializer(t *testing.T) {
+	t.Parallel()
+
+	for _, codec := range []compression.Codec{compression.Gzip, compression.Zstd, compression.Snappy} {
+		t.Run(codec.String(), func(t *testing.T) {
+			t.Parallel()
+
+			serializer := compression.NewSerializer(json.NewSerializer(ConfigurationUpdated{}), compression.WithCodec(codec))
+			event := createEvent(10000)
+
+			data, err := serializer.Marshal(event)
+			require.NoError(t, err)
+			assert.Equal(t, []byte{0x00, 'Z', 'C', 0x01, byte(codec)}, data[:5])
+			assert.Less(t, len(data), 1000)
+
+			unmarshalled, err := serializer.Unmarshal(data, "ConfigurationUpdated")
+			require.NoError(t, err)
+			assert.Equal(t, event, unmarshalled)
+		})
+	}
+}
+
+func TestSerializer_Threshold(t *testing.T) {
+	t.Parallel()
+
+	serializer := compression.NewSerializer(json.NewSerializer(ConfigurationUpdated{}), compression.WithThreshold(2000))
+
+	event := createEvent(1500)
+
+	data, err := serializer.Marshal(event)
+	require.NoError(t, err)
+	assert.Equal(t, byte('{'), data[0], "Small data isn't compressed")
+
+	unmarshalled, err := serializer.Unmarshal(data, "ConfigurationUpdated")
+	require.NoError(t, err)
+	assert.Equal(t, event, unmarshalled)
+}
+
+func TestSerializer_ReadsOtherCodecs(t *testing.T) {
+	t.Parallel()
+
+	wrapped := json.NewSerializer(ConfigurationUpdated{})
+	event := createEvent(10000)
+
+	data, err := compression.NewSerializer(wrapped, compression.WithCodec(compression.Gzip)).Marshal(event)
+	require.NoError(t, err)
+
+	unmarshalled, err := compression.NewSerializer(wrapped, compression.WithCodec(compression.Snappy)).Unmarshal(data, "ConfigurationUpdated")
+	require.NoError(t, err)
+	assert.Equal(t, event, unmarshalled)
+}
+
+func TestSerializer_ReadsUncompressedRecords(t *testing.T) {
+	t.Parallel()
+
+	wrapped := json.NewSerializer(ConfigurationUpdated{})
+	event := createEvent(10000)
+
+	data, err := wrapped.Marshal(event)
+	require.NoError(t, err)
+
+	unmarshalled, err := compression.NewSerializer(wrapped).Unmarshal(data, "ConfigurationUpdated")
+	require.NoError(t, err)
+	assert.Equal(t, event, unmarshalled)
+}
+
+func TestSerializer_Errors(t *testing.T) {
+	t.Parallel()
+
+	serializer := compression.NewSerializer(json.NewSerializer(ConfigurationUpdated{}), compression.WithCodec(compression.Codec(9)))
+
+	_, err := serializer.Marshal(createEvent(10000))
+	require.EqualError(t, err, "unsupported codec 9")
+
+	_, err = serializer.Unmarshal([]byte{0x00, 'Z', 'C', 0x01, byte(compression.Gzip), 1, 2, 3}, "ConfigurationUpdated")
+	require.ErrorContains(t, err, "failed to gunzip data")
+}
+
+func TestSerializer_UnknownCodecIsReadAsIs(t *testing.T) {
+	t.Parallel()
+
+	serializerMock := eventsource.CreateSerializerMock()
+	data := []byte{0x00, 'Z', 'C', 0x01, 9, 1, 2, 3}
+	serializerMock.On("Unmarshal", data, "ConfigurationUpdated").Return(createEvent(0), nil)
+
+	_, err := compression.NewSerializer(serializerMock).Unmarshal(data, "ConfigurationUpdated")
+	require.NoError(t, err)
+	serializerMock.AssertExpectations(t)
+}
+
+func TestSerializer_MaxSize(t *testing.T) {
+	t.Parallel()
+
+	for _, codec := range []compression.Codec{compression.Gzip, compression.Zstd, compression.Snappy} {
+		t.Run(codec.String(), func(t *testing.T) {
+			t.Parallel()
+
+			wrapped := json.NewSerializer(ConfigurationUpdated{})
+
+			data, err := compression.NewSerializer(wrapped, compression.WithCodec(codec)).Marshal(createEvent(100000))
+			require.NoError(t, err)
+
+			_, err = compression.NewSerializer(wrapped, compression.WithMaxSize(50000)).Unmarshal(data, "ConfigurationUpdated")
+			require.ErrorIs(t, err, compression.ErrTooLarge)
+		})
+	}
+}
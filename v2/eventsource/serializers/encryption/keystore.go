@@ -0,0 +1,88 @@
+package encryption
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	keySize   = 32
+	keyIDSize = 16
+)
+
+// ErrKeyNotFound is returned by KeyStore.GetKey() when there is no key with the ID, like after it's deleted
+var ErrKeyNotFound = errors.New("key not found")
+
+// KeyStore stores a data key per subject. The keys are identified by opaque key IDs, which
+// are stored with the encrypted data instead of the subject. Deleting the key of a subject
+// shreds all of the data encrypted with it.
+type KeyStore interface {
+	// GetOrCreateKey returns the ID and the 256 bit key of the subject, creating them the first time
+	GetOrCreateKey(subject string) (keyID string, key []byte, err error)
+	// GetKey returns the key with the ID, or ErrKeyNotFound if there is none
+	GetKey(keyID string) ([]byte, error)
+	// DeleteKey deletes the key of the subject
+	DeleteKey(subject string) error
+}
+
+type memoryKeyStore struct {
+	mutex    sync.RWMutex
+	subjects map[string]string
+	keys     map[string][]byte
+}
+
+// NewMemoryKeyStore creates a key store which only keeps the keys in memory
+func NewMemoryKeyStore() KeyStore {
+	return &memoryKeyStore{subjects: map[string]string{}, keys: map[string][]byte{}}
+}
+
+func (s *memoryKeyStore) GetOrCreateKey(subject string) (string, []byte, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if keyID, ok := s.subjects[subject]; ok {
+		return keyID, s.keys[keyID], nil
+	}
+
+	id := make([]byte, keyIDSize)
+	key := make([]byte, keySize)
+
+	if _, err := rand.Read(id); err != nil {
+		return "", nil, errors.Wrap(err, "failed to generate key ID")
+	}
+
+	if _, err := rand.Read(key); err != nil {
+		return "", nil, errors.Wrap(err, "failed to generate key")
+	}
+
+	keyID := hex.EncodeToString(id)
+	s.subjects[subject] = keyID
+	s.keys[keyID] = key
+
+	return keyID, key, nil
+}
+
+func (s *memoryKeyStore) GetKey(keyID string) ([]byte, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	key, ok := s.keys[keyID]
+	if !ok {
+		return nil, errors.Wrapf(ErrKeyNotFound, "key ID %s", keyID)
+	}
+
+	return key, nil
+}
+
+func (s *memoryKeyStore) DeleteKey(subject string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.keys, s.subjects[subject])
+	delete(s.subjects, subject)
+
+	return nil
+}
@@ -0,0 +1,180 @@
+package encryption
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+
+	"github.com/SKF/go-eventsource/v2/eventsource"
+)
+
+// magic starts the encrypted data, followed by the JSON envelope. Data without it is read as is.
+var magic = []byte{0x00, 'E'}
+
+// SubjectFunc returns the subject whose key encrypts the event, like the user the personal
+// data belongs to. Events without a subject aren't encrypted.
+type SubjectFunc func(event eventsource.Event) string
+
+// RedactedEvent is unmarshalled instead of an event whose subject key has been deleted. Only
+// the fields of BaseEvent which aren't personal data are set.
+type RedactedEvent struct {
+	*eventsource.BaseEvent
+	// EventType is the type the event was unmarshalled as
+	EventType string
+}
+
+// envelope is the encrypted data, with the fields needed to redact the event in clear text.
+// The subject isn't stored, only the opaque ID of its key.
+type envelope struct {
+	KeyID       string `json:"keyId"`
+	AggregateID string `json:"aggregateId"`
+	SequenceID  string `json:"sequenceId"`
+	Timestamp   int64  `json:"timestamp"`
+	Version     int64  `json:"version"`
+	Nonce       []byte `json:"nonce"`
+	Ciphertext  []byte `json:"ciphertext"`
+}
+
+type serializer struct {
+	serializer eventsource.Serializer
+	keys       KeyStore
+	subject    SubjectFunc
+}
+
+// Option is used to configure the encrypting serializer
+type Option func(*serializer)
+
+// WithSubject sets how the subject of the events is chosen, by default it's the user ID
+func WithSubject(subject SubjectFunc) Option {
+	return func(s *serializer) {
+		s.subject = subject
+	}
+}
+
+// NewSerializer wraps the serializer, encrypting the marshalled data with AES-GCM using
+// the key of the subject of the event in the key store. Unmarshal decrypts the data, and
+// returns a RedactedEvent if the key has been deleted. The user ID of encrypted events is
+// only stored in the encrypted data, and not in the records.
+func NewSerializer(wrapped eventsource.Serializer, keys KeyStore, opts ...Option) eventsource.Serializer {
+	s := &serializer{
+		serializer: wrapped,
+		keys:       keys,
+		subject:    eventsource.Event.GetUserID,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// Unmarshal decrypts the data and unmarshals it with the wrapped serializer
+func (s *serializer) Unmarshal(data []byte, eventType string) (eventsource.Event, error) {
+	if !bytes.HasPrefix(data, magic) {
+		return s.serializer.Unmarshal(data, eventType) // nolint:wrapcheck
+	}
+
+	var env envelope
+	if err := json.Unmarshal(data[len(magic):], &env); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal encrypted envelope")
+	}
+
+	key, err := s.keys.GetKey(env.KeyID)
+	if errors.Is(err, ErrKeyNotFound) {
+		return RedactedEvent{
+			BaseEvent: &eventsource.BaseEvent{
+				AggregateID: env.AggregateID,
+				SequenceID:  env.SequenceID,
+				Timestamp:   env.Timestamp,
+				Version:     env.Version,
+			},
+			EventType: eventType,
+		}, nil
+	} else if err != nil {
+		return nil, errors.Wrap(err, "failed to get key")
+	}
+
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	plaintext, err := aead.Open(nil, env.Nonce, env.Ciphertext, []byte(env.KeyID))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to decrypt event")
+	}
+
+	return s.serializer.Unmarshal(plaintext, eventType) // nolint:wrapcheck
+}
+
+// Marshal marshals the event with the wrapped serializer and encrypts the data
+func (s *serializer) Marshal(event eventsource.Event) ([]byte, error) {
+	data, err := s.serializer.Marshal(event)
+	if err != nil {
+		return nil, err // nolint:wrapcheck
+	}
+
+	subject := s.subject(event)
+	if subject == "" {
+		return data, nil
+	}
+
+	keyID, key, err := s.keys.GetOrCreateKey(subject)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to get key")
+	}
+
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return nil, errors.Wrap(err, "failed to generate nonce")
+	}
+
+	envelopeData, err := json.Marshal(envelope{
+		KeyID:       keyID,
+		AggregateID: event.GetAggregateID(),
+		SequenceID:  event.GetSequenceID(),
+		Timestamp:   event.GetTimestamp(),
+		Version:     event.GetVersion(),
+		Nonce:       nonce,
+		Ciphertext:  aead.Seal(nil, nonce, data, []byte(keyID)),
+	})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal encrypted envelope")
+	}
+
+	return append(append([]byte{}, magic...), envelopeData...), nil
+}
+
+// RecordUserID returns an empty user ID for the records of encrypted events, which keep
+// the user ID in the encrypted data
+func (s *serializer) RecordUserID(event eventsource.Event) string {
+	if s.subject(event) != "" {
+		return ""
+	}
+
+	return event.GetUserID()
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create cipher")
+	}
+
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create cipher")
+	}
+
+	return aead, nil
+}
@@ -0,0 +1,174 @@
+package encryption_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/SKF/go-eventsource/v2/eventsource"
+	"github.com/SKF/go-eventsource/v2/eventsource/serializers/encryption"
+	"github.com/SKF/go-eventsource/v2/eventsource/serializers/json"
+	"github.com/SKF/go-eventsource/v2/eventsource/stores/memorystore"
+)
+
+type EmailChanged struct {
+	*eventsource.BaseEvent
+	Email string `json:"email"`
+}
+
+type user struct {
+	id     string
+	email  string
+	events []eventsource.Event
+}
+
+func (u *user) On(_ context.Context, event eventsource.Event) error {
+	u.events = append(u.events, event)
+
+	if e, ok := event.(EmailChanged); ok {
+		u.email = e.Email
+	}
+
+	return nil
+}
+
+func (u *user) SetAggregateID(id string) {
+	u.id = id
+}
+
+func createEvent() EmailChanged {
+	return EmailChanged{
+		BaseEvent: &eventsource.BaseEvent{
+			AggregateID: "aggregate",
+			UserID:      "subject",
+			SequenceID:  "sequence",
+			Timestamp:   1700000000000000000,
+			Version:     4,
+		},
+		Email: "someone@example.com",
+	}
+}
+
+func TestSerializer(t *testing.T) {
+	t.Parallel()
+
+	keys := encryption.NewMemoryKeyStore()
+	serializer := encryption.NewSerializer(json.NewSerializer(EmailChanged{}), keys)
+	event := createEvent()
+
+	data, err := serializer.Marshal(event)
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "someone@example.com")
+	assert.NotContains(t, string(data), "subject", "Only the key ID is stored")
+
+	unmarshalled, err := serializer.Unmarshal(data, "EmailChanged")
+	require.NoError(t, err)
+	assert.Equal(t, event, unmarshalled)
+
+	require.NoError(t, keys.DeleteKey("subject"))
+
+	unmarshalled, err = serializer.Unmarshal(data, "EmailChanged")
+	require.NoError(t, err)
+	assert.Equal(t, encryption.RedactedEvent{
+		BaseEvent: &eventsource.BaseEvent{
+			AggregateID: "aggregate",
+			SequenceID:  "sequence",
+			Timestamp:   1700000000000000000,
+			Version:     4,
+		},
+		EventType: "EmailChanged",
+	}, unmarshalled)
+}
+
+func TestSerializer_WithSubject(t *testing.T) {
+	t.Parallel()
+
+	keys := encryption.NewMemoryKeyStore()
+	serializer := encryption.NewSerializer(json.NewSerializer(EmailChanged{}), keys,
+		encryption.WithSubject(eventsource.Event.GetAggregateID),
+	)
+
+	data, err := serializer.Marshal(createEvent())
+	require.NoError(t, err)
+
+	require.NoError(t, keys.DeleteKey("subject"))
+
+	unmarshalled, err := serializer.Unmarshal(data, "EmailChanged")
+	require.NoError(t, err)
+	assert.IsType(t, EmailChanged{}, unmarshalled)
+
+	require.NoError(t, keys.DeleteKey("aggregate"))
+
+	unmarshalled, err = serializer.Unmarshal(data, "EmailChanged")
+	require.NoError(t, err)
+	assert.IsType(t, encryption.RedactedEvent{}, unmarshalled)
+}
+
+func TestSerializer_Plaintext(t *testing.T) {
+	t.Parallel()
+
+	wrapped := json.NewSerializer(EmailChanged{})
+	serializer := encryption.NewSerializer(wrapped, encryption.NewMemoryKeyStore())
+
+	event := createEvent()
+	event.UserID = ""
+
+	data, err := serializer.Marshal(event)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "someone@example.com", "Events without subject aren't encrypted")
+
+	legacy, err := wrapped.Marshal(createEvent())
+	require.NoError(t, err)
+
+	unmarshalled, err := serializer.Unmarshal(legacy, "EmailChanged")
+	require.NoError(t, err)
+	assert.Equal(t, createEvent(), unmarshalled, "Unencrypted records are read as is")
+}
+
+// wrongKeyStore returns the same key for every key ID
+type wrongKeyStore struct {
+	encryption.KeyStore
+}
+
+func (wrongKeyStore) GetKey(string) ([]byte, error) {
+	return make([]byte, 32), nil
+}
+
+func TestSerializer_WrongKey(t *testing.T) {
+	t.Parallel()
+
+	keys := encryption.NewMemoryKeyStore()
+	data, err := encryption.NewSerializer(json.NewSerializer(EmailChanged{}), keys).Marshal(createEvent())
+	require.NoError(t, err)
+
+	_, err = encryption.NewSerializer(json.NewSerializer(EmailChanged{}), wrongKeyStore{keys}).Unmarshal(data, "EmailChanged")
+	require.ErrorContains(t, err, "failed to decrypt event")
+}
+
+func TestRepository_LoadShredded(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.TODO()
+	keys := encryption.NewMemoryKeyStore()
+	repo := eventsource.NewRepository(memorystore.New(), encryption.NewSerializer(json.NewSerializer(EmailChanged{}), keys))
+
+	event := createEvent()
+	require.NoError(t, repo.Save(ctx, event))
+
+	records, err := repo.Store().LoadByAggregate(ctx, "aggregate")
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Empty(t, records[0].UserID, "The user ID is only stored encrypted")
+
+	require.NoError(t, keys.DeleteKey("subject"))
+
+	var aggregate user
+	_, err = repo.Load(ctx, "aggregate", &aggregate)
+	require.NoError(t, err)
+
+	require.Len(t, aggregate.events, 1)
+	assert.IsType(t, encryption.RedactedEvent{}, aggregate.events[0])
+	assert.Empty(t, aggregate.email)
+}
@@ -12,6 +12,7 @@ import (
 // JSONSerializer takes events and marshals
 type serializer struct {
 	eventTypes map[string]reflect.Type
+	registry   *eventsource.TypeRegistry
 }
 
 // NewSerializer returns a seriablizable eventsource
@@ -24,11 +25,16 @@ func NewSerializer(events ...eventsource.Event) eventsource.Serializer {
 	return &serializer{eventTypes: eventTypes}
 }
 
+// NewSerializerWithRegistry returns a serializer looking up the event types by their
+// names and aliases in the registry
+func NewSerializerWithRegistry(registry *eventsource.TypeRegistry) eventsource.Serializer {
+	return &serializer{registry: registry}
+}
+
 // Unmarshal implements the Marshaler encoding interface
 func (s *serializer) Unmarshal(data []byte, eventType string) (out eventsource.Event, err error) {
-	recordType, ok := s.eventTypes[eventType]
-	if !ok {
-		err = errors.Errorf("Unmarshal error, unbound event type, %v", eventType)
+	recordType, err := s.lookup(eventType)
+	if err != nil {
 		return
 	}
 
@@ -38,7 +44,7 @@ func (s *serializer) Unmarshal(data []byte, eventType string) (out eventsource.E
 		return
 	}
 
-	out, ok = reflect.ValueOf(event).Elem().Interface().(eventsource.Event)
+	out, ok := reflect.ValueOf(event).Elem().Interface().(eventsource.Event)
 	if !ok {
 		err = errors.New("event doesn't implement struct Event")
 		return
@@ -47,6 +53,19 @@ func (s *serializer) Unmarshal(data []byte, eventType string) (out eventsource.E
 	return
 }
 
+func (s *serializer) lookup(eventType string) (reflect.Type, error) {
+	if s.registry != nil {
+		return s.registry.Type(eventType) // nolint:wrapcheck
+	}
+
+	recordType, ok := s.eventTypes[eventType]
+	if !ok {
+		return nil, errors.Errorf("Unmarshal error, unbound event type, %v", eventType)
+	}
+
+	return recordType, nil
+}
+
 // Marshal implements the Unmarshaler encoding interface
 func (s *serializer) Marshal(event eventsource.Event) (data []byte, err error) {
 	if data, err = json.Marshal(event); err != nil {
@@ -0,0 +1,53 @@
+package json_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/SKF/go-eventsource/v2/eventsource"
+	"github.com/SKF/go-eventsource/v2/eventsource/serializers/json"
+)
+
+type OrderCreated struct {
+	*eventsource.BaseEvent
+	OrderID string `json:"orderId"`
+}
+
+func TestSerializer(t *testing.T) {
+	t.Parallel()
+
+	serializer := json.NewSerializer(OrderCreated{})
+	event := OrderCreated{BaseEvent: &eventsource.BaseEvent{AggregateID: "aggregate"}, OrderID: "order"}
+
+	data, err := serializer.Marshal(event)
+	require.NoError(t, err)
+
+	unmarshalled, err := serializer.Unmarshal(data, "OrderCreated")
+	require.NoError(t, err)
+	assert.Equal(t, event, unmarshalled)
+
+	_, err = serializer.Unmarshal(data, "OrderPlaced")
+	require.EqualError(t, err, "Unmarshal error, unbound event type, OrderPlaced")
+}
+
+func TestSerializerWithRegistry(t *testing.T) {
+	t.Parallel()
+
+	registry := eventsource.NewTypeRegistry().MustRegister("orders.OrderCreated", OrderCreated{}, "OrderPlaced")
+	serializer := json.NewSerializerWithRegistry(registry)
+	event := OrderCreated{BaseEvent: &eventsource.BaseEvent{AggregateID: "aggregate"}, OrderID: "order"}
+
+	data, err := serializer.Marshal(event)
+	require.NoError(t, err)
+
+	for _, eventType := range []string{"orders.OrderCreated", "OrderPlaced"} {
+		unmarshalled, err := serializer.Unmarshal(data, eventType)
+		require.NoError(t, err)
+		assert.Equal(t, event, unmarshalled)
+	}
+
+	_, err = serializer.Unmarshal(data, "OrderCreated")
+	require.ErrorIs(t, err, eventsource.ErrUnregisteredType)
+}
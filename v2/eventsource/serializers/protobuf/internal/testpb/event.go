@@ -0,0 +1,27 @@
+package testpb
+
+// The methods make VibrationMeasured an eventsource.Event, complementing the generated getters
+
+func (x *VibrationMeasured) GetAggregateID() string {
+	return x.GetAggregateId()
+}
+
+func (x *VibrationMeasured) GetUserID() string {
+	return x.GetUserId()
+}
+
+func (x *VibrationMeasured) GetSequenceID() string {
+	return x.GetSequenceId()
+}
+
+func (x *VibrationMeasured) SetSequenceID(sequenceID string) {
+	x.SequenceId = sequenceID
+}
+
+func (x *VibrationMeasured) SetTimestamp(timestamp int64) {
+	x.Timestamp = timestamp
+}
+
+func (x *VibrationMeasured) SetVersion(version int64) {
+	x.Version = version
+}
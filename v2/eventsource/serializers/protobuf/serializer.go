@@ -0,0 +1,104 @@
+package protobuf
+
+import (
+	"reflect"
+
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+
+	"github.com/SKF/go-eventsource/v2/eventsource"
+)
+
+// serializer marshals events which are protobuf messages
+type serializer struct {
+	messageTypes map[string]protoreflect.MessageType
+	registry     *eventsource.TypeRegistry
+}
+
+// NewSerializer returns a serializer for the events, which have to be protobuf messages,
+// like generated messages with the methods of eventsource.Event added to them
+func NewSerializer(events ...eventsource.Event) (eventsource.Serializer, error) {
+	messageTypes := map[string]protoreflect.MessageType{}
+
+	for _, event := range events {
+		message, ok := event.(proto.Message)
+		if !ok {
+			return nil, errors.Errorf("event %s isn't a protobuf message", eventsource.GetTypeName(event))
+		}
+
+		messageTypes[eventsource.GetTypeName(event)] = message.ProtoReflect().Type()
+	}
+
+	return &serializer{messageTypes: messageTypes}, nil
+}
+
+// NewSerializerWithRegistry returns a serializer looking up the event types by their
+// names and aliases in the registry, whose types have to be protobuf messages
+func NewSerializerWithRegistry(registry *eventsource.TypeRegistry) (eventsource.Serializer, error) {
+	for _, goType := range registry.Types() {
+		if _, ok := reflect.New(goType).Interface().(proto.Message); !ok {
+			return nil, errors.Errorf("event %s isn't a protobuf message", goType.Name())
+		}
+	}
+
+	return &serializer{registry: registry}, nil
+}
+
+// Unmarshal implements the Serializer interface
+func (s *serializer) Unmarshal(data []byte, eventType string) (eventsource.Event, error) {
+	messageType, err := s.lookup(eventType)
+	if err != nil {
+		return nil, err
+	}
+
+	message := messageType.New().Interface()
+	if err = proto.Unmarshal(data, message); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal event")
+	}
+
+	event, ok := message.(eventsource.Event)
+	if !ok {
+		return nil, errors.Errorf("message %s doesn't implement Event", messageType.Descriptor().FullName())
+	}
+
+	return event, nil
+}
+
+// Marshal implements the Serializer interface
+func (s *serializer) Marshal(event eventsource.Event) ([]byte, error) {
+	message, ok := event.(proto.Message)
+	if !ok {
+		return nil, errors.Errorf("event %s isn't a protobuf message", eventsource.GetTypeName(event))
+	}
+
+	data, err := proto.Marshal(message)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal event")
+	}
+
+	return data, nil
+}
+
+func (s *serializer) lookup(eventType string) (protoreflect.MessageType, error) {
+	if s.registry == nil {
+		messageType, ok := s.messageTypes[eventType]
+		if !ok {
+			return nil, errors.Errorf("Unmarshal error, unbound event type, %v", eventType)
+		}
+
+		return messageType, nil
+	}
+
+	goType, err := s.registry.Type(eventType)
+	if err != nil {
+		return nil, err // nolint:wrapcheck
+	}
+
+	message, ok := reflect.New(goType).Interface().(proto.Message)
+	if !ok {
+		return nil, errors.Errorf("event %s isn't a protobuf message", goType)
+	}
+
+	return message.ProtoReflect().Type(), nil
+}
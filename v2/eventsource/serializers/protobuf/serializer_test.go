@@ -0,0 +1,112 @@
+package protobuf_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/SKF/go-eventsource/v2/eventsource"
+	"github.com/SKF/go-eventsource/v2/eventsource/serializers/json"
+	"github.com/SKF/go-eventsource/v2/eventsource/serializers/protobuf"
+	"github.com/SKF/go-eventsource/v2/eventsource/serializers/protobuf/internal/testpb"
+)
+
+func createEvent() *testpb.VibrationMeasured {
+	samples := make([]float64, 512)
+	for i := range samples {
+		samples[i] = float64(i) / 3
+	}
+
+	return &testpb.VibrationMeasured{
+		AggregateId: "0c8a8e4c-5a5b-4b8e-9a43-2f5b1a1c9d11",
+		UserId:      "a5e1a7c4-3b0b-4a52-8a3f-6f1a2b9e4c77",
+		SequenceId:  eventsource.NewULID(),
+		Timestamp:   1700000000000000000,
+		Version:     42,
+		SensorId:    "sensor",
+		Samples:     samples,
+	}
+}
+
+func TestSerializer(t *testing.T) {
+	t.Parallel()
+
+	serializer, err := protobuf.NewSerializer(&testpb.VibrationMeasured{})
+	require.NoError(t, err)
+
+	event := createEvent()
+
+	data, err := serializer.Marshal(event)
+	require.NoError(t, err)
+
+	unmarshalled, err := serializer.Unmarshal(data, "VibrationMeasured")
+	require.NoError(t, err)
+	assert.True(t, proto.Equal(event, unmarshalled.(proto.Message)), "Round trip") // nolint:forcetypeassert
+	assert.Equal(t, event.GetAggregateID(), unmarshalled.GetAggregateID())
+
+	_, err = serializer.Unmarshal(data, "VibrationSkipped")
+	require.EqualError(t, err, "Unmarshal error, unbound event type, VibrationSkipped")
+}
+
+func TestSerializer_NotProtoMessage(t *testing.T) {
+	t.Parallel()
+
+	_, err := protobuf.NewSerializer(&eventsource.BaseEvent{})
+	require.EqualError(t, err, "event BaseEvent isn't a protobuf message")
+
+	_, err = protobuf.NewSerializerWithRegistry(eventsource.NewTypeRegistry().MustRegister("base", &eventsource.BaseEvent{}))
+	require.EqualError(t, err, "event BaseEvent isn't a protobuf message")
+}
+
+func TestSerializerWithRegistry(t *testing.T) {
+	t.Parallel()
+
+	registry := eventsource.NewTypeRegistry().MustRegister("telemetry.VibrationMeasured", &testpb.VibrationMeasured{})
+	serializer, err := protobuf.NewSerializerWithRegistry(registry)
+	require.NoError(t, err)
+
+	event := createEvent()
+
+	data, err := serializer.Marshal(event)
+	require.NoError(t, err)
+
+	unmarshalled, err := serializer.Unmarshal(data, "telemetry.VibrationMeasured")
+	require.NoError(t, err)
+	assert.True(t, proto.Equal(event, unmarshalled.(proto.Message))) // nolint:forcetypeassert
+}
+
+func benchmarkSerializer(b *testing.B, serializer eventsource.Serializer) {
+	b.Helper()
+
+	event := createEvent()
+
+	data, err := serializer.Marshal(event)
+	require.NoError(b, err)
+
+	b.ReportMetric(float64(len(data)), "bytes/event")
+
+	b.Run("Marshal", func(b *testing.B) {
+		for range b.N {
+			_, _ = serializer.Marshal(event)
+		}
+	})
+
+	b.Run("Unmarshal", func(b *testing.B) {
+		for range b.N {
+			_, _ = serializer.Unmarshal(data, "VibrationMeasured")
+		}
+	})
+}
+
+func BenchmarkProtobufSerializer(b *testing.B) {
+	serializer, err := protobuf.NewSerializer(&testpb.VibrationMeasured{})
+	require.NoError(b, err)
+
+	benchmarkSerializer(b, serializer)
+}
+
+func BenchmarkJSONSerializer(b *testing.B) {
+	benchmarkSerializer(b, json.NewSerializer(&testpb.VibrationMeasured{}))
+}
@@ -0,0 +1,156 @@
+package eventsource
+
+import (
+	"context"
+
+	"github.com/SKF/go-utility/v2/log"
+	"github.com/pkg/errors"
+)
+
+// ErrNoSnapshot is returned by SnapshotStore.LoadSnapshot() when no snapshot exist for the given aggregate ID
+var ErrNoSnapshot = errors.New("no snapshot found")
+
+// Snapshot is the marshalled state of an aggregate at the given version. The sequence ID
+// and timestamp are those of the last record applied to it.
+type Snapshot struct {
+	AggregateID string `json:"aggregateId" dynamodbav:"aggregateId"`
+	SequenceID  string `json:"sequenceId" dynamodbav:"sequenceId"`
+	Version     int64  `json:"version" dynamodbav:"version"`
+	Data        []byte `json:"data" dynamodbav:"data"`
+	Timestamp   int64  `json:"timestamp" dynamodbav:"timestamp"`
+}
+
+// SnapshotStore is the interface implemented by the data stores that can be used
+// to keep the latest snapshot of each aggregate.
+type SnapshotStore interface {
+	// Save the snapshot, unless a snapshot with a later version already exist
+	SaveSnapshot(ctx context.Context, snapshot Snapshot) error
+	// Load the latest snapshot of the aggregate, or return ErrNoSnapshot
+	LoadSnapshot(ctx context.Context, aggregateID string) (Snapshot, error)
+}
+
+// Snapshotter is implemented by aggregates whose state can be saved in snapshots,
+// so they can be loaded without replaying all their events.
+type Snapshotter interface {
+	Aggregate
+	MarshalSnapshot() ([]byte, error)
+	UnmarshalSnapshot(data []byte) error
+}
+
+// SnapshotPolicy decides if a new snapshot should be saved after events have been saved to
+// an aggregate, given the version of the aggregate before and after them.
+type SnapshotPolicy func(previousVersion, version int64) bool
+
+// EveryNEvents is a SnapshotPolicy saving a new snapshot each time the version of the
+// aggregate passes a multiple of n.
+func EveryNEvents(n int64) SnapshotPolicy {
+	return func(previousVersion, version int64) bool {
+		return version/n > previousVersion/n
+	}
+}
+
+// WithSnapshots makes the repository load aggregates implementing Snapshotter from
+// their latest snapshot, only replaying the records stored after it. When events have
+// been committed and the policy decides so, the aggregate returned by newSnapshotter is
+// loaded and a new snapshot of it is saved. Failing to save a snapshot doesn't fail the
+// commit, but is logged. If newSnapshotter is nil or returns nil, no snapshots are saved.
+func WithSnapshots(store SnapshotStore, policy SnapshotPolicy, newSnapshotter func(aggregateID string) Snapshotter) RepositoryOption {
+	return func(repo *repository) {
+		repo.snapshotStore = store
+		repo.snapshotPolicy = policy
+		repo.newSnapshotter = newSnapshotter
+	}
+}
+
+func (repo repository) loadSnapshot(ctx context.Context, aggregateID string, aggr Aggregate) (Snapshotter, *Snapshot, error) {
+	snapshotter, ok := aggr.(Snapshotter)
+	if repo.snapshotStore == nil || !ok {
+		return nil, nil, nil
+	}
+
+	snapshot, err := repo.snapshotStore.LoadSnapshot(ctx, aggregateID)
+	if errors.Is(err, ErrNoSnapshot) {
+		return snapshotter, nil, nil
+	}
+
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "failed to load snapshot")
+	}
+
+	return snapshotter, &snapshot, nil
+}
+
+// saveSnapshots saves new snapshots of the aggregates of the committed records, when the
+// policy decides so given the versions of the records. The records have been committed,
+// so failures are only logged.
+func (repo repository) saveSnapshots(ctx context.Context, records []Record) {
+	type versions struct {
+		previous, current int64
+	}
+
+	var (
+		aggregateIDs []string
+		byAggregate  = map[string]*versions{}
+	)
+
+	for _, record := range records {
+		if record.Version == 0 {
+			continue
+		}
+
+		v, ok := byAggregate[record.AggregateID]
+		if !ok {
+			byAggregate[record.AggregateID] = &versions{previous: record.Version - 1, current: record.Version}
+			aggregateIDs = append(aggregateIDs, record.AggregateID)
+
+			continue
+		}
+
+		v.previous = min(v.previous, record.Version-1)
+		v.current = max(v.current, record.Version)
+	}
+
+	for _, aggregateID := range aggregateIDs {
+		v := byAggregate[aggregateID]
+		if !repo.snapshotPolicy(v.previous, v.current) {
+			continue
+		}
+
+		if err := repo.saveSnapshot(ctx, aggregateID); err != nil {
+			log.Warnf("Saving snapshot of aggregate %s failed: %+v", aggregateID, err)
+		}
+	}
+}
+
+// saveSnapshot loads the aggregate and saves a snapshot of it
+func (repo repository) saveSnapshot(ctx context.Context, aggregateID string) error {
+	snapshotter := repo.newSnapshotter(aggregateID)
+	if snapshotter == nil {
+		return nil
+	}
+
+	loaded, deleted, err := repo.loadStored(ctx, aggregateID, snapshotter)
+	if deleted || err != nil || len(loaded.history) == 0 {
+		return err
+	}
+
+	data, err := snapshotter.MarshalSnapshot()
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal snapshot")
+	}
+
+	last := loaded.history[len(loaded.history)-1]
+	snapshot := Snapshot{
+		AggregateID: aggregateID,
+		SequenceID:  last.SequenceID,
+		Version:     loaded.version,
+		Data:        data,
+		Timestamp:   last.Timestamp,
+	}
+
+	if err = repo.snapshotStore.SaveSnapshot(ctx, snapshot); err != nil {
+		return errors.Wrap(err, "failed to save snapshot")
+	}
+
+	return nil
+}
@@ -0,0 +1,166 @@
+package eventsource
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type snapshotterMock struct {
+	*AggregatorMock
+}
+
+func (o snapshotterMock) MarshalSnapshot() ([]byte, error) {
+	args := o.Mock.Called()
+	return args.Get(0).([]byte), args.Error(1)
+}
+
+func (o snapshotterMock) UnmarshalSnapshot(data []byte) error {
+	args := o.Mock.Called(data)
+	return args.Error(0)
+}
+
+func Test_RepoLoadFromSnapshot(t *testing.T) {
+	storeMock, _, serializerMock, aggregatorMock := setupMocks()
+	snapshotStoreMock := CreateSnapshotStoreMock()
+	aggr := snapshotterMock{aggregatorMock}
+
+	history, baseEvent, id := createMockDataForLoadAggregate()
+	otherEvent := &OtherEvent{BaseEvent: baseEvent, OtherEventField: 42}
+	snapshot := Snapshot{AggregateID: id, SequenceID: "2", Version: 2, Data: []byte("state")}
+
+	ctx := context.TODO()
+	snapshotStoreMock.On("LoadSnapshot", ctx, id).Return(snapshot, nil)
+	// the store returns the whole history, as if it didn't support BySequenceID
+	storeMock.On("LoadByAggregate", ctx, id, mock.Anything).Return(history, nil)
+	serializerMock.On("Unmarshal", []byte{byte(1)}, "BaseEvent").Return(baseEvent, nil).Once()
+	serializerMock.On("Unmarshal", []byte{byte(3)}, "OtherEvent").Return(otherEvent, nil).Once()
+	aggregatorMock.Mock.On("UnmarshalSnapshot", []byte("state")).Return(nil).Once()
+	aggregatorMock.Mock.On("On", ctx, mock.Anything).Return(nil).Twice()
+
+	repo := NewRepository(storeMock, serializerMock, WithSnapshots(snapshotStoreMock, EveryNEvents(10), nil))
+	deleted, err := repo.Load(ctx, id, aggr)
+
+	aggregatorMock.Mock.AssertExpectations(t)
+	serializerMock.AssertExpectations(t)
+	snapshotStoreMock.AssertExpectations(t)
+	assert.Nil(t, err)
+	assert.False(t, deleted)
+}
+
+func Test_RepoLoadFromSnapshot_NoNewRecords(t *testing.T) {
+	storeMock, _, serializerMock, aggregatorMock := setupMocks()
+	snapshotStoreMock := CreateSnapshotStoreMock()
+	aggr := snapshotterMock{aggregatorMock}
+
+	ctx, id := context.TODO(), "1234-1234-1234"
+	snapshot := Snapshot{AggregateID: id, SequenceID: "4", Version: 4, Data: []byte("state")}
+
+	snapshotStoreMock.On("LoadSnapshot", ctx, id).Return(snapshot, nil)
+	storeMock.On("LoadByAggregate", ctx, id, mock.Anything).Return([]Record{}, nil)
+	aggregatorMock.Mock.On("UnmarshalSnapshot", []byte("state")).Return(nil).Once()
+
+	repo := NewRepository(storeMock, serializerMock, WithSnapshots(snapshotStoreMock, EveryNEvents(1), nil))
+	_, err := repo.Load(ctx, id, aggr)
+
+	aggregatorMock.Mock.AssertExpectations(t)
+	snapshotStoreMock.AssertExpectations(t)
+	assert.Nil(t, err)
+}
+
+func Test_RepoCommitSavesSnapshot(t *testing.T) {
+	storeMock, storeTransactionMock, serializerMock, aggregatorMock := setupMocks()
+	snapshotStoreMock := CreateSnapshotStoreMock()
+
+	history, baseEvent, id := createMockDataForLoadAggregate()
+	event := &BaseEvent{AggregateID: id}
+
+	ctx := context.TODO()
+	serializerMock.On("Marshal", event).Return([]byte("data"), nil)
+	storeMock.On("NewTransaction", ctx, mock.Anything).Return(storeTransactionMock, nil).Once()
+	storeTransactionMock.On("Commit").Return(nil).Once()
+	storeTransactionMock.On("GetRecords").Return([]Record{{AggregateID: id, SequenceID: "4", Version: 4}})
+	snapshotStoreMock.On("LoadSnapshot", ctx, id).Return(Snapshot{}, ErrNoSnapshot).Once()
+	storeMock.On("LoadByAggregate", ctx, id, []QueryOption(nil)).Return(history, nil).Once()
+	serializerMock.On("Unmarshal", mock.Anything, mock.Anything).Return(baseEvent, nil)
+	aggregatorMock.Mock.On("On", ctx, mock.Anything).Return(nil)
+	aggregatorMock.Mock.On("MarshalSnapshot").Return([]byte("state"), nil).Once()
+	snapshotStoreMock.On("SaveSnapshot", ctx, Snapshot{
+		AggregateID: id,
+		SequenceID:  "4",
+		Version:     4,
+		Data:        []byte("state"),
+		Timestamp:   555,
+	}).Return(errors.New("unavailable")).Once()
+
+	repo := NewRepository(storeMock, serializerMock, WithSnapshots(snapshotStoreMock, EveryNEvents(4), func(aggregateID string) Snapshotter {
+		return snapshotterMock{aggregatorMock}
+	}))
+	err := repo.Save(ctx, event)
+
+	aggregatorMock.Mock.AssertExpectations(t)
+	snapshotStoreMock.AssertExpectations(t)
+	assert.Nil(t, err, "Failing to save the snapshot doesn't fail the commit")
+}
+
+func Test_RepoCommitSkipsSnapshot(t *testing.T) {
+	storeMock, storeTransactionMock, serializerMock, aggregatorMock := setupMocks()
+	snapshotStoreMock := CreateSnapshotStoreMock()
+	event := &BaseEvent{AggregateID: "1234"}
+
+	ctx := context.TODO()
+	serializerMock.On("Marshal", event).Return([]byte("data"), nil)
+	storeMock.On("NewTransaction", ctx, mock.Anything).Return(storeTransactionMock, nil).Once()
+	storeTransactionMock.On("Commit").Return(nil).Once()
+	storeTransactionMock.On("GetRecords").Return([]Record{{AggregateID: "1234", SequenceID: "5", Version: 5}})
+
+	repo := NewRepository(storeMock, serializerMock, WithSnapshots(snapshotStoreMock, EveryNEvents(4), func(aggregateID string) Snapshotter {
+		return snapshotterMock{aggregatorMock}
+	}))
+	err := repo.Save(ctx, event)
+
+	storeMock.AssertExpectations(t)
+	snapshotStoreMock.AssertNotCalled(t, "LoadSnapshot", mock.Anything, mock.Anything)
+	assert.Nil(t, err)
+}
+
+func Test_RepoLoadSnapshotErr(t *testing.T) {
+	storeMock, _, serializerMock, aggregatorMock := setupMocks()
+	snapshotStoreMock := CreateSnapshotStoreMock()
+
+	ctx, id := context.TODO(), "1234-1234-1234"
+	snapshotStoreMock.On("LoadSnapshot", ctx, id).Return(Snapshot{}, errors.New("unavailable"))
+
+	repo := NewRepository(storeMock, serializerMock, WithSnapshots(snapshotStoreMock, EveryNEvents(1), nil))
+	_, err := repo.Load(ctx, id, snapshotterMock{aggregatorMock})
+
+	storeMock.AssertExpectations(t)
+	assert.EqualError(t, err, "failed to load snapshot: unavailable")
+}
+
+func Test_RepoLoadWithoutSnapshotter(t *testing.T) {
+	storeMock, _, serializerMock, aggregatorMock := setupMocks()
+	snapshotStoreMock := CreateSnapshotStoreMock()
+
+	ctx, id := context.TODO(), "1234-1234-1234"
+	storeMock.On("LoadByAggregate", ctx, id, []QueryOption(nil)).Return([]Record{}, nil)
+
+	repo := NewRepository(storeMock, serializerMock, WithSnapshots(snapshotStoreMock, EveryNEvents(1), nil))
+	_, err := repo.Load(ctx, id, aggregatorMock)
+
+	snapshotStoreMock.AssertExpectations(t)
+	assert.Equal(t, ErrNoHistory, err)
+}
+
+func Test_EveryNEvents(t *testing.T) {
+	policy := EveryNEvents(3)
+
+	assert.False(t, policy(0, 2))
+	assert.True(t, policy(0, 3))
+	assert.True(t, policy(2, 4))
+	assert.False(t, policy(3, 5))
+	assert.True(t, policy(5, 7))
+}
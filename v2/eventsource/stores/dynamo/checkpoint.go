@@ -0,0 +1,70 @@
+package dynamo
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"github.com/SKF/go-eventsource/v2/eventsource/projection"
+)
+
+type checkpoint struct {
+	Name       string `dynamodbav:"name"`
+	SequenceID string `dynamodbav:"sequenceId"`
+}
+
+type checkpointStore struct {
+	db        *dynamodb.Client
+	tableName string
+}
+
+// NewCheckpointStore creates a new checkpoint store for projections, for a table
+// with the partition key name (S)
+func NewCheckpointStore(db *dynamodb.Client, tableName string) projection.CheckpointStore {
+	return &checkpointStore{
+		db:        db,
+		tableName: tableName,
+	}
+}
+
+// SaveCheckpoint ...
+func (store *checkpointStore) SaveCheckpoint(ctx context.Context, name string, sequenceID string) error {
+	item, err := attributevalue.MarshalMap(checkpoint{Name: name, SequenceID: sequenceID})
+	if err != nil {
+		return fmt.Errorf("couldn't marshal checkpoint: %w", err)
+	}
+
+	if _, err = store.db.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: &store.tableName,
+		Item:      item,
+	}); err != nil {
+		return fmt.Errorf("couldn't put checkpoint to dynamodb store: %w", err)
+	}
+
+	return nil
+}
+
+// LoadCheckpoint ...
+func (store *checkpointStore) LoadCheckpoint(ctx context.Context, name string) (string, error) {
+	output, err := store.db.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: &store.tableName,
+		Key: map[string]types.AttributeValue{
+			"name": &types.AttributeValueMemberS{Value: name},
+		},
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return "", fmt.Errorf("couldn't get checkpoint from dynamodb store: %w", err)
+	}
+
+	var result checkpoint
+	if err = attributevalue.UnmarshalMap(output.Item, &result); err != nil {
+		return "", fmt.Errorf("couldn't unmarshal checkpoint: %w", err)
+	}
+
+	return result.SequenceID, nil
+}
@@ -43,6 +43,7 @@ type options struct {
 	index         *string
 	filterOptions *filterOpt
 	timestamp     *string
+	version       *int64
 }
 
 // WithLimit will limit the result
@@ -96,6 +97,11 @@ func BySequenceID(value string) eventsource.QueryOption {
 	return greaterThan(columnSequenceID, value)
 }
 
+// FilterBySequenceID implements support for eventsource.BySequenceID
+func (o *options) FilterBySequenceID(sequenceID string) {
+	BySequenceID(sequenceID)(o)
+}
+
 // ByTimestamp will set filter to only return records with timestamp greater than value
 func ByTimestamp(value string) eventsource.QueryOption {
 	return func(i interface{}) {
@@ -107,6 +113,23 @@ func ByTimestamp(value string) eventsource.QueryOption {
 	}
 }
 
+// ByVersion will only return records with version greater than value. On tables with the
+// sort key version it's part of the key condition, otherwise a filter.
+func ByVersion(value int64) eventsource.QueryOption {
+	return func(i interface{}) {
+		if o, ok := i.(*options); ok {
+			o.version = &value
+		} else {
+			log.Warn("Trying to put version option to a non dynamodbstore.options")
+		}
+	}
+}
+
+// FilterByVersion implements support for eventsource.ByVersion
+func (o *options) FilterByVersion(version int64) {
+	ByVersion(version)(o)
+}
+
 // ByType will set filter to only return records with type equal to value
 func ByType(value string) eventsource.QueryOption {
 	return withFilter(columnType, value, "=")
@@ -0,0 +1,80 @@
+package dynamo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	"github.com/SKF/go-eventsource/v2/eventsource"
+)
+
+type snapshotStore struct {
+	db        *dynamodb.Client
+	tableName string
+}
+
+// NewSnapshotStore creates a new snapshot store for a table with the partition
+// key aggregateId (S), keeping the latest snapshot of each aggregate
+func NewSnapshotStore(db *dynamodb.Client, tableName string) eventsource.SnapshotStore {
+	return &snapshotStore{
+		db:        db,
+		tableName: tableName,
+	}
+}
+
+// SaveSnapshot puts the snapshot, unless a snapshot with the same or a later version already exist
+func (store *snapshotStore) SaveSnapshot(ctx context.Context, snapshot eventsource.Snapshot) error {
+	item, err := attributevalue.MarshalMap(snapshot)
+	if err != nil {
+		return fmt.Errorf("couldn't marshal snapshot: %w", err)
+	}
+
+	_, err = store.db.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:           &store.tableName,
+		Item:                item,
+		ConditionExpression: aws.String("attribute_not_exists(aggregateId) OR version < :version"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":version": item["version"],
+		},
+	})
+
+	var conditionFailed *types.ConditionalCheckFailedException
+	if errors.As(err, &conditionFailed) {
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("couldn't put snapshot to dynamodb store: %w", err)
+	}
+
+	return nil
+}
+
+// LoadSnapshot ...
+func (store *snapshotStore) LoadSnapshot(ctx context.Context, aggregateID string) (snapshot eventsource.Snapshot, err error) {
+	output, err := store.db.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: &store.tableName,
+		Key: map[string]types.AttributeValue{
+			"aggregateId": &types.AttributeValueMemberS{Value: aggregateID},
+		},
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return snapshot, fmt.Errorf("couldn't get snapshot from dynamodb store: %w", err)
+	}
+
+	if len(output.Item) == 0 {
+		return snapshot, eventsource.ErrNoSnapshot
+	}
+
+	if err = attributevalue.UnmarshalMap(output.Item, &snapshot); err != nil {
+		return snapshot, fmt.Errorf("couldn't unmarshal snapshot: %w", err)
+	}
+
+	return snapshot, nil
+}
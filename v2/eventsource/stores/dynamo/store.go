@@ -4,6 +4,8 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"iter"
+	"strconv"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
@@ -14,24 +16,55 @@ import (
 	"github.com/SKF/go-utility/v2/log"
 )
 
+// Store is an event source store backed by a DynamoDB table
+type Store interface {
+	eventsource.IterStore
+	WithVersionKey() Store
+}
+
 type store struct {
-	db        *dynamodb.Client
-	tableName string
+	db         *dynamodb.Client
+	tableName  string
+	versionKey bool
 }
 
-// New creates a new event source store
-func New(db *dynamodb.Client, tableName string) eventsource.Store {
+// New creates a new event source store for a table with the partition key
+// aggregateId (S) and the sort key timestamp (N). The records get versions following
+// the latest record of the aggregate, but as the version isn't part of the key,
+// concurrent saves to the same aggregate may get the same version. Versions are only
+// reliable WithVersionKey, which snapshots and the aggregate cache of the repository
+// depend on.
+func New(db *dynamodb.Client, tableName string) Store {
 	return &store{
 		db:        db,
 		tableName: tableName,
 	}
 }
 
+// WithVersionKey configures the store for a table with the sort key version (N)
+// instead of timestamp. This is required to save records with an expected version,
+// as DynamoDB only can guarantee that a version is unique when it is part of the key.
+// It also avoids the key collisions of records sharing the same timestamp.
+func (store *store) WithVersionKey() Store {
+	store.versionKey = true
+
+	return store
+}
+
 // LoadByAggregate ...
 func (store *store) LoadByAggregate(ctx context.Context, aggregateID string, opts ...eventsource.QueryOption) ([]eventsource.Record, error) {
+	records, err := eventsource.CollectRecords(store.IterByAggregate(ctx, aggregateID, opts...))
+	if err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+// IterByAggregate queries the records of the aggregate one page at a time while iterating
+func (store *store) IterByAggregate(ctx context.Context, aggregateID string, opts ...eventsource.QueryOption) iter.Seq2[eventsource.Record, error] {
 	var (
-		records = []eventsource.Record{}
-		key     = map[string]types.AttributeValue{
+		key = map[string]types.AttributeValue{
 			":id": &types.AttributeValueMemberS{Value: aggregateID},
 		}
 		input = dynamodb.QueryInput{
@@ -40,33 +73,48 @@ func (store *store) LoadByAggregate(ctx context.Context, aggregateID string, opt
 			ExpressionAttributeValues: key,
 			ConsistentRead:            aws.Bool(true),
 		}
-		resultItems []map[string]types.AttributeValue
-		queryOpts   = evaluateQueryOptions(opts)
+		queryOpts = evaluateQueryOptions(opts)
 	)
 
-	addTimestampToQuery(&input, queryOpts.timestamp)
+	if store.versionKey {
+		addTimestampOnQueryFilter(&input, queryOpts.timestamp)
+	} else {
+		addTimestampToQuery(&input, queryOpts.timestamp)
+	}
+
 	addFilteringOnQuery(&input, queryOpts.filterOptions)
+	addVersionToQuery(&input, queryOpts.version, store.versionKey)
 
-	for pagniator := dynamodb.NewQueryPaginator(store.db, &input); pagniator.HasMorePages(); {
-		page, err := pagniator.NextPage(ctx)
-		if err != nil {
-			return nil, fmt.Errorf("couldn't scan pages (input=%+v): %w", input, err)
-		}
+	return func(yield func(eventsource.Record, error) bool) {
+		for paginator := dynamodb.NewQueryPaginator(store.db, &input); paginator.HasMorePages(); {
+			page, err := paginator.NextPage(ctx)
+			if err != nil {
+				yield(eventsource.Record{}, fmt.Errorf("couldn't scan pages (input=%+v): %w", input, err))
+				return
+			}
 
-		resultItems = append(resultItems, page.Items...)
+			if !yieldItems(page.Items, yield) {
+				return
+			}
+		}
 	}
+}
 
-	if err := attributevalue.UnmarshalListOfMaps(resultItems, &records); err != nil {
-		return nil, fmt.Errorf("couldn't unmarshal list of maps: %w", err)
+// Load will load records based on specified query options
+func (store *store) Load(ctx context.Context, opts ...eventsource.QueryOption) ([]eventsource.Record, error) {
+	records, err := eventsource.CollectRecords(store.Iter(ctx, opts...))
+	if err != nil {
+		return nil, err
 	}
 
 	return records, nil
 }
 
-// Load will load records based on specified query options
-func (store *store) Load(ctx context.Context, opts ...eventsource.QueryOption) ([]eventsource.Record, error) {
+// Iter scans the records matching the query options one page at a time while iterating.
+// The filters are applied by DynamoDB after reading each item, so filtering BySequenceID,
+// as subscriptions do when polling, reads the whole table and consumes read capacity for it.
+func (store *store) Iter(ctx context.Context, opts ...eventsource.QueryOption) iter.Seq2[eventsource.Record, error] {
 	var (
-		records   = []eventsource.Record{}
 		queryOpts = evaluateQueryOptions(opts)
 
 		scanInput = dynamodb.ScanInput{
@@ -75,26 +123,105 @@ func (store *store) Load(ctx context.Context, opts ...eventsource.QueryOption) (
 			TableName:      &store.tableName,
 			ConsistentRead: aws.Bool(true),
 		}
-		scanItems = make([]map[string]types.AttributeValue, 0)
 	)
 
 	addTimestampOnScan(&scanInput, queryOpts.timestamp)
 	addFilteringOnScan(&scanInput, queryOpts.filterOptions)
 
-	for paginator := dynamodb.NewScanPaginator(store.db, &scanInput); paginator.HasMorePages(); {
-		page, err := paginator.NextPage(ctx)
-		if err != nil {
-			return nil, fmt.Errorf("couldn't scan pages (input=%+v): %w", scanInput, err)
+	return func(yield func(eventsource.Record, error) bool) {
+		for paginator := dynamodb.NewScanPaginator(store.db, &scanInput); paginator.HasMorePages(); {
+			page, err := paginator.NextPage(ctx)
+			if err != nil {
+				yield(eventsource.Record{}, fmt.Errorf("couldn't scan pages (input=%+v): %w", scanInput, err))
+				return
+			}
+
+			if !yieldItems(page.Items, yield) {
+				return
+			}
 		}
+	}
+}
 
-		scanItems = append(scanItems, page.Items...)
+// yieldItems unmarshals the items of a page and yields them, returning false when the iteration should stop
+func yieldItems(items []map[string]types.AttributeValue, yield func(eventsource.Record, error) bool) bool {
+	var records []eventsource.Record
+	if err := attributevalue.UnmarshalListOfMaps(items, &records); err != nil {
+		yield(eventsource.Record{}, fmt.Errorf("couldn't unmarshal list of maps: %w", err))
+		return false
 	}
 
-	if err := attributevalue.UnmarshalListOfMaps(scanItems, &records); err != nil {
-		return nil, fmt.Errorf("couldn't unmarshal list of maps: %w", err)
+	for _, record := range records {
+		if !yield(record, nil) {
+			return false
+		}
 	}
 
-	return records, nil
+	return true
+}
+
+// loadVersion returns the version of the latest record stored for the aggregate. It's read
+// before writing the records, so it only protects against concurrent writers WithVersionKey,
+// where the conditional writes fail for versions which already exist.
+func (store *store) loadVersion(ctx context.Context, aggregateID string) (int64, error) {
+	output, err := store.db.Query(ctx, &dynamodb.QueryInput{
+		TableName:                &store.tableName,
+		KeyConditionExpression:   aws.String("aggregateId = :id"),
+		ProjectionExpression:     aws.String("#version"),
+		ExpressionAttributeNames: map[string]string{"#version": "version"},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":id": &types.AttributeValueMemberS{Value: aggregateID},
+		},
+		ScanIndexForward: aws.Bool(false),
+		Limit:            aws.Int32(1),
+		ConsistentRead:   aws.Bool(true),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("couldn't query version of aggregate %s: %w", aggregateID, err)
+	}
+
+	if len(output.Items) == 0 {
+		return 0, nil
+	}
+
+	var record eventsource.Record
+	if err = attributevalue.UnmarshalMap(output.Items[0], &record); err != nil {
+		return 0, fmt.Errorf("couldn't unmarshal version: %w", err)
+	}
+
+	// Records saved with earlier releases have no version, so the version is the number of
+	// records. This only happens until the first record with a version is saved for the aggregate.
+	if record.Version == 0 {
+		return store.countRecords(ctx, aggregateID)
+	}
+
+	return record.Version, nil
+}
+
+func (store *store) countRecords(ctx context.Context, aggregateID string) (int64, error) {
+	var (
+		count int64
+		input = dynamodb.QueryInput{
+			TableName:              &store.tableName,
+			KeyConditionExpression: aws.String("aggregateId = :id"),
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":id": &types.AttributeValueMemberS{Value: aggregateID},
+			},
+			Select:         types.SelectCount,
+			ConsistentRead: aws.Bool(true),
+		}
+	)
+
+	for paginator := dynamodb.NewQueryPaginator(store.db, &input); paginator.HasMorePages(); {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return 0, fmt.Errorf("couldn't count records of aggregate %s: %w", aggregateID, err)
+		}
+
+		count += int64(page.Count)
+	}
+
+	return count, nil
 }
 
 func (store *store) LoadBySequenceID(context.Context, string, ...eventsource.QueryOption) ([]eventsource.Record, error) {
@@ -135,6 +262,40 @@ func addFilteringOnQuery(queryInput *dynamodb.QueryInput, filterOption *filterOp
 	}
 }
 
+// addVersionToQuery only queries records with a version greater than the given one, in
+// the key condition if version is the sort key and otherwise in the filter
+func addVersionToQuery(queryInput *dynamodb.QueryInput, version *int64, versionKey bool) {
+	if version == nil {
+		return
+	}
+
+	if queryInput.ExpressionAttributeNames == nil {
+		queryInput.ExpressionAttributeNames = map[string]string{}
+	}
+
+	queryInput.ExpressionAttributeNames["#version"] = "version"
+	queryInput.ExpressionAttributeValues[":version"] = &types.AttributeValueMemberN{Value: strconv.FormatInt(*version, 10)}
+
+	switch {
+	case versionKey:
+		queryInput.KeyConditionExpression = aws.String(aws.ToString(queryInput.KeyConditionExpression) + " AND #version > :version")
+	case queryInput.FilterExpression != nil:
+		queryInput.FilterExpression = aws.String(*queryInput.FilterExpression + " AND #version > :version")
+	default:
+		queryInput.FilterExpression = aws.String("#version > :version")
+	}
+}
+
+func addTimestampOnQueryFilter(queryInput *dynamodb.QueryInput, timestamp *string) {
+	if timestamp != nil {
+		exprWithTs, values, names := mapTimestampToDynamoExpr(queryInput.FilterExpression, queryInput.ExpressionAttributeValues, queryInput.ExpressionAttributeNames, timestamp)
+
+		queryInput.FilterExpression = &exprWithTs
+		queryInput.ExpressionAttributeValues = values
+		queryInput.ExpressionAttributeNames = names
+	}
+}
+
 func addTimestampOnScan(scanInput *dynamodb.ScanInput, timestamp *string) {
 	if timestamp != nil {
 		exprWithTs, values, names := mapTimestampToDynamoExpr(scanInput.FilterExpression, scanInput.ExpressionAttributeValues, scanInput.ExpressionAttributeNames, timestamp)
@@ -13,7 +13,12 @@ import (
 	"github.com/SKF/go-utility/v2/env"
 )
 
-const dynamoTableName = "Events"
+const (
+	dynamoTableName           = "Events"
+	dynamoVersionedTableName  = "EventsByVersion"
+	dynamoSnapshotTableName   = "Snapshots"
+	dynamoCheckpointTableName = "Checkpoints"
+)
 
 func Test_SaveLoadRollback_AllInOne(t *testing.T) {
 	if testing.Short() || env.GetAsString("AWS_REGION", "") == "" {
@@ -104,6 +109,46 @@ func Test_LoadByAggrWithFiltering(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func Test_IterByAggregate(t *testing.T) {
+	if testing.Short() || env.GetAsString("AWS_REGION", "") == "" {
+		t.Skip("Do not run integration test")
+	}
+
+	ctx := context.TODO()
+	cfg, err := config.LoadDefaultConfig(ctx)
+	require.NoError(t, err)
+
+	store := New(dynamodb.NewFromConfig(cfg), dynamoTableName)
+
+	tx, err := store.NewTransaction(ctx, []eventsource.Record{
+		{AggregateID: "iter-A", Timestamp: 1, SequenceID: "1a"},
+		{AggregateID: "iter-A", Timestamp: 2, SequenceID: "1b"},
+		{AggregateID: "iter-A", Timestamp: 3, SequenceID: "1c"},
+	}...)
+	require.NoError(t, err)
+
+	err = tx.Commit()
+	require.NoError(t, err)
+
+	var sequenceIDs []string
+	for record, err := range store.IterByAggregate(ctx, "iter-A") {
+		require.NoError(t, err)
+
+		if sequenceIDs = append(sequenceIDs, record.SequenceID); len(sequenceIDs) == 2 {
+			break
+		}
+	}
+
+	assert.Equal(t, []string{"1a", "1b"}, sequenceIDs)
+
+	records, err := eventsource.CollectRecords(store.Iter(ctx, BySequenceID("1b")))
+	require.NoError(t, err)
+	assert.NotEmpty(t, records)
+
+	err = tx.Rollback()
+	require.NoError(t, err)
+}
+
 func Test_LoadWithFiltering(t *testing.T) {
 	if testing.Short() || env.GetAsString("AWS_REGION", "") == "" {
 		t.Skip("Do not run integration test")
@@ -140,3 +185,102 @@ func Test_LoadWithFiltering(t *testing.T) {
 	err = tx.Rollback()
 	require.NoError(t, err)
 }
+
+func Test_SaveWithVersionKey(t *testing.T) {
+	if testing.Short() || env.GetAsString("AWS_REGION", "") == "" {
+		t.Skip("Do not run integration test")
+	}
+
+	ctx := context.TODO()
+	cfg, err := config.LoadDefaultConfig(ctx)
+	require.NoError(t, err)
+
+	store := New(dynamodb.NewFromConfig(cfg), dynamoVersionedTableName).WithVersionKey()
+
+	tx, err := store.NewTransaction(ctx, []eventsource.Record{
+		{AggregateID: "A", Timestamp: 1, SequenceID: "1a"},
+		{AggregateID: "A", Timestamp: 2, SequenceID: "1b"},
+	}...)
+	require.NoError(t, err)
+
+	err = tx.Commit()
+	require.NoError(t, err)
+
+	conflicting, err := store.NewTransaction(ctx, eventsource.Record{AggregateID: "A", Timestamp: 3, SequenceID: "1c", Version: 2})
+	require.NoError(t, err)
+	require.ErrorIs(t, conflicting.Commit(), eventsource.ErrConcurrencyConflict)
+
+	records, err := store.LoadByAggregate(ctx, "A")
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, int64(1), records[0].Version)
+	assert.Equal(t, int64(2), records[1].Version)
+
+	err = tx.Rollback()
+	require.NoError(t, err)
+}
+
+func Test_SaveWithVersionKey_TooManyRecords(t *testing.T) {
+	t.Parallel()
+
+	records := make([]eventsource.Record, maxTransactItems+1)
+	for i := range records {
+		records[i] = eventsource.Record{AggregateID: "A", Timestamp: int64(i)}
+	}
+
+	store := New(nil, dynamoVersionedTableName).WithVersionKey()
+	tx, err := store.NewTransaction(context.TODO(), records...)
+	require.NoError(t, err)
+
+	require.Error(t, tx.Commit())
+	require.NoError(t, tx.Rollback(), "Nothing was saved")
+}
+
+func Test_SaveLoadSnapshot(t *testing.T) {
+	if testing.Short() || env.GetAsString("AWS_REGION", "") == "" {
+		t.Skip("Do not run integration test")
+	}
+
+	ctx := context.TODO()
+	cfg, err := config.LoadDefaultConfig(ctx)
+	require.NoError(t, err)
+
+	store := NewSnapshotStore(dynamodb.NewFromConfig(cfg), dynamoSnapshotTableName)
+
+	_, err = store.LoadSnapshot(ctx, "snapshot-A")
+	require.ErrorIs(t, err, eventsource.ErrNoSnapshot)
+
+	err = store.SaveSnapshot(ctx, eventsource.Snapshot{AggregateID: "snapshot-A", SequenceID: "2", Version: 2, Data: []byte("2")})
+	require.NoError(t, err)
+
+	err = store.SaveSnapshot(ctx, eventsource.Snapshot{AggregateID: "snapshot-A", SequenceID: "1", Version: 1, Data: []byte("1")})
+	require.NoError(t, err)
+
+	snapshot, err := store.LoadSnapshot(ctx, "snapshot-A")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), snapshot.Version)
+	assert.Equal(t, []byte("2"), snapshot.Data)
+}
+
+func Test_SaveLoadCheckpoint(t *testing.T) {
+	if testing.Short() || env.GetAsString("AWS_REGION", "") == "" {
+		t.Skip("Do not run integration test")
+	}
+
+	ctx := context.TODO()
+	cfg, err := config.LoadDefaultConfig(ctx)
+	require.NoError(t, err)
+
+	store := NewCheckpointStore(dynamodb.NewFromConfig(cfg), dynamoCheckpointTableName)
+
+	sequenceID, err := store.LoadCheckpoint(ctx, "missing")
+	require.NoError(t, err)
+	assert.Empty(t, sequenceID)
+
+	err = store.SaveCheckpoint(ctx, "projection", "1a")
+	require.NoError(t, err)
+
+	sequenceID, err = store.LoadCheckpoint(ctx, "projection")
+	require.NoError(t, err)
+	assert.Equal(t, "1a", sequenceID)
+}
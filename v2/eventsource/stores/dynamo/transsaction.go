@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
@@ -12,6 +13,9 @@ import (
 	"github.com/SKF/go-eventsource/v2/eventsource"
 )
 
+// maxTransactItems is the maximum number of items in a DynamoDB transaction
+const maxTransactItems = 100
+
 type transaction struct {
 	store   *store
 	ctx     context.Context
@@ -30,6 +34,26 @@ func (store *store) NewTransaction(ctx context.Context, records ...eventsource.R
 
 // Commit ...
 func (tx *transaction) Commit() error {
+	if !tx.store.versionKey {
+		for _, record := range tx.records {
+			if record.Version > 0 {
+				return errors.New("couldn't save record with version, the store isn't configured WithVersionKey")
+			}
+		}
+	}
+
+	if tx.store.versionKey && len(tx.records) > maxTransactItems {
+		return errors.Errorf("couldn't save %d records in one transaction, at most %d are supported WithVersionKey", len(tx.records), maxTransactItems)
+	}
+
+	if err := tx.assignVersions(); err != nil {
+		return err
+	}
+
+	if tx.store.versionKey {
+		return tx.commitVersioned()
+	}
+
 	for _, record := range tx.records {
 		result, err := attributevalue.MarshalMap(record)
 		if err != nil {
@@ -50,14 +74,83 @@ func (tx *transaction) Commit() error {
 	return nil
 }
 
+// assignVersions gives each record the version following the records already stored
+// for its aggregate, and verifies that records saved with a version are in sequence
+func (tx *transaction) assignVersions() error {
+	versions := map[string]int64{}
+
+	for i, record := range tx.records {
+		version, ok := versions[record.AggregateID]
+		if !ok {
+			var err error
+			if version, err = tx.store.loadVersion(tx.ctx, record.AggregateID); err != nil {
+				return err
+			}
+		}
+
+		version++
+
+		if record.Version != 0 && record.Version != version {
+			return errors.Wrapf(eventsource.ErrConcurrencyConflict, "aggregate %s is at version %d, expected %d", record.AggregateID, version-1, record.Version-1)
+		}
+
+		tx.records[i].Version = version
+		versions[record.AggregateID] = version
+	}
+
+	return nil
+}
+
+// commitVersioned puts the records, in a single DynamoDB transaction, on the condition
+// that their versions don't already exist for the aggregate
+func (tx *transaction) commitVersioned() error {
+	items := make([]types.TransactWriteItem, 0, len(tx.records))
+
+	for _, record := range tx.records {
+		item, err := attributevalue.MarshalMap(record)
+		if err != nil {
+			return errors.Wrap(err, "couldn't marshal record")
+		}
+
+		items = append(items, types.TransactWriteItem{
+			Put: &types.Put{
+				TableName:           &tx.store.tableName,
+				Item:                item,
+				ConditionExpression: aws.String("attribute_not_exists(aggregateId)"),
+			},
+		})
+	}
+
+	_, err := tx.store.db.TransactWriteItems(tx.ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: items,
+	})
+	if err != nil {
+		return wrapTransactError(err)
+	}
+
+	tx.saved = append(tx.saved, tx.records...)
+
+	return nil
+}
+
+func wrapTransactError(err error) error {
+	var canceled *types.TransactionCanceledException
+	if errors.As(err, &canceled) {
+		for _, reason := range canceled.CancellationReasons {
+			if aws.ToString(reason.Code) == "ConditionalCheckFailed" {
+				return errors.Wrap(eventsource.ErrConcurrencyConflict, "version already exists")
+			}
+		}
+	}
+
+	return errors.Wrap(err, "couldn't write records to dynamodb store")
+}
+
 func (tx *transaction) Rollback() error {
 	for _, record := range tx.saved {
 		_, err := tx.store.db.DeleteItem(tx.ctx, &dynamodb.DeleteItemInput{
 			TableName: &tx.store.tableName,
-			Key: map[string]types.AttributeValue{
-				"aggregateId": &types.AttributeValueMemberS{Value: record.AggregateID},
-				"timestamp":   &types.AttributeValueMemberN{Value: fmt.Sprintf("%d", record.Timestamp)},
-			},
+			Key:       tx.store.key(record),
 		})
 		if err != nil {
 			return errors.Wrap(err, "couldn't delete record in dynamodb store")
@@ -70,3 +163,17 @@ func (tx *transaction) Rollback() error {
 func (tx *transaction) GetRecords() []eventsource.Record {
 	return tx.records
 }
+
+func (store *store) key(record eventsource.Record) map[string]types.AttributeValue {
+	if store.versionKey {
+		return map[string]types.AttributeValue{
+			"aggregateId": &types.AttributeValueMemberS{Value: record.AggregateID},
+			"version":     &types.AttributeValueMemberN{Value: fmt.Sprintf("%d", record.Version)},
+		}
+	}
+
+	return map[string]types.AttributeValue{
+		"aggregateId": &types.AttributeValueMemberS{Value: record.AggregateID},
+		"timestamp":   &types.AttributeValueMemberN{Value: fmt.Sprintf("%d", record.Timestamp)},
+	}
+}
@@ -23,15 +23,24 @@ type options struct {
 	filters []FilterFunc
 }
 
-// WithLimit will limit the result
+// WithLimit will limit the result. Like eventsource.WithLimit, it's also applied to other
+// options implementing LimitRecords(limit int), so the repository sees the limit
 func WithLimit(limit int) eventsource.QueryOption {
 	return func(i interface{}) {
-		if o, ok := i.(*options); ok {
+		switch o := i.(type) {
+		case *options:
 			o.limit = &limit
+		case interface{ LimitRecords(limit int) }:
+			o.LimitRecords(limit)
 		}
 	}
 }
 
+// LimitRecords implements support for eventsource.WithLimit
+func (o *options) LimitRecords(limit int) {
+	WithLimit(limit)(o)
+}
+
 func WithFilter(filter FilterFunc) eventsource.QueryOption {
 	return func(i interface{}) {
 		if o, ok := i.(*options); ok {
@@ -46,6 +55,23 @@ func BySequenceID(sequenceID string) eventsource.QueryOption {
 	})
 }
 
+// FilterBySequenceID implements support for eventsource.BySequenceID
+func (o *options) FilterBySequenceID(sequenceID string) {
+	BySequenceID(sequenceID)(o)
+}
+
+// ByVersion will filter to only return records with a version greater than version
+func ByVersion(version int64) eventsource.QueryOption {
+	return WithFilter(func(record eventsource.Record) bool {
+		return record.Version > version
+	})
+}
+
+// FilterByVersion implements support for eventsource.ByVersion
+func (o *options) FilterByVersion(version int64) {
+	ByVersion(version)(o)
+}
+
 func ByType(eventType string) eventsource.QueryOption {
 	return WithFilter(func(record eventsource.Record) bool {
 		return record.Type == eventType
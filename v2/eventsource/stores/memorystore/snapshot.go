@@ -0,0 +1,45 @@
+package memorystore
+
+import (
+	"context"
+	"sync"
+
+	"github.com/SKF/go-eventsource/v2/eventsource"
+)
+
+type snapshotStore struct {
+	Data  map[string]eventsource.Snapshot
+	mutex sync.RWMutex
+}
+
+// NewSnapshotStore creates a new snapshot store
+func NewSnapshotStore() eventsource.SnapshotStore {
+	return &snapshotStore{
+		Data: map[string]eventsource.Snapshot{},
+	}
+}
+
+func (mem *snapshotStore) SaveSnapshot(_ context.Context, snapshot eventsource.Snapshot) error {
+	mem.mutex.Lock()
+	defer mem.mutex.Unlock()
+
+	if existing, ok := mem.Data[snapshot.AggregateID]; ok && existing.Version >= snapshot.Version {
+		return nil
+	}
+
+	mem.Data[snapshot.AggregateID] = snapshot
+
+	return nil
+}
+
+func (mem *snapshotStore) LoadSnapshot(_ context.Context, aggregateID string) (eventsource.Snapshot, error) {
+	mem.mutex.RLock()
+	defer mem.mutex.RUnlock()
+
+	snapshot, ok := mem.Data[aggregateID]
+	if !ok {
+		return eventsource.Snapshot{}, eventsource.ErrNoSnapshot
+	}
+
+	return snapshot, nil
+}
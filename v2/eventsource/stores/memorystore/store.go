@@ -2,6 +2,7 @@ package memorystore
 
 import (
 	"context"
+	"iter"
 	"sync"
 
 	"github.com/SKF/go-eventsource/v2/eventsource"
@@ -13,7 +14,7 @@ type store struct {
 }
 
 // New creates a new event store
-func New() eventsource.Store {
+func New() eventsource.IterStore {
 	return &store{
 		Data: map[string][]eventsource.Record{},
 	}
@@ -24,14 +25,35 @@ func (mem *store) Load(_ context.Context, opts ...eventsource.QueryOption) ([]ev
 	return mem.loadRecords(opts)
 }
 
+// Iter iterates over the records matching the query options, as they were when the iteration started
+func (mem *store) Iter(_ context.Context, opts ...eventsource.QueryOption) iter.Seq2[eventsource.Record, error] {
+	records, _ := mem.loadRecords(opts) // nolint:errcheck
+
+	return iterRecords(records)
+}
+
+// IterByAggregate iterates over the records of the aggregate, as they were when the iteration started
+func (mem *store) IterByAggregate(ctx context.Context, aggregateID string, opts ...eventsource.QueryOption) iter.Seq2[eventsource.Record, error] {
+	records, _ := mem.LoadByAggregate(ctx, aggregateID, opts...) // nolint:errcheck
+
+	return iterRecords(records)
+}
+
+func iterRecords(records []eventsource.Record) iter.Seq2[eventsource.Record, error] {
+	return func(yield func(eventsource.Record, error) bool) {
+		for _, record := range records {
+			if !yield(record, nil) {
+				return
+			}
+		}
+	}
+}
+
 func (mem *store) LoadByAggregate(_ context.Context, aggregateID string, opts ...eventsource.QueryOption) (records []eventsource.Record, err error) {
 	mem.mutex.RLock()
 	defer mem.mutex.RUnlock()
 
-	if rows, ok := mem.Data[aggregateID]; ok {
-		return rows, nil
-	}
-	return records, nil
+	return filterRecords(mem.Data[aggregateID], evaluateQueryOptions(opts)), nil
 }
 
 func (mem *store) loadRecords(opts []eventsource.QueryOption) (records []eventsource.Record, err error) {
@@ -45,6 +67,11 @@ func (mem *store) loadRecords(opts []eventsource.QueryOption) (records []eventso
 		recordSlice = append(recordSlice, aggregate...)
 	}
 	queryOpts.sorter(recordSlice)
+
+	return filterRecords(recordSlice, queryOpts), nil
+}
+
+func filterRecords(recordSlice []eventsource.Record, queryOpts *options) (records []eventsource.Record) {
 	for _, record := range recordSlice {
 		filterResult := true
 		for _, filter := range queryOpts.filters {
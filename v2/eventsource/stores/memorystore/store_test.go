@@ -2,6 +2,7 @@ package memorystore
 
 import (
 	"context"
+	"strconv"
 	"sync"
 	"testing"
 
@@ -87,6 +88,150 @@ func TestMemoryStoreConcurrentSave(t *testing.T) {
 	wg.Wait()
 }
 
+func TestMemoryStoreSaveWithExpectedVersion(t *testing.T) {
+	ctx := context.Background()
+	store := New()
+	repo := eventsource.NewRepository(store, &serializer{})
+
+	err := repo.SaveWithExpectedVersion(ctx, "A", 0, &eventsource.BaseEvent{AggregateID: "A"}, &eventsource.BaseEvent{AggregateID: "A"})
+	require.NoError(t, err)
+
+	err = repo.SaveWithExpectedVersion(ctx, "A", 1, &eventsource.BaseEvent{AggregateID: "A"})
+	require.ErrorIs(t, err, eventsource.ErrConcurrencyConflict)
+
+	err = repo.SaveWithExpectedVersion(ctx, "A", 2, &eventsource.BaseEvent{AggregateID: "A"})
+	require.NoError(t, err)
+
+	records, err := store.LoadByAggregate(ctx, "A")
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+
+	for i, record := range records {
+		assert.Equal(t, int64(i+1), record.Version)
+	}
+}
+
+func TestMemoryStoreAssignsVersions(t *testing.T) {
+	ctx := context.Background()
+	store := New()
+	repo := eventsource.NewRepository(store, &serializer{})
+
+	first, second := &eventsource.BaseEvent{AggregateID: "A"}, &eventsource.BaseEvent{AggregateID: "A"}
+	require.NoError(t, repo.Save(ctx, first, &eventsource.BaseEvent{AggregateID: "B"}))
+	require.NoError(t, repo.Save(ctx, second))
+
+	assert.Equal(t, int64(1), first.Version)
+	assert.Equal(t, int64(2), second.Version)
+
+	events, err := repo.LoadEvents(ctx)
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+	assert.Equal(t, int64(1), events[0].GetVersion())
+	assert.Equal(t, int64(1), events[1].GetVersion())
+	assert.Equal(t, int64(2), events[2].GetVersion())
+}
+
+func TestMemoryStoreMetadata(t *testing.T) {
+	ctx := eventsource.WithCorrelationID(context.Background(), "correlation")
+	store := New()
+	repo := eventsource.NewRepository(store, &serializer{})
+
+	require.NoError(t, repo.Save(ctx, &eventsource.BaseEvent{AggregateID: "A"}))
+
+	records, err := store.LoadByAggregate(ctx, "A")
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, "correlation", records[0].Metadata.CorrelationID())
+}
+
+func TestMemoryStoreLoadFromSnapshot(t *testing.T) {
+	ctx := context.Background()
+	store, snapshots := New(), NewSnapshotStore()
+	repo := eventsource.NewRepository(store, &serializer{}, eventsource.WithSnapshots(snapshots, eventsource.EveryNEvents(2),
+		func(string) eventsource.Snapshotter { return &counter{} }))
+
+	for range 3 {
+		require.NoError(t, repo.Save(ctx, &eventsource.BaseEvent{AggregateID: "A", SequenceID: eventsource.NewULID()}))
+	}
+
+	snapshot, err := snapshots.LoadSnapshot(ctx, "A")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), snapshot.Version, "The snapshot is saved when committing the second event")
+	assert.Equal(t, []byte("2"), snapshot.Data)
+
+	aggr := &counter{}
+	_, err = repo.Load(ctx, "A", aggr)
+	require.NoError(t, err)
+	assert.Equal(t, 3, aggr.count)
+	assert.Equal(t, 1, aggr.replayed)
+
+	snapshot, err = snapshots.LoadSnapshot(ctx, "A")
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), snapshot.Version, "Loading doesn't save snapshots")
+
+	require.NoError(t, repo.Save(ctx, &eventsource.BaseEvent{AggregateID: "A", SequenceID: eventsource.NewULID()}))
+
+	aggr = &counter{}
+	_, err = repo.Load(ctx, "A", aggr)
+	require.NoError(t, err)
+	assert.Equal(t, 4, aggr.count)
+	assert.Equal(t, 0, aggr.replayed)
+}
+
+func TestMemoryStoreIter(t *testing.T) {
+	ctx := context.Background()
+	store := New()
+
+	tx, err := store.NewTransaction(ctx, []eventsource.Record{
+		{AggregateID: "A", SequenceID: "1"},
+		{AggregateID: "B", SequenceID: "2"},
+		{AggregateID: "A", SequenceID: "3"},
+	}...)
+	require.NoError(t, err)
+	require.NoError(t, tx.Commit())
+
+	var sequenceIDs []string
+	for record, err := range store.Iter(ctx) {
+		require.NoError(t, err)
+
+		if sequenceIDs = append(sequenceIDs, record.SequenceID); len(sequenceIDs) == 2 {
+			break
+		}
+	}
+
+	assert.Equal(t, []string{"1", "2"}, sequenceIDs)
+
+	records, err := eventsource.CollectRecords(store.IterByAggregate(ctx, "A", BySequenceID("1")))
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, "3", records[0].SequenceID)
+}
+
+type counter struct {
+	id       string
+	count    int
+	replayed int
+}
+
+func (c *counter) SetAggregateID(id string) { c.id = id }
+
+func (c *counter) On(_ context.Context, _ eventsource.Event) error {
+	c.count++
+	c.replayed++
+
+	return nil
+}
+
+func (c *counter) MarshalSnapshot() ([]byte, error) {
+	return []byte(strconv.Itoa(c.count)), nil
+}
+
+func (c *counter) UnmarshalSnapshot(data []byte) (err error) {
+	c.count, err = strconv.Atoi(string(data))
+
+	return err // nolint:wrapcheck
+}
+
 type serializer struct{}
 
 func (s *serializer) Unmarshal(data []byte, eventType string) (event eventsource.Event, err error) {
@@ -2,6 +2,7 @@ package memorystore
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/SKF/go-eventsource/v2/eventsource"
 )
@@ -23,6 +24,10 @@ func (tx *transaction) Commit() error {
 	tx.mem.mutex.Lock()
 	defer tx.mem.mutex.Unlock()
 
+	if err := tx.assignVersions(); err != nil {
+		return err
+	}
+
 	for _, record := range tx.records {
 		id := record.AggregateID
 		tx.mem.Data[id] = append(tx.mem.Data[id], record)
@@ -31,6 +36,34 @@ func (tx *transaction) Commit() error {
 	return nil
 }
 
+// assignVersions gives each record the version following the records already stored
+// for its aggregate, and verifies that records saved with a version are in sequence
+func (tx *transaction) assignVersions() error {
+	versions := map[string]int64{}
+	assigned := make([]int64, len(tx.records))
+
+	for i, record := range tx.records {
+		id := record.AggregateID
+		if _, ok := versions[id]; !ok {
+			versions[id] = int64(len(tx.mem.Data[id]))
+		}
+
+		versions[id]++
+
+		if record.Version != 0 && record.Version != versions[id] {
+			return fmt.Errorf("%w: aggregate %s is not at version %d", eventsource.ErrConcurrencyConflict, id, record.Version-1)
+		}
+
+		assigned[i] = versions[id]
+	}
+
+	for i := range tx.records {
+		tx.records[i].Version = assigned[i]
+	}
+
+	return nil
+}
+
 func (tx *transaction) Rollback() error {
 	tx.mem.mutex.Lock()
 	defer tx.mem.mutex.Unlock()
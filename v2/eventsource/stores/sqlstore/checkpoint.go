@@ -0,0 +1,51 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/SKF/go-eventsource/v2/eventsource/projection"
+	"github.com/SKF/go-eventsource/v2/eventsource/stores/sqlstore/driver"
+)
+
+// CheckpointDB is implemented by the drivers to save and load checkpoints
+type CheckpointDB interface {
+	SaveCheckpoint(ctx context.Context, query string, name string, sequenceID string) error
+	LoadCheckpoint(ctx context.Context, query string, name string) (string, error)
+}
+
+type checkpointStore struct {
+	db        CheckpointDB
+	tableName string
+}
+
+var (
+	saveCheckpointSQL = `INSERT INTO %s (name, sequence_id) VALUES ($1, $2)
+		ON CONFLICT (name) DO UPDATE SET sequence_id = EXCLUDED.sequence_id`
+	loadCheckpointSQL = "SELECT sequence_id FROM %s WHERE name = $1"
+)
+
+// NewCheckpointStore creates a new checkpoint store for projections, keeping the checkpoints in the table.
+func NewCheckpointStore(db *sql.DB, tableName string) projection.CheckpointStore {
+	return &checkpointStore{
+		db:        &driver.Generic{DB: db},
+		tableName: tableName,
+	}
+}
+
+// NewPgxCheckpointStore creates a new checkpoint store for projections, keeping the checkpoints in the table.
+func NewPgxCheckpointStore(db driver.PgxPool, tableName string) projection.CheckpointStore {
+	return &checkpointStore{
+		db:        &driver.PGX{DB: db, NotificationChannel: nil},
+		tableName: tableName,
+	}
+}
+
+func (s *checkpointStore) SaveCheckpoint(ctx context.Context, name string, sequenceID string) error {
+	return s.db.SaveCheckpoint(ctx, fmt.Sprintf(saveCheckpointSQL, s.tableName), name, sequenceID) // nolint:wrapcheck
+}
+
+func (s *checkpointStore) LoadCheckpoint(ctx context.Context, name string) (string, error) {
+	return s.db.LoadCheckpoint(ctx, fmt.Sprintf(loadCheckpointSQL, s.tableName), name) // nolint:wrapcheck
+}
@@ -0,0 +1,70 @@
+package driver
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+// SaveCheckpoint executes the query with the name and the sequence ID as arguments
+func (dwWrap *Generic) SaveCheckpoint(ctx context.Context, query string, name string, sequenceID string) error {
+	if _, err := dwWrap.DB.ExecContext(ctx, query, name, sequenceID); err != nil {
+		return errors.Wrap(err, "failed to save checkpoint")
+	}
+
+	return nil
+}
+
+// LoadCheckpoint executes the query with the name as argument and scans the sequence ID
+func (dwWrap *Generic) LoadCheckpoint(ctx context.Context, query string, name string) (sequenceID string, err error) {
+	err = dwWrap.DB.QueryRowContext(ctx, query, name).Scan(&sequenceID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+
+	if err != nil {
+		return "", errors.Wrap(err, "failed to load checkpoint")
+	}
+
+	return sequenceID, nil
+}
+
+// SaveCheckpoint executes the query with the name and the sequence ID as arguments
+func (pgx *PGX) SaveCheckpoint(ctx context.Context, query string, name string, sequenceID string) error {
+	rows, err := pgx.DB.Query(ctx, query, name, sequenceID)
+	if err != nil {
+		return errors.Wrap(err, "failed to save checkpoint using pgx")
+	}
+
+	rows.Close()
+
+	if err = rows.Err(); err != nil {
+		return errors.Wrap(err, "failed to save checkpoint using pgx")
+	}
+
+	return nil
+}
+
+// LoadCheckpoint executes the query with the name as argument and scans the sequence ID
+func (pgx *PGX) LoadCheckpoint(ctx context.Context, query string, name string) (sequenceID string, err error) {
+	rows, err := pgx.DB.Query(ctx, query, name)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to load checkpoint using pgx")
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err = rows.Err(); err != nil {
+			return "", errors.Wrap(err, "failed to load checkpoint using pgx")
+		}
+
+		return "", nil
+	}
+
+	if err = rows.Scan(&sequenceID); err != nil {
+		return "", errors.Wrap(err, "failed to scan checkpoint")
+	}
+
+	return sequenceID, nil
+}
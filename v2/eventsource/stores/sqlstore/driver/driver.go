@@ -0,0 +1,109 @@
+package driver
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+
+	"github.com/SKF/go-eventsource/v2/eventsource"
+)
+
+// uniqueViolation is the SQLSTATE reported by postgres when a unique index is violated
+const uniqueViolation = "23505"
+
+// Queries are the statements executed by a driver when writing records.
+// Insert takes the record columns as arguments, and Version takes the aggregate ID
+// and returns the latest version stored for the aggregate. Outbox is optional and
+// takes the same arguments as Insert, to also write the records to an outbox within
+// the same transaction.
+type Queries struct {
+	Insert  string
+	Version string
+	Outbox  string
+}
+
+// recordWriter is implemented by the transactions of the drivers
+type recordWriter interface {
+	version(ctx context.Context, aggregateID string) (int64, error)
+	insert(ctx context.Context, query string, record eventsource.Record) error
+}
+
+// writeRecords inserts the records, giving each the version following the latest version
+// stored for its aggregate, and verifies that records saved with a version are in sequence.
+// Writers aren't serialized, instead the unique index on the aggregate ID and version makes
+// a concurrent writer of the same version fail with ErrConcurrencyConflict.
+func writeRecords(ctx context.Context, w recordWriter, queries Queries, records []eventsource.Record) ([]eventsource.Record, error) {
+	versions := map[string]int64{}
+	written := make([]eventsource.Record, 0, len(records))
+
+	for _, record := range records {
+		version, ok := versions[record.AggregateID]
+		if !ok {
+			var err error
+			if version, err = w.version(ctx, record.AggregateID); err != nil {
+				return nil, errors.Wrap(err, "failed to load aggregate version")
+			}
+		}
+
+		version++
+
+		if record.Version != 0 && record.Version != version {
+			return nil, errors.Wrapf(eventsource.ErrConcurrencyConflict, "aggregate %s is at version %d, expected %d", record.AggregateID, version-1, record.Version-1)
+		}
+
+		record.Version = version
+		versions[record.AggregateID] = version
+
+		if err := w.insert(ctx, queries.Insert, record); err != nil {
+			return nil, wrapInsertError(err, record)
+		}
+
+		if queries.Outbox != "" {
+			if err := w.insert(ctx, queries.Outbox, record); err != nil {
+				return nil, errors.Wrap(err, "failed to write record to outbox")
+			}
+		}
+
+		written = append(written, record)
+	}
+
+	return written, nil
+}
+
+// wrapInsertError translates unique violations on versions to ErrConcurrencyConflict,
+// as another writer has stored the same version
+func wrapInsertError(err error, record eventsource.Record) error {
+	var sqlErr interface{ SQLState() string }
+	if errors.As(err, &sqlErr) && sqlErr.SQLState() == uniqueViolation {
+		return errors.Wrapf(eventsource.ErrConcurrencyConflict, "version %d of aggregate %s already exists", record.Version, record.AggregateID)
+	}
+
+	return errors.Wrap(err, "failed to execute query")
+}
+
+// marshalMetadata returns the metadata as JSON for the metadata column, or nil if there is none
+func marshalMetadata(metadata eventsource.Metadata) (interface{}, error) {
+	if len(metadata) == 0 {
+		return nil, nil
+	}
+
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal metadata")
+	}
+
+	return string(data), nil
+}
+
+func unmarshalMetadata(data []byte) (metadata eventsource.Metadata, err error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	if err = json.Unmarshal(data, &metadata); err != nil {
+		return nil, errors.Wrap(err, "failed to unmarshal metadata")
+	}
+
+	return metadata, nil
+}
@@ -3,6 +3,7 @@ package driver
 import (
 	"context"
 	"database/sql"
+	"iter"
 
 	"github.com/pkg/errors"
 
@@ -14,71 +15,67 @@ type Generic struct {
 }
 
 func (dwWrap *Generic) Load(ctx context.Context, query string, args []interface{}) ([]eventsource.Record, error) {
-	records := []eventsource.Record{}
+	return eventsource.CollectRecords(dwWrap.Iter(ctx, query, args))
+}
 
-	stmt, err := dwWrap.DB.PrepareContext(ctx, query)
-	if err != nil {
-		return records, errors.Wrap(err, "failed to prepare sql query")
-	}
+// Iter executes the query and scans the rows lazily while iterating
+func (dwWrap *Generic) Iter(ctx context.Context, query string, args []interface{}) iter.Seq2[eventsource.Record, error] {
+	return func(yield func(eventsource.Record, error) bool) {
+		rows, err := dwWrap.DB.QueryContext(ctx, query, args...)
+		if err != nil {
+			yield(eventsource.Record{}, errors.Wrap(err, "failed to execute sql query"))
+			return
+		}
+		defer rows.Close()
 
-	defer func() {
-		if errClose := stmt.Close(); errClose != nil {
+		for rows.Next() {
+			record, err := scanGeneric(rows)
 			if err != nil {
-				err = errors.Wrapf(err, "failed to close sql statement: %s", errClose)
-			} else {
-				err = errors.Wrap(errClose, "failed to close sql statement")
+				yield(eventsource.Record{}, err)
+				return
 			}
-		}
-	}()
-
-	rows, err := stmt.QueryContext(ctx, args...)
-	if err != nil {
-		err = errors.Wrap(err, "failed to execute sql query")
-
-		return records, err
-	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var record eventsource.Record
-		if err = rows.Scan(
-			&record.AggregateID, &record.SequenceID, &record.Timestamp,
-			&record.UserID, &record.Type, &record.Data,
-		); err != nil {
-			err = errors.Wrap(err, "failed to scan sql row")
 
-			return records, err
+			if !yield(record, nil) {
+				return
+			}
 		}
 
-		records = append(records, record)
+		if err = rows.Err(); err != nil {
+			yield(eventsource.Record{}, errors.Wrap(err, "errors returned from sql store"))
+		}
 	}
+}
 
-	if err = rows.Err(); err != nil {
-		err = errors.Wrap(err, "errors returned from sql store")
-
-		return records, err
+func scanGeneric(rows *sql.Rows) (record eventsource.Record, err error) {
+	var (
+		userID   sql.NullString
+		version  sql.NullInt64
+		metadata []byte
+	)
+
+	if err = rows.Scan(
+		&record.AggregateID, &record.SequenceID, &record.Timestamp,
+		&userID, &record.Type, &record.Data, &version, &metadata,
+	); err != nil {
+		return record, errors.Wrap(err, "failed to scan sql row")
 	}
 
-	return records, err
+	record.UserID = userID.String
+	record.Version = version.Int64
+	record.Metadata, err = unmarshalMetadata(metadata)
+
+	return record, err
 }
 
-func (dwWrap *Generic) NewTransaction(ctx context.Context, query string, records ...eventsource.Record) (eventsource.StoreTransaction, error) {
+func (dwWrap *Generic) NewTransaction(ctx context.Context, queries Queries, records ...eventsource.Record) (eventsource.StoreTransaction, error) {
 	tx, err := dwWrap.DB.BeginTx(ctx, nil)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to start new transaction")
 	}
 
-	stmt, err := tx.PrepareContext(ctx, query)
+	records, err = writeRecords(ctx, &genericWriter{tx: tx, queries: queries}, queries, records)
 	if err != nil {
-		return nil, errors.Wrap(err, "failed to prepare query")
-	}
-	defer stmt.Close()
-
-	for _, record := range records {
-		_, err = stmt.ExecContext(ctx, record.AggregateID, record.SequenceID, record.Timestamp, record.UserID, record.Type, record.Data)
-		if err != nil {
-			return nil, errors.Wrap(err, "failed to execute query")
-		}
+		return nil, rollbackGeneric(tx, err)
 	}
 
 	return &generalTransaction{
@@ -87,6 +84,39 @@ func (dwWrap *Generic) NewTransaction(ctx context.Context, query string, records
 	}, nil
 }
 
+type genericWriter struct {
+	tx      *sql.Tx
+	queries Queries
+}
+
+func (w *genericWriter) version(ctx context.Context, aggregateID string) (version int64, err error) {
+	err = w.tx.QueryRowContext(ctx, w.queries.Version, aggregateID).Scan(&version)
+
+	return
+}
+
+func (w *genericWriter) insert(ctx context.Context, query string, record eventsource.Record) error {
+	metadata, err := marshalMetadata(record.Metadata)
+	if err != nil {
+		return err
+	}
+
+	// records without a user ID, like encrypted events, get NULL as the empty string isn't a uuid
+	userID := sql.NullString{String: record.UserID, Valid: record.UserID != ""}
+
+	_, err = w.tx.ExecContext(ctx, query, record.AggregateID, record.SequenceID, record.Timestamp, userID, record.Type, record.Data, record.Version, metadata)
+
+	return err // nolint:wrapcheck
+}
+
+func rollbackGeneric(tx *sql.Tx, err error) error {
+	if rollbackErr := tx.Rollback(); rollbackErr != nil {
+		return errors.Wrapf(err, "rollback error: %+v", rollbackErr)
+	}
+
+	return err
+}
+
 type generalTransaction struct {
 	sqlTx   *sql.Tx
 	records []eventsource.Record
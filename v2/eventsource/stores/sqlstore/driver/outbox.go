@@ -0,0 +1,232 @@
+package driver
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/pkg/errors"
+
+	"github.com/SKF/go-eventsource/v2/eventsource"
+)
+
+// OutboxQueries are the statements executed by a driver when relaying records from an outbox.
+// Claim takes the maximum number of attempts, the batch size, the current time and the time
+// the claim expires as arguments. It claims the pending records which aren't claimed by
+// someone else, and returns their record columns. Delivered takes the sequence ID and
+// removes the delivered record, Failed takes the sequence ID and the error message, and Release takes the
+// sequence ID of a claimed record which wasn't sent.
+type OutboxQueries struct {
+	Claim     string
+	Delivered string
+	Failed    string
+	Release   string
+}
+
+// SendFunc publishes a record relayed from the outbox
+type SendFunc func(ctx context.Context, record eventsource.Record) error
+
+// ErrSendFailed is returned when relaying records from the outbox and a record couldn't be sent
+var ErrSendFailed = errors.New("failed to send record")
+
+// relayTx is implemented by the transactions of the drivers
+type relayTx interface {
+	claim(ctx context.Context, query string, args ...interface{}) ([]eventsource.Record, error)
+	exec(ctx context.Context, query string, args ...interface{}) error
+	commit(ctx context.Context) error
+	rollback(err error) error
+}
+
+// relayRecords claims the pending records in one transaction, sends them in sequence order
+// outside of it, and marks them in another transaction. Other relays skip the claimed records
+// until the lease has expired, so a relay which stops while sending only delays its records.
+// At the first record which can't be sent, its failed attempt is recorded and the following
+// records are released, so the records are sent in order. It returns the number of
+// delivered records.
+func relayRecords(ctx context.Context, begin func(ctx context.Context) (relayTx, error), queries OutboxQueries, maxAttempts, limit int, lease time.Duration, send SendFunc) (int, error) {
+	now := time.Now()
+
+	records, err := inRelayTx(ctx, begin, func(tx relayTx) ([]eventsource.Record, error) {
+		return tx.claim(ctx, queries.Claim, maxAttempts, limit, now.UnixNano(), now.Add(lease).UnixNano())
+	})
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to claim pending records")
+	}
+
+	sort.Slice(records, func(i, j int) bool {
+		return records[i].SequenceID < records[j].SequenceID
+	})
+
+	var (
+		sent    int
+		sendErr error
+	)
+
+	for ; sent < len(records); sent++ {
+		if sendErr = send(ctx, records[sent]); sendErr != nil {
+			break
+		}
+	}
+
+	_, err = inRelayTx(ctx, begin, func(tx relayTx) ([]eventsource.Record, error) {
+		return nil, markRecords(ctx, tx, queries, records, sent, sendErr)
+	})
+	if err != nil {
+		return sent, err
+	}
+
+	if sendErr != nil {
+		return sent, errors.Wrapf(ErrSendFailed, "record %s: %s", records[sent].SequenceID, sendErr)
+	}
+
+	return sent, nil
+}
+
+// markRecords removes the sent records from the outbox, records the failed attempt of the record
+// which couldn't be sent, if any, and releases the following records
+func markRecords(ctx context.Context, tx relayTx, queries OutboxQueries, records []eventsource.Record, sent int, sendErr error) error {
+	for _, record := range records[:sent] {
+		if err := tx.exec(ctx, queries.Delivered, record.SequenceID); err != nil {
+			return errors.Wrap(err, "failed to delete delivered record")
+		}
+	}
+
+	if sendErr == nil {
+		return nil
+	}
+
+	if err := tx.exec(ctx, queries.Failed, records[sent].SequenceID, sendErr.Error()); err != nil {
+		return errors.Wrap(err, "failed to record failed attempt")
+	}
+
+	for _, record := range records[sent+1:] {
+		if err := tx.exec(ctx, queries.Release, record.SequenceID); err != nil {
+			return errors.Wrap(err, "failed to release record")
+		}
+	}
+
+	return nil
+}
+
+// inRelayTx calls fn within a transaction, which is committed unless fn fails
+func inRelayTx(ctx context.Context, begin func(ctx context.Context) (relayTx, error), fn func(tx relayTx) ([]eventsource.Record, error)) ([]eventsource.Record, error) {
+	tx, err := begin(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to start new transaction")
+	}
+
+	records, err := fn(tx)
+	if err != nil {
+		return nil, tx.rollback(err)
+	}
+
+	if err = tx.commit(ctx); err != nil {
+		return nil, errors.Wrap(err, "failed to commit transaction")
+	}
+
+	return records, nil
+}
+
+// RelayOutbox claims at most limit pending records from the outbox, sends them and marks them
+func (dwWrap *Generic) RelayOutbox(ctx context.Context, queries OutboxQueries, maxAttempts, limit int, lease time.Duration, send SendFunc) (int, error) {
+	return relayRecords(ctx, func(ctx context.Context) (relayTx, error) {
+		tx, err := dwWrap.DB.BeginTx(ctx, nil)
+		if err != nil {
+			return nil, err // nolint:wrapcheck
+		}
+
+		return &genericRelayTx{tx: tx}, nil
+	}, queries, maxAttempts, limit, lease, send)
+}
+
+type genericRelayTx struct {
+	tx *sql.Tx
+}
+
+func (t *genericRelayTx) claim(ctx context.Context, query string, args ...interface{}) ([]eventsource.Record, error) {
+	rows, err := t.tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err // nolint:wrapcheck
+	}
+	defer rows.Close()
+
+	var records []eventsource.Record
+
+	for rows.Next() {
+		record, err := scanGeneric(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		records = append(records, record)
+	}
+
+	return records, rows.Err() // nolint:wrapcheck
+}
+
+func (t *genericRelayTx) exec(ctx context.Context, query string, args ...interface{}) error {
+	_, err := t.tx.ExecContext(ctx, query, args...)
+
+	return err // nolint:wrapcheck
+}
+
+func (t *genericRelayTx) commit(context.Context) error {
+	return t.tx.Commit() // nolint:wrapcheck
+}
+
+func (t *genericRelayTx) rollback(err error) error {
+	return rollbackGeneric(t.tx, err)
+}
+
+// RelayOutbox claims at most limit pending records from the outbox, sends them and marks them
+func (pgx *PGX) RelayOutbox(ctx context.Context, queries OutboxQueries, maxAttempts, limit int, lease time.Duration, send SendFunc) (int, error) {
+	return relayRecords(ctx, func(ctx context.Context) (relayTx, error) {
+		tx, err := pgx.DB.Begin(ctx)
+		if err != nil {
+			return nil, err // nolint:wrapcheck
+		}
+
+		return &pgxRelayTx{tx: tx}, nil
+	}, queries, maxAttempts, limit, lease, send)
+}
+
+type pgxRelayTx struct {
+	tx pgx.Tx
+}
+
+func (t *pgxRelayTx) claim(ctx context.Context, query string, args ...interface{}) ([]eventsource.Record, error) {
+	rows, err := t.tx.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err // nolint:wrapcheck
+	}
+	defer rows.Close()
+
+	var records []eventsource.Record
+
+	for rows.Next() {
+		record, err := scanPgx(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		records = append(records, record)
+	}
+
+	return records, rows.Err() // nolint:wrapcheck
+}
+
+func (t *pgxRelayTx) exec(ctx context.Context, query string, args ...interface{}) error {
+	_, err := t.tx.Exec(ctx, query, args...)
+
+	return err // nolint:wrapcheck
+}
+
+func (t *pgxRelayTx) commit(ctx context.Context) error {
+	return t.tx.Commit(ctx) // nolint:wrapcheck
+}
+
+func (t *pgxRelayTx) rollback(err error) error {
+	return rollbackPgx(t.tx, err)
+}
@@ -2,6 +2,8 @@ package driver
 
 import (
 	"context"
+	"database/sql"
+	"iter"
 
 	"github.com/jackc/pgx/v4"
 	"github.com/pkg/errors"
@@ -21,62 +23,79 @@ type PGX struct {
 }
 
 func (pgx *PGX) Load(ctx context.Context, query string, args []interface{}) ([]eventsource.Record, error) {
-	records := []eventsource.Record{}
-
-	rows, err := pgx.DB.Query(ctx, query, args...)
-	if err != nil {
-		return records, errors.Wrap(err, "failed to load events using pgx")
-	}
+	return eventsource.CollectRecords(pgx.Iter(ctx, query, args))
+}
 
-	for rows.Next() {
-		var (
-			record      eventsource.Record
-			aggregateID uuid.UUID
-			userID      uuid.UUID
-		)
-
-		// Scan aggregateID and userID to intermediate uuid, so they are transferred using binary representation
-		if err = rows.Scan(
-			&aggregateID, &record.SequenceID, &record.Timestamp,
-			&userID, &record.Type, &record.Data,
-		); err != nil {
-			err = errors.Wrap(err, "failed to scan sql row")
-
-			return records, err
+// Iter executes the query and scans the rows lazily while iterating
+func (pgx *PGX) Iter(ctx context.Context, query string, args []interface{}) iter.Seq2[eventsource.Record, error] {
+	return func(yield func(eventsource.Record, error) bool) {
+		rows, err := pgx.DB.Query(ctx, query, args...)
+		if err != nil {
+			yield(eventsource.Record{}, errors.Wrap(err, "failed to load events using pgx"))
+			return
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			record, err := scanPgx(rows)
+			if err != nil {
+				yield(eventsource.Record{}, err)
+				return
+			}
+
+			if !yield(record, nil) {
+				return
+			}
 		}
 
-		record.AggregateID = aggregateID.String()
-		record.UserID = userID.String()
-
-		records = append(records, record)
+		if err = rows.Err(); err != nil {
+			yield(eventsource.Record{}, errors.Wrap(err, "errors returned from sql store"))
+		}
 	}
+}
 
-	if err = rows.Err(); err != nil {
-		err = errors.Wrap(err, "errors returned from sql store")
+func scanPgx(rows pgx.Rows) (record eventsource.Record, err error) {
+	var (
+		aggregateID uuid.UUID
+		userID      *uuid.UUID
+		version     sql.NullInt64
+		metadata    []byte
+	)
+
+	// Scan aggregateID and userID to intermediate uuid, so they are transferred using binary representation.
+	// The user ID is NULL for records stored without one, like encrypted events.
+	if err = rows.Scan(
+		&aggregateID, &record.SequenceID, &record.Timestamp,
+		&userID, &record.Type, &record.Data, &version, &metadata,
+	); err != nil {
+		return record, errors.Wrap(err, "failed to scan sql row")
+	}
 
-		return records, err
+	record.AggregateID = aggregateID.String()
+	if userID != nil {
+		record.UserID = userID.String()
 	}
+	record.Version = version.Int64
+	record.Metadata, err = unmarshalMetadata(metadata)
 
-	return records, err
+	return record, err
 }
 
-func (pgx *PGX) NewTransaction(ctx context.Context, query string, records ...eventsource.Record) (eventsource.StoreTransaction, error) {
+func (pgx *PGX) NewTransaction(ctx context.Context, queries Queries, records ...eventsource.Record) (eventsource.StoreTransaction, error) {
 	tx, err := pgx.DB.Begin(ctx)
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to start new transaction")
 	}
 
-	for _, record := range records {
-		_, err = tx.Exec(ctx, query, uuid.UUID(record.AggregateID), record.SequenceID, record.Timestamp, uuid.UUID(record.UserID), record.Type, record.Data)
-		if err != nil {
-			return nil, errors.Wrap(err, "failed to execute query")
-		}
+	records, err = writeRecords(ctx, &pgxWriter{tx: tx, queries: queries}, queries, records)
+	if err != nil {
+		return nil, rollbackPgx(tx, err)
 	}
 
 	if pgx.NotificationChannel != nil && len(records) > 0 {
 		_, err = tx.Exec(ctx, "SELECT pg_notify($1, $2)", pgx.NotificationChannel, records[len(records)-1].SequenceID)
 		if err != nil {
-			return nil, errors.Wrap(err, "failed to notify listeners of new events")
+			return nil, rollbackPgx(tx, errors.Wrap(err, "failed to notify listeners of new events"))
 		}
 	}
 
@@ -87,6 +106,43 @@ func (pgx *PGX) NewTransaction(ctx context.Context, query string, records ...eve
 	}, nil
 }
 
+type pgxWriter struct {
+	tx      pgx.Tx
+	queries Queries
+}
+
+func (w *pgxWriter) version(ctx context.Context, aggregateID string) (version int64, err error) {
+	err = w.tx.QueryRow(ctx, w.queries.Version, uuid.UUID(aggregateID)).Scan(&version)
+
+	return
+}
+
+func (w *pgxWriter) insert(ctx context.Context, query string, record eventsource.Record) error {
+	metadata, err := marshalMetadata(record.Metadata)
+	if err != nil {
+		return err
+	}
+
+	// records without a user ID, like encrypted events, get NULL as the empty string isn't a uuid
+	var userID *uuid.UUID
+	if record.UserID != "" {
+		id := uuid.UUID(record.UserID)
+		userID = &id
+	}
+
+	_, err = w.tx.Exec(ctx, query, uuid.UUID(record.AggregateID), record.SequenceID, record.Timestamp, userID, record.Type, record.Data, record.Version, metadata)
+
+	return err // nolint:wrapcheck
+}
+
+func rollbackPgx(tx pgx.Tx, err error) error {
+	if rollbackErr := tx.Rollback(context.Background()); rollbackErr != nil {
+		return errors.Wrapf(err, "rollback error: %+v", rollbackErr)
+	}
+
+	return err
+}
+
 type pgxTransaction struct {
 	sqlTx   pgx.Tx
 	ctx     context.Context
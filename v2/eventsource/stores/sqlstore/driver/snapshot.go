@@ -0,0 +1,79 @@
+package driver
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/pkg/errors"
+
+	"github.com/SKF/go-eventsource/v2/eventsource"
+	"github.com/SKF/go-utility/v2/uuid"
+)
+
+// SaveSnapshot executes the query with the snapshot columns as arguments
+func (dwWrap *Generic) SaveSnapshot(ctx context.Context, query string, snapshot eventsource.Snapshot) error {
+	_, err := dwWrap.DB.ExecContext(ctx, query, snapshot.AggregateID, snapshot.SequenceID, snapshot.Version, snapshot.Data, snapshot.Timestamp)
+	if err != nil {
+		return errors.Wrap(err, "failed to save snapshot")
+	}
+
+	return nil
+}
+
+// LoadSnapshot executes the query with the aggregate ID as argument and scans the snapshot
+func (dwWrap *Generic) LoadSnapshot(ctx context.Context, query string, aggregateID string) (snapshot eventsource.Snapshot, err error) {
+	err = dwWrap.DB.QueryRowContext(ctx, query, aggregateID).Scan(
+		&snapshot.AggregateID, &snapshot.SequenceID, &snapshot.Version, &snapshot.Data, &snapshot.Timestamp,
+	)
+	if errors.Is(err, sql.ErrNoRows) {
+		return snapshot, eventsource.ErrNoSnapshot
+	}
+
+	if err != nil {
+		return snapshot, errors.Wrap(err, "failed to load snapshot")
+	}
+
+	return snapshot, nil
+}
+
+// SaveSnapshot executes the query with the snapshot columns as arguments
+func (pgx *PGX) SaveSnapshot(ctx context.Context, query string, snapshot eventsource.Snapshot) error {
+	rows, err := pgx.DB.Query(ctx, query, uuid.UUID(snapshot.AggregateID), snapshot.SequenceID, snapshot.Version, snapshot.Data, snapshot.Timestamp)
+	if err != nil {
+		return errors.Wrap(err, "failed to save snapshot using pgx")
+	}
+
+	rows.Close()
+
+	if err = rows.Err(); err != nil {
+		return errors.Wrap(err, "failed to save snapshot using pgx")
+	}
+
+	return nil
+}
+
+// LoadSnapshot executes the query with the aggregate ID as argument and scans the snapshot
+func (pgx *PGX) LoadSnapshot(ctx context.Context, query string, aggregateID string) (snapshot eventsource.Snapshot, err error) {
+	rows, err := pgx.DB.Query(ctx, query, uuid.UUID(aggregateID))
+	if err != nil {
+		return snapshot, errors.Wrap(err, "failed to load snapshot using pgx")
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		if err = rows.Err(); err != nil {
+			return snapshot, errors.Wrap(err, "failed to load snapshot using pgx")
+		}
+
+		return snapshot, eventsource.ErrNoSnapshot
+	}
+
+	var id uuid.UUID
+	if err = rows.Scan(&id, &snapshot.SequenceID, &snapshot.Version, &snapshot.Data, &snapshot.Timestamp); err != nil {
+		return snapshot, errors.Wrap(err, "failed to scan snapshot")
+	}
+
+	snapshot.AggregateID = id.String()
+
+	return snapshot, nil
+}
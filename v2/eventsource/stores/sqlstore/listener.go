@@ -0,0 +1,247 @@
+package sqlstore
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/pkg/errors"
+
+	"github.com/SKF/go-eventsource/v2/eventsource"
+	"github.com/SKF/go-eventsource/v2/eventsource/stores/sqlstore/driver"
+	"github.com/SKF/go-utility/v2/log"
+)
+
+const (
+	defaultReconnectDelay = time.Second
+	// maxNotifiedRecords is the number of sequence IDs kept to recognize late notifications
+	maxNotifiedRecords = 10000
+)
+
+// ListenerPool is a pool which can hand out a dedicated connection for LISTEN, like pgxpool.Pool
+type ListenerPool interface {
+	driver.PgxPool
+	Acquire(ctx context.Context) (*pgxpool.Conn, error)
+}
+
+// Notification is sent by the Listener when new records have been saved
+type Notification struct {
+	// SequenceID of the latest saved record
+	SequenceID string
+	// Records saved since the previous notification, if the Listener was created WithRecords
+	Records []eventsource.Record
+	// CatchUp is set when the notification is the result of a catch-up load, after
+	// notifications may have been missed while (re)connecting
+	CatchUp bool
+	// Late is set when the records of a transaction were committed after a record with a
+	// greater sequence ID had been notified. SequenceID is then the latest record of the
+	// transaction, and the records aren't loaded. Unless the Listener was created WithRecords,
+	// the records may already have been included by an earlier notification.
+	Late bool
+}
+
+// Listener receives the notifications sent by stores created WithPostgresNotify
+type Listener struct {
+	db             ListenerPool
+	store          eventsource.Store
+	channel        string
+	records        bool
+	reconnectDelay time.Duration
+	lastSequenceID string
+	started        bool
+	// notified are the sequence IDs of the recently loaded records, WithRecords
+	notified map[string]struct{}
+}
+
+// ListenerOption is used for configuring a Listener
+type ListenerOption func(l *Listener)
+
+// WithRecords makes the Listener load the records saved since the previous notification
+func WithRecords() ListenerOption {
+	return func(l *Listener) {
+		l.records = true
+	}
+}
+
+// FromSequenceID makes the Listener start with a catch-up of the records saved after the
+// sequence ID, instead of only notifying about records saved after it started listening
+func FromSequenceID(sequenceID string) ListenerOption {
+	return func(l *Listener) {
+		l.lastSequenceID = sequenceID
+		l.started = true
+	}
+}
+
+// WithReconnectDelay sets the delay before reconnecting after a failure, one second by default
+func WithReconnectDelay(delay time.Duration) ListenerOption {
+	return func(l *Listener) {
+		l.reconnectDelay = delay
+	}
+}
+
+// NewListener creates a listener for the notifications of the store using the table.
+func NewListener(db ListenerPool, tableName string, opts ...ListenerOption) *Listener {
+	l := &Listener{
+		db:             db,
+		store:          NewPgx(db, tableName),
+		channel:        tableName,
+		reconnectDelay: defaultReconnectDelay,
+		notified:       map[string]struct{}{},
+	}
+
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	return l
+}
+
+// Listen acquires a dedicated connection from the pool, listening on the channel of the
+// table until the context is done, when the returned channel is closed. The connection is
+// reestablished after failures, followed by a catch-up load of the records saved meanwhile,
+// so no notifications are missed. A Listener should only be listening once at a time.
+func (l *Listener) Listen(ctx context.Context) <-chan Notification {
+	notifications := make(chan Notification)
+
+	go func() {
+		defer close(notifications)
+
+		for ctx.Err() == nil {
+			if err := l.listen(ctx, notifications); err != nil && ctx.Err() == nil {
+				log.Warnf("Listening on %s failed, reconnecting: %+v", l.channel, err)
+
+				select {
+				case <-ctx.Done():
+				case <-time.After(l.reconnectDelay):
+				}
+			}
+		}
+	}()
+
+	return notifications
+}
+
+func (l *Listener) listen(ctx context.Context, notifications chan<- Notification) error {
+	conn, err := l.db.Acquire(ctx)
+	if err != nil {
+		return errors.Wrap(err, "failed to acquire connection")
+	}
+
+	// close the connection, so it isn't returned to the pool while listening
+	defer func() {
+		_ = conn.Conn().Close(context.Background())
+		conn.Release()
+	}()
+
+	if _, err = conn.Exec(ctx, "LISTEN "+pgx.Identifier{l.channel}.Sanitize()); err != nil {
+		return errors.Wrap(err, "failed to listen")
+	}
+
+	if err = l.catchUp(ctx, notifications); err != nil {
+		return err
+	}
+
+	for {
+		notification, err := conn.Conn().WaitForNotification(ctx)
+		if err != nil {
+			return errors.Wrap(err, "failed to wait for notification")
+		}
+
+		if notification.Payload <= l.lastSequenceID {
+			l.sendLate(ctx, notifications, notification.Payload)
+			continue
+		}
+
+		if err = l.loadAndSend(ctx, notifications, false); err != nil {
+			return err
+		}
+	}
+}
+
+// sendLate notifies about a transaction committed after a record with a greater sequence ID
+// was notified, as its records are skipped when loading the records after the last
+// notification. The notification of a transaction committed before the records were loaded
+// may also arrive afterwards, which is recognized WithRecords.
+func (l *Listener) sendLate(ctx context.Context, notifications chan<- Notification, sequenceID string) {
+	if _, ok := l.notified[sequenceID]; ok || sequenceID == l.lastSequenceID {
+		return
+	}
+
+	log.Warnf("Records up to %s on %s were committed after %s was notified", sequenceID, l.channel, l.lastSequenceID)
+
+	select {
+	case notifications <- Notification{SequenceID: sequenceID, Late: true}:
+	case <-ctx.Done():
+	}
+}
+
+// catchUp sends a notification about the records saved after the last notification, as
+// notifications are missed while not listening. The first time, the listener only starts
+// from the latest saved record, unless it's created FromSequenceID.
+func (l *Listener) catchUp(ctx context.Context, notifications chan<- Notification) error {
+	if !l.started {
+		records, err := l.store.Load(ctx, WithDescending(), WithLimit(1))
+		if err != nil {
+			return errors.Wrap(err, "failed to load latest record")
+		}
+
+		if len(records) > 0 {
+			l.lastSequenceID = records[0].SequenceID
+		}
+
+		l.started = true
+	}
+
+	return l.loadAndSend(ctx, notifications, true)
+}
+
+// loadAndSend loads the records saved after the last notification, or only the latest of
+// them unless the listener loads records, and sends a notification unless there are none.
+// The records are loaded rather than trusting the payload of a notification, so records
+// whose notifications were missed are included.
+func (l *Listener) loadAndSend(ctx context.Context, notifications chan<- Notification, catchUp bool) error {
+	opts := []eventsource.QueryOption{BySequenceID(l.lastSequenceID)}
+	if !l.records {
+		opts = append(opts, WithDescending(), WithLimit(1))
+	}
+
+	records, err := l.store.Load(ctx, opts...)
+	if err != nil {
+		return errors.Wrap(err, "failed to load records")
+	}
+
+	if len(records) == 0 {
+		return nil
+	}
+
+	notification := Notification{
+		SequenceID: records[0].SequenceID,
+		CatchUp:    catchUp,
+	}
+
+	if l.records {
+		notification.SequenceID = records[len(records)-1].SequenceID
+		notification.Records = records
+
+		if len(l.notified)+len(records) > maxNotifiedRecords {
+			clear(l.notified)
+		}
+
+		for _, record := range records {
+			l.notified[record.SequenceID] = struct{}{}
+		}
+	}
+
+	l.send(ctx, notifications, notification)
+
+	return nil
+}
+
+func (l *Listener) send(ctx context.Context, notifications chan<- Notification, notification Notification) {
+	select {
+	case notifications <- notification:
+		l.lastSequenceID = notification.SequenceID
+	case <-ctx.Done():
+	}
+}
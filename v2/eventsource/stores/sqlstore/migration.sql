@@ -0,0 +1,20 @@
+-- Migration of tables created by earlier releases, see schema.sql -------------
+-- Stop the writers first, the versions are assigned following the latest version
+-- of each aggregate, which the backfill has to set for the existing events.
+
+-- Columns --------------------------------------------------------
+ALTER TABLE events ADD COLUMN version bigint;
+ALTER TABLE events ADD COLUMN metadata jsonb;
+COMMENT ON COLUMN events.version IS 'position of the event in the stream of its aggregate';
+COMMENT ON COLUMN events.metadata IS 'correlation ID, causation ID and headers, see eventsource.WithMetadata';
+
+-- Version backfill ----------------------------------------------
+UPDATE events SET version = numbered.version
+FROM (
+    SELECT sequence_id, row_number() OVER (PARTITION BY aggregate_id ORDER BY sequence_id) AS version
+    FROM events
+) AS numbered
+WHERE events.sequence_id = numbered.sequence_id AND events.version IS NULL;
+
+-- Indices -------------------------------------------------------
+CREATE UNIQUE INDEX events_aggregate_id_version_idx ON events(aggregate_id, version);
@@ -13,6 +13,7 @@ const (
 	columnUserID      column = "user_id"
 	columnType        column = "type"
 	columnData        column = "data"
+	columnVersion     column = "version"
 )
 
 type whereOperator string
@@ -34,15 +35,24 @@ type options struct {
 	descending bool
 }
 
-// WithLimit will limit the result.
+// WithLimit will limit the result. Like eventsource.WithLimit, it's also applied to other
+// options implementing LimitRecords(limit int), so the repository sees the limit.
 func WithLimit(limit int) eventsource.QueryOption {
 	return func(i interface{}) {
-		if o, ok := i.(*options); ok {
+		switch o := i.(type) {
+		case *options:
 			o.limit = &limit
+		case interface{ LimitRecords(limit int) }:
+			o.LimitRecords(limit)
 		}
 	}
 }
 
+// LimitRecords implements support for eventsource.WithLimit.
+func (o *options) LimitRecords(limit int) {
+	WithLimit(limit)(o)
+}
+
 // WithOffset will offset the result.
 func WithOffset(offset int) eventsource.QueryOption {
 	return func(i interface{}) {
@@ -93,6 +103,21 @@ func BySequenceID(value string) eventsource.QueryOption {
 	return greaterThan(columnSequenceID, value)
 }
 
+// FilterBySequenceID implements support for eventsource.BySequenceID.
+func (o *options) FilterBySequenceID(sequenceID string) {
+	BySequenceID(sequenceID)(o)
+}
+
+// ByVersion only returns records with a version greater than value
+func ByVersion(value int64) eventsource.QueryOption {
+	return greaterThan(columnVersion, value)
+}
+
+// FilterByVersion implements support for eventsource.ByVersion.
+func (o *options) FilterByVersion(version int64) {
+	ByVersion(version)(o)
+}
+
 func ByTimestamp(value int64) eventsource.QueryOption {
 	return greaterThan(columnCreatedAt, value)
 }
@@ -0,0 +1,154 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/SKF/go-eventsource/v2/eventsource"
+	"github.com/SKF/go-eventsource/v2/eventsource/stores/sqlstore/driver"
+	"github.com/SKF/go-utility/v2/log"
+)
+
+const (
+	defaultRelayBatchSize   = 100
+	defaultRelayInterval    = time.Second
+	defaultRelayMaxAttempts = 10
+	defaultRelayLease       = time.Minute
+)
+
+var (
+	claimOutboxSQL = `UPDATE %[1]s SET claimed_until = $4 WHERE sequence_id IN (
+			SELECT sequence_id FROM %[1]s
+			WHERE attempts < $1 AND (claimed_until IS NULL OR claimed_until < $3)
+			ORDER BY sequence_id ASC LIMIT $2 FOR UPDATE SKIP LOCKED)
+		RETURNING aggregate_id, sequence_id, created_at, user_id, type, data, version, metadata`
+	deliveredOutboxSQL = "DELETE FROM %s WHERE sequence_id = $1"
+	failedOutboxSQL    = "UPDATE %s SET attempts = attempts + 1, last_error = $2, claimed_until = NULL WHERE sequence_id = $1"
+	releaseOutboxSQL   = "UPDATE %s SET claimed_until = NULL WHERE sequence_id = $1"
+)
+
+// OutboxDB is implemented by the drivers to relay records from an outbox
+type OutboxDB interface {
+	RelayOutbox(ctx context.Context, queries driver.OutboxQueries, maxAttempts, limit int, lease time.Duration, send driver.SendFunc) (int, error)
+}
+
+// Relay publishes the records written to the outbox by a store created WithOutbox through
+// notification services, and deletes them once delivered. A record is retried until it has been
+// sent to all services, giving at-least-once delivery.
+type Relay struct {
+	db          OutboxDB
+	queries     driver.OutboxQueries
+	services    []eventsource.NotificationService
+	batchSize   int
+	interval    time.Duration
+	maxAttempts int
+	lease       time.Duration
+}
+
+// RelayOption is used for configuring a Relay
+type RelayOption func(r *Relay)
+
+// WithBatchSize sets the maximum number of records claimed at a time, 100 by default
+func WithBatchSize(batchSize int) RelayOption {
+	return func(r *Relay) {
+		r.batchSize = batchSize
+	}
+}
+
+// WithRelayInterval sets how long the relay waits after finding no pending records, one second by default
+func WithRelayInterval(interval time.Duration) RelayOption {
+	return func(r *Relay) {
+		r.interval = interval
+	}
+}
+
+// WithMaxAttempts sets how many times a record is attempted, 10 by default. Records failing all
+// attempts are left in the outbox, with the last error, but don't block the following records.
+func WithMaxAttempts(maxAttempts int) RelayOption {
+	return func(r *Relay) {
+		r.maxAttempts = maxAttempts
+	}
+}
+
+// WithLease sets how long the claimed records are skipped by other relays, one minute by
+// default. It should be longer than sending a batch takes, or the records may be sent twice.
+func WithLease(lease time.Duration) RelayOption {
+	return func(r *Relay) {
+		r.lease = lease
+	}
+}
+
+// NewRelay creates a relay for the outbox table.
+func NewRelay(db *sql.DB, outboxTable string, services []eventsource.NotificationService, opts ...RelayOption) *Relay {
+	return newRelay(&driver.Generic{DB: db}, outboxTable, services, opts)
+}
+
+// NewPgxRelay creates a relay for the outbox table.
+func NewPgxRelay(db driver.PgxPool, outboxTable string, services []eventsource.NotificationService, opts ...RelayOption) *Relay {
+	return newRelay(&driver.PGX{DB: db, NotificationChannel: nil}, outboxTable, services, opts)
+}
+
+func newRelay(db OutboxDB, outboxTable string, services []eventsource.NotificationService, opts []RelayOption) *Relay {
+	r := &Relay{
+		db: db,
+		queries: driver.OutboxQueries{
+			Claim:     fmt.Sprintf(claimOutboxSQL, outboxTable),
+			Delivered: fmt.Sprintf(deliveredOutboxSQL, outboxTable),
+			Failed:    fmt.Sprintf(failedOutboxSQL, outboxTable),
+			Release:   fmt.Sprintf(releaseOutboxSQL, outboxTable),
+		},
+		services:    services,
+		batchSize:   defaultRelayBatchSize,
+		interval:    defaultRelayInterval,
+		maxAttempts: defaultRelayMaxAttempts,
+		lease:       defaultRelayLease,
+	}
+
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
+}
+
+// Run relays pending records until the context is done. Failures are logged, and the
+// records are retried after the relay interval.
+func (r *Relay) Run(ctx context.Context) {
+	for ctx.Err() == nil {
+		delivered, err := r.RelayPending(ctx)
+		if err != nil && ctx.Err() == nil {
+			log.Warnf("Relaying records from outbox failed: %+v", err)
+		}
+
+		if err == nil && delivered == r.batchSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(r.interval):
+		}
+	}
+}
+
+// RelayPending claims one batch of pending records, sends them in sequence order and returns
+// the number of delivered records. The records aren't locked while they are sent, instead
+// they are claimed for the lease. It stops at the first record which can't be sent, records
+// the failed attempt and releases the remaining records.
+func (r *Relay) RelayPending(ctx context.Context) (int, error) {
+	return r.db.RelayOutbox(ctx, r.queries, r.maxAttempts, r.batchSize, r.lease, r.send) // nolint:wrapcheck
+}
+
+func (r *Relay) send(ctx context.Context, record eventsource.Record) error {
+	for _, service := range r.services {
+		if err := service.SendWithContext(ctx, record); err != nil {
+			return errors.Wrap(err, "notification service failed")
+		}
+	}
+
+	return nil
+}
@@ -5,11 +5,50 @@ CREATE TABLE events (
     user_id uuid,
     created_at bigint NOT NULL,
     type character varying(255),
-    data bytea
+    data bytea,
+    version bigint,
+    metadata jsonb
 );
 COMMENT ON COLUMN events.sequence_id IS 'github.com/oklog/ulid';
+COMMENT ON COLUMN events.version IS 'position of the event in the stream of its aggregate';
+COMMENT ON COLUMN events.metadata IS 'correlation ID, causation ID and headers, see eventsource.WithMetadata';
 
 -- Indices -------------------------------------------------------
 CREATE UNIQUE INDEX events_pkey ON events(sequence_id bpchar_ops);
 CREATE INDEX events_aggregate_id_idx ON events(aggregate_id uuid_ops);
 CREATE INDEX events_type_idx ON events(type text_ops);
+CREATE UNIQUE INDEX events_aggregate_id_version_idx ON events(aggregate_id, version);
+
+-- Snapshots -----------------------------------------------------
+CREATE TABLE snapshots (
+    aggregate_id uuid PRIMARY KEY,
+    sequence_id character(26) NOT NULL,
+    version bigint NOT NULL,
+    data bytea,
+    created_at bigint NOT NULL
+);
+COMMENT ON TABLE snapshots IS 'latest snapshot of each aggregate, see eventsource.WithSnapshots';
+
+-- Projection checkpoints ----------------------------------------
+CREATE TABLE checkpoints (
+    name character varying(255) PRIMARY KEY,
+    sequence_id character varying(26) NOT NULL
+);
+COMMENT ON TABLE checkpoints IS 'sequence ID of the last event handled by each projection, see projection.Runner';
+
+-- Transactional outbox ------------------------------------------
+CREATE TABLE outbox (
+    sequence_id character(26) PRIMARY KEY,
+    aggregate_id uuid,
+    user_id uuid,
+    created_at bigint NOT NULL,
+    type character varying(255),
+    data bytea,
+    version bigint,
+    metadata jsonb,
+    attempts integer NOT NULL DEFAULT 0,
+    last_error text,
+    claimed_until bigint
+);
+COMMENT ON TABLE outbox IS 'records to be published by sqlstore.Relay, see sqlstore.WithOutbox. Delivered records are deleted, so only pending and failed records remain';
+COMMENT ON COLUMN outbox.claimed_until IS 'time until which the record is claimed by a relay, see sqlstore.WithLease';
@@ -0,0 +1,53 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/SKF/go-eventsource/v2/eventsource"
+	"github.com/SKF/go-eventsource/v2/eventsource/stores/sqlstore/driver"
+)
+
+// SnapshotDB is implemented by the drivers to save and load snapshots
+type SnapshotDB interface {
+	SaveSnapshot(ctx context.Context, query string, snapshot eventsource.Snapshot) error
+	LoadSnapshot(ctx context.Context, query string, aggregateID string) (eventsource.Snapshot, error)
+}
+
+type snapshotStore struct {
+	db        SnapshotDB
+	tableName string
+}
+
+var (
+	saveSnapshotSQL = `INSERT INTO %[1]s (aggregate_id, sequence_id, version, data, created_at) VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (aggregate_id) DO UPDATE SET
+			sequence_id = EXCLUDED.sequence_id, version = EXCLUDED.version, data = EXCLUDED.data, created_at = EXCLUDED.created_at
+		WHERE %[1]s.version < EXCLUDED.version`
+	loadSnapshotSQL = "SELECT aggregate_id, sequence_id, version, data, created_at FROM %s WHERE aggregate_id = $1"
+)
+
+// NewSnapshotStore creates a new snapshot store, keeping the latest snapshot of each aggregate in the table.
+func NewSnapshotStore(db *sql.DB, tableName string) eventsource.SnapshotStore {
+	return &snapshotStore{
+		db:        &driver.Generic{DB: db},
+		tableName: tableName,
+	}
+}
+
+// NewPgxSnapshotStore creates a new snapshot store, keeping the latest snapshot of each aggregate in the table.
+func NewPgxSnapshotStore(db driver.PgxPool, tableName string) eventsource.SnapshotStore {
+	return &snapshotStore{
+		db:        &driver.PGX{DB: db, NotificationChannel: nil},
+		tableName: tableName,
+	}
+}
+
+func (s *snapshotStore) SaveSnapshot(ctx context.Context, snapshot eventsource.Snapshot) error {
+	return s.db.SaveSnapshot(ctx, fmt.Sprintf(saveSnapshotSQL, s.tableName), snapshot) // nolint:wrapcheck
+}
+
+func (s *snapshotStore) LoadSnapshot(ctx context.Context, aggregateID string) (eventsource.Snapshot, error) {
+	return s.db.LoadSnapshot(ctx, fmt.Sprintf(loadSnapshotSQL, s.tableName), aggregateID) // nolint:wrapcheck
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"iter"
 	"strings"
 
 	"github.com/pkg/errors"
@@ -14,39 +15,61 @@ import (
 
 type EventDB interface {
 	Load(ctx context.Context, query string, args []interface{}) ([]eventsource.Record, error)
-	NewTransaction(ctx context.Context, query string, records ...eventsource.Record) (eventsource.StoreTransaction, error)
+	Iter(ctx context.Context, query string, args []interface{}) iter.Seq2[eventsource.Record, error]
+	NewTransaction(ctx context.Context, queries driver.Queries, records ...eventsource.Record) (eventsource.StoreTransaction, error)
 }
 
 type PGXStore interface {
-	eventsource.Store
+	eventsource.IterStore
+	eventsource.NotifyingStore
 	WithPostgresNotify() PGXStore
 }
 
 type store struct {
-	db        EventDB
-	tableName string
+	db          EventDB
+	tableName   string
+	outboxTable string
+}
+
+// StoreOption is used for configuring optional features of the store
+type StoreOption func(s *store)
+
+// WithOutbox makes the store also write the records to the outbox table, within the
+// same transaction, so they can be published by a Relay.
+func WithOutbox(tableName string) StoreOption {
+	return func(s *store) {
+		s.outboxTable = tableName
+	}
 }
 
 var (
-	columns = []column{columnAggregateID, columnSequenceID, columnCreatedAt, columnUserID, columnType, columnData}
-	saveSQL = "INSERT INTO %s (aggregate_id, sequence_id, created_at, user_id, type, data) VALUES ($1, $2, $3, $4, $5, $6)"
-	loadSQL = "SELECT aggregate_id, sequence_id, created_at, user_id, type, data FROM %s"
+	columns    = []column{columnAggregateID, columnSequenceID, columnCreatedAt, columnUserID, columnType, columnData, columnVersion}
+	saveSQL    = "INSERT INTO %s (aggregate_id, sequence_id, created_at, user_id, type, data, version, metadata) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)"
+	loadSQL    = "SELECT aggregate_id, sequence_id, created_at, user_id, type, data, version, metadata FROM %s"
+	versionSQL = "SELECT coalesce(max(version), 0) FROM %s WHERE aggregate_id = $1"
 )
 
 // New creates a new event source store.
-func New(db *sql.DB, tableName string) eventsource.Store {
-	return &store{
-		db:        &driver.Generic{DB: db},
-		tableName: tableName,
-	}
+func New(db *sql.DB, tableName string, opts ...StoreOption) eventsource.IterStore {
+	return newStore(&driver.Generic{DB: db}, tableName, opts)
 }
 
 // NewPgx creates a new event source store.
-func NewPgx(db driver.PgxPool, tableName string) PGXStore {
-	return &store{
-		db:        &driver.PGX{DB: db, NotificationChannel: nil},
+func NewPgx(db driver.PgxPool, tableName string, opts ...StoreOption) PGXStore {
+	return newStore(&driver.PGX{DB: db, NotificationChannel: nil}, tableName, opts)
+}
+
+func newStore(db EventDB, tableName string, opts []StoreOption) *store {
+	s := &store{
+		db:        db,
 		tableName: tableName,
 	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
 }
 
 func columnExist(key column) bool {
@@ -59,6 +82,12 @@ func columnExist(key column) bool {
 	return false
 }
 
+// Relays returns true if the store was created WithOutbox, as the records are then published
+// by a Relay, see eventsource.RelayingStore
+func (s *store) Relays() bool {
+	return s.outboxTable != ""
+}
+
 func (s *store) WithPostgresNotify() PGXStore {
 	if db, ok := s.db.(*driver.PGX); ok {
 		db.NotificationChannel = &s.tableName
@@ -67,8 +96,47 @@ func (s *store) WithPostgresNotify() PGXStore {
 	return s
 }
 
+// Notify listens for the notifications sent by stores created WithPostgresNotify, using a
+// Listener with a dedicated connection from the pool. A nil channel is returned for other stores.
+func (s *store) Notify(ctx context.Context) (<-chan string, error) {
+	db, ok := s.db.(*driver.PGX)
+	if !ok || db.NotificationChannel == nil {
+		return nil, nil
+	}
+
+	pool, ok := db.DB.(ListenerPool)
+	if !ok {
+		return nil, nil
+	}
+
+	sequenceIDs := make(chan string, 1)
+
+	go func() {
+		defer close(sequenceIDs)
+
+		for notification := range NewListener(pool, s.tableName).Listen(ctx) {
+			// a pending notification is enough to wake up the receiver
+			select {
+			case sequenceIDs <- notification.SequenceID:
+			default:
+			}
+		}
+	}()
+
+	return sequenceIDs, nil
+}
+
 func (s *store) NewTransaction(ctx context.Context, records ...eventsource.Record) (eventsource.StoreTransaction, error) {
-	return s.db.NewTransaction(ctx, fmt.Sprintf(saveSQL, s.tableName), records...) // nolint:wrapcheck
+	queries := driver.Queries{
+		Insert:  fmt.Sprintf(saveSQL, s.tableName),
+		Version: fmt.Sprintf(versionSQL, s.tableName),
+	}
+
+	if s.outboxTable != "" {
+		queries.Outbox = fmt.Sprintf(saveSQL, s.outboxTable)
+	}
+
+	return s.db.NewTransaction(ctx, queries, records...) // nolint:wrapcheck
 }
 
 func (s *store) buildQuery(queryOpts []eventsource.QueryOption, query string) (string, []any, error) {
@@ -123,6 +191,22 @@ func (s *store) Load(ctx context.Context, opts ...eventsource.QueryOption) (reco
 	return s.fetchRecords(ctx, opts, loadSQL)
 }
 
+// Iter will iterate over the records matching the query options, scanning rows lazily.
+func (s *store) Iter(ctx context.Context, opts ...eventsource.QueryOption) iter.Seq2[eventsource.Record, error] {
+	fullQuery, args, err := s.buildQuery(opts, loadSQL)
+	if err != nil {
+		return func(yield func(eventsource.Record, error) bool) {
+			yield(eventsource.Record{}, err)
+		}
+	}
+
+	return s.db.Iter(ctx, fullQuery, args)
+}
+
+func (s *store) IterByAggregate(ctx context.Context, aggregateID string, opts ...eventsource.QueryOption) iter.Seq2[eventsource.Record, error] {
+	return s.Iter(ctx, append(opts, equals(columnAggregateID, aggregateID))...)
+}
+
 func (s *store) LoadByAggregate(ctx context.Context, aggregateID string, opts ...eventsource.QueryOption) (records []eventsource.Record, err error) {
 	return s.Load(ctx, append(opts, equals(columnAggregateID, aggregateID))...)
 }
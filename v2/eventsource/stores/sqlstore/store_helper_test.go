@@ -120,7 +120,52 @@ func createTableQuery() (tableName, query string) {
 			user_id uuid,
 			created_at bigint NOT NULL,
 			type character varying(255),
-			data bytea
+			data bytea,
+			version bigint,
+			metadata jsonb,
+			UNIQUE (aggregate_id, version)
+		)`, tableName)
+}
+
+func createSnapshotTableQuery() (tableName, query string) {
+	tableName = randomTableName()
+
+	return tableName, fmt.Sprintf(`
+		CREATE TABLE %s (
+			aggregate_id uuid PRIMARY KEY,
+			sequence_id character(26) NOT NULL,
+			version bigint NOT NULL,
+			data bytea,
+			created_at bigint NOT NULL
+		)`, tableName)
+}
+
+func createCheckpointTableQuery() (tableName, query string) {
+	tableName = randomTableName()
+
+	return tableName, fmt.Sprintf(`
+		CREATE TABLE %s (
+			name character varying(255) PRIMARY KEY,
+			sequence_id character varying(26) NOT NULL
+		)`, tableName)
+}
+
+func createOutboxTableQuery() (tableName, query string) {
+	tableName = randomTableName()
+
+	return tableName, fmt.Sprintf(`
+		CREATE TABLE %s (
+			sequence_id character(26) PRIMARY KEY,
+			aggregate_id uuid,
+			user_id uuid,
+			created_at bigint NOT NULL,
+			type character varying(255),
+			data bytea,
+			version bigint,
+			metadata jsonb,
+			attempts integer NOT NULL DEFAULT 0,
+			last_error text,
+			claimed_until bigint
 		)`, tableName)
 }
 
@@ -172,6 +217,7 @@ func createTestEvents(store eventsource.Store, numberOfEvents int, eventTypeList
 		}
 
 		event.SequenceID = records[0].SequenceID
+		event.Version = 1
 
 		if !reflect.DeepEqual(event, records[0]) {
 			return result, fmt.Errorf("Expected identical records, saved: %v  loaded: %v", event, records[0]) // nolint:goerr113
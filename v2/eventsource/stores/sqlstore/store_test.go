@@ -2,20 +2,26 @@ package sqlstore_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math/rand"
+	"sync"
 	"testing"
 	"time"
 
-	"github.com/jackc/pgconn"
 	_ "github.com/lib/pq"
 	"github.com/oklog/ulid"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
 
 	"github.com/SKF/go-eventsource/v2/eventsource"
+	"github.com/SKF/go-eventsource/v2/eventsource/notification/recorder"
+	"github.com/SKF/go-eventsource/v2/eventsource/projection"
+	"github.com/SKF/go-eventsource/v2/eventsource/serializers/encryption"
 	"github.com/SKF/go-eventsource/v2/eventsource/serializers/json"
 	"github.com/SKF/go-eventsource/v2/eventsource/stores/sqlstore"
+	"github.com/SKF/go-eventsource/v2/eventsource/stores/sqlstore/driver"
 	"github.com/SKF/go-utility/v2/uuid"
 )
 
@@ -66,6 +72,11 @@ var allTests = map[string]testFunc{
 	"Populate object by loading events": testLoadAggregate,
 	"Load events given options":         testLoadEventOptions,
 	"Test behaviour of ULIDs":           testULID,
+	"Save with expected version":        testSaveWithExpectedVersion,
+	"Assign versions when saving":       testAssignVersions,
+	"Iterate over records":              testIter,
+	"Save and load metadata":            testMetadata,
+	"Save and load encrypted events":    testEncrypted,
 }
 
 func wrapTest(tf testFunc, store eventsource.Store) func(*testing.T) {
@@ -95,43 +106,90 @@ func TestPgxDriver(t *testing.T) { // nolint:paralleltest
 
 func TestPgxListenNotify(t *testing.T) { // nolint:paralleltest
 	db, tableName := setupDBPgx(t)
+	defer cleanupDBPgx(t, db, tableName)
+
 	store := sqlstore.NewPgx(db, tableName).WithPostgresNotify()
-	c := make(chan *pgconn.Notification)
-	timeout, listening := make(chan bool), make(chan bool)
 
-	go func() {
-		conn, _ := db.Acquire(ctx) // nolint:errcheck
-		defer conn.Release()
+	earlier, err := createTestEvents(store, 2, nil, nil)
+	require.NoError(t, err)
 
-		_, err := conn.Exec(ctx, fmt.Sprintf("LISTEN %s", tableName))
-		require.NoError(t, err)
+	listenCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
 
-		listening <- true
+	// start from the latest record, so the new record is notified even if the listener connects after it's saved
+	notifications := sqlstore.NewListener(db, tableName, sqlstore.FromSequenceID(earlier[1].SequenceID)).Listen(listenCtx)
+	recordNotifications := sqlstore.NewListener(db, tableName, sqlstore.WithRecords(), sqlstore.FromSequenceID(earlier[0].SequenceID)).Listen(listenCtx)
 
-		timeoutCtx, cancel := context.WithTimeout(ctx, 1*time.Second)
-		defer cancel()
+	notification := receiveNotification(t, recordNotifications)
+	assert.True(t, notification.CatchUp)
+	assert.Equal(t, earlier[1:], notification.Records)
 
-		n, err := conn.Conn().WaitForNotification(timeoutCtx)
-		if err != nil {
-			timeout <- true
-		} else {
-			c <- n
-		}
-	}()
+	events, err := createTestEvents(store, 1, []string{"EventTypeA"}, [][]byte{[]byte("TestData")})
+	require.NoError(t, err)
 
-	<-listening
+	notification = receiveNotification(t, notifications)
+	assert.Equal(t, events[0].SequenceID, notification.SequenceID)
+	assert.Empty(t, notification.Records)
 
-	events, err := createTestEvents(store, 1, []string{"EventTypeA"}, [][]byte{[]byte("TestData")})
+	notification = receiveNotification(t, recordNotifications)
+	assert.False(t, notification.CatchUp)
+	assert.Equal(t, events, notification.Records)
+
+	// as if the transaction of the first record was committed after the others were notified
+	_, err = db.Exec(ctx, "SELECT pg_notify($1, $2)", tableName, earlier[0].SequenceID)
 	require.NoError(t, err)
 
-	select {
-	case event := <-c:
-		require.Equal(t, events[0].SequenceID, event.Payload)
-	case <-timeout:
-		t.Error("Timed out waiting for NOTIFY event")
-	}
+	notification = receiveNotification(t, notifications)
+	assert.True(t, notification.Late)
+	assert.Equal(t, earlier[0].SequenceID, notification.SequenceID)
+
+	notification = receiveNotification(t, recordNotifications)
+	assert.True(t, notification.Late)
+	assert.Empty(t, notification.Records)
+}
+
+func receiveNotification(t *testing.T, notifications <-chan sqlstore.Notification) sqlstore.Notification {
+	t.Helper()
+
+	notification, ok := <-notifications
+	require.True(t, ok, "Timed out waiting for notification")
+
+	return notification
+}
+
+func TestPgxSubscribe(t *testing.T) { // nolint:paralleltest
+	db, tableName := setupDBPgx(t)
+	defer cleanupDBPgx(t, db, tableName)
 
-	cleanupDBPgx(t, db, tableName)
+	store := sqlstore.NewPgx(db, tableName).WithPostgresNotify()
+	repo := eventsource.NewRepository(store, json.NewSerializer(TestEventA{})) // nolint:exhaustivestruct
+	aggregateID, userID := uuid.New().String(), uuid.New().String()
+
+	subCtx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	received := make(chan string)
+
+	go func() {
+		err := repo.Subscribe(subCtx, "", func(_ context.Context, event eventsource.Event) error {
+			received <- event.(TestEventA).TestString // nolint:forcetypeassert
+
+			return nil
+		}, eventsource.WithPollInterval(time.Hour, time.Hour))
+		assert.NoError(t, err)
+	}()
+
+	for _, s := range []string{"a", "b"} {
+		err := repo.Save(ctx, TestEventA{BaseEvent: &eventsource.BaseEvent{AggregateID: aggregateID, UserID: userID}, TestString: s}) // nolint:exhaustivestruct
+		require.NoError(t, err)
+
+		select {
+		case testString := <-received:
+			assert.Equal(t, s, testString)
+		case <-subCtx.Done():
+			t.Fatal("Timed out waiting for subscribed event")
+		}
+	}
 }
 
 func testLoadBySequenceID(t *testing.T, store eventsource.Store) { // nolint:thelper
@@ -270,3 +328,289 @@ func testLoadEventOptions(t *testing.T, store eventsource.Store) { // nolint:the
 	assert.Equal(t, testData[offset].Position, events[0].(TestEventPosition).Position)
 	assert.Equal(t, testData[offset+4].Position, events[4].(TestEventPosition).Position)
 }
+
+func testSaveWithExpectedVersion(t *testing.T, store eventsource.Store) { // nolint:thelper
+	aggregateID := uuid.New().String()
+	userID := uuid.New().String()
+
+	repo := eventsource.NewRepository(store, json.NewSerializer(TestEventA{})) // nolint:exhaustivestruct
+	newEvent := func(s string) eventsource.Event {
+		return TestEventA{BaseEvent: &eventsource.BaseEvent{AggregateID: aggregateID, UserID: userID}, TestString: s} // nolint:exhaustivestruct
+	}
+
+	err := repo.SaveWithExpectedVersion(ctx, aggregateID, 0, newEvent("a"), newEvent("b"))
+	require.NoError(t, err)
+
+	err = repo.SaveWithExpectedVersion(ctx, aggregateID, 1, newEvent("c"))
+	require.ErrorIs(t, err, eventsource.ErrConcurrencyConflict)
+
+	err = repo.SaveWithExpectedVersion(ctx, aggregateID, 2, newEvent("c"))
+	require.NoError(t, err)
+
+	records, err := store.LoadByAggregate(ctx, aggregateID)
+	require.NoError(t, err)
+	require.Len(t, records, 3)
+
+	for i, record := range records {
+		assert.Equal(t, int64(i+1), record.Version)
+	}
+}
+
+func testAssignVersions(t *testing.T, store eventsource.Store) { // nolint:thelper
+	const numberOfEvents = 10
+
+	aggregateID := uuid.New().String()
+	repo := eventsource.NewRepository(store, json.NewSerializer(TestEventB{})) // nolint:exhaustivestruct
+
+	wg := sync.WaitGroup{}
+	for i := range numberOfEvents {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			event := TestEventB{BaseEvent: &eventsource.BaseEvent{AggregateID: aggregateID, UserID: uuid.New().String()}, TestInt: i} // nolint:exhaustivestruct
+			assert.NoError(t, repo.Save(ctx, event))
+			assert.NotZero(t, event.Version)
+		}()
+	}
+	wg.Wait()
+
+	records, err := store.LoadByAggregate(ctx, aggregateID)
+	require.NoError(t, err)
+	require.Len(t, records, numberOfEvents)
+
+	versions := map[int64]bool{}
+	for _, record := range records {
+		versions[record.Version] = true
+	}
+
+	for version := int64(1); version <= numberOfEvents; version++ {
+		assert.True(t, versions[version], "missing version %d", version)
+	}
+}
+
+func testIter(t *testing.T, store eventsource.Store) { // nolint:thelper
+	testData, err := createTestEvents(store, 5, nil, nil)
+	require.NoError(t, err)
+
+	iterStore, ok := store.(eventsource.IterStore)
+	require.True(t, ok)
+
+	var records []eventsource.Record
+	for record, err := range iterStore.Iter(ctx, sqlstore.BySequenceID(testData[0].SequenceID)) {
+		require.NoError(t, err)
+
+		if records = append(records, record); len(records) == 2 {
+			break
+		}
+	}
+
+	assert.Equal(t, testData[1:3], records)
+
+	records, err = eventsource.CollectRecords(iterStore.IterByAggregate(ctx, testData[4].AggregateID))
+	require.NoError(t, err)
+	assert.Equal(t, testData[4:], records)
+
+	_, err = eventsource.CollectRecords(iterStore.Iter(ctx, sqlstore.WithLimit(-1)))
+	require.Error(t, err, "Negative limits are rejected by postgres")
+}
+
+func testMetadata(t *testing.T, store eventsource.Store) { // nolint:thelper
+	aggregateID := uuid.New().String()
+	repo := eventsource.NewRepository(store, json.NewSerializer(TestEventB{})) // nolint:exhaustivestruct
+
+	metadataCtx := eventsource.WithMetadata(eventsource.WithCorrelationID(ctx, "correlation"), eventsource.Metadata{"tenant": "a"})
+	require.NoError(t, repo.Save(metadataCtx, TestEventB{BaseEvent: &eventsource.BaseEvent{AggregateID: aggregateID, UserID: uuid.New().String()}})) // nolint:exhaustivestruct
+	require.NoError(t, repo.Save(ctx, TestEventB{BaseEvent: &eventsource.BaseEvent{AggregateID: aggregateID, UserID: uuid.New().String()}}))         // nolint:exhaustivestruct
+
+	records, err := store.LoadByAggregate(ctx, aggregateID)
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+
+	assert.Equal(t, eventsource.Metadata{eventsource.CorrelationIDKey: "correlation", "tenant": "a"}, records[0].Metadata)
+	assert.Nil(t, records[1].Metadata)
+}
+
+func testEncrypted(t *testing.T, store eventsource.Store) { // nolint:thelper
+	aggregateID, userID := uuid.New().String(), uuid.New().String()
+	repo := eventsource.NewRepository(store, encryption.NewSerializer(json.NewSerializer(TestEventB{}), encryption.NewMemoryKeyStore())) // nolint:exhaustivestruct
+
+	require.NoError(t, repo.Save(ctx, TestEventB{BaseEvent: &eventsource.BaseEvent{AggregateID: aggregateID, UserID: userID}, TestInt: 1})) // nolint:exhaustivestruct
+
+	records, err := store.LoadByAggregate(ctx, aggregateID)
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Empty(t, records[0].UserID, "The user ID is only stored encrypted")
+
+	events, err := repo.UnmarshalRecords(records)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, userID, events[0].GetUserID())
+	assert.Equal(t, 1, events[0].(TestEventB).TestInt) // nolint:forcetypeassert
+}
+
+func TestGenericSnapshotStore(t *testing.T) { // nolint:paralleltest
+	db, eventsTable := setupDB(t)
+	defer cleanupDBGeneric(t, db, eventsTable)
+
+	tableName, query := createSnapshotTableQuery()
+	_, err := db.Exec(query)
+	require.NoError(t, err, "Could not create table")
+
+	testSnapshotStore(t, sqlstore.NewSnapshotStore(db, tableName))
+
+	_, err = db.Exec(fmt.Sprintf("DROP TABLE %s", tableName))
+	require.NoError(t, err, "Could not perform DB cleanup")
+}
+
+func TestPgxSnapshotStore(t *testing.T) { // nolint:paralleltest
+	db, eventsTable := setupDBPgx(t)
+	defer cleanupDBPgx(t, db, eventsTable)
+
+	tableName, query := createSnapshotTableQuery()
+	_, err := db.Exec(ctx, query)
+	require.NoError(t, err, "Could not create table")
+
+	testSnapshotStore(t, sqlstore.NewPgxSnapshotStore(db, tableName))
+
+	_, err = db.Exec(ctx, fmt.Sprintf("DROP TABLE %s", tableName))
+	require.NoError(t, err, "Could not perform DB cleanup")
+}
+
+func testSnapshotStore(t *testing.T, store eventsource.SnapshotStore) {
+	t.Helper()
+
+	aggID := uuid.New().String()
+
+	_, err := store.LoadSnapshot(ctx, aggID)
+	require.ErrorIs(t, err, eventsource.ErrNoSnapshot)
+
+	latest := eventsource.Snapshot{AggregateID: aggID, SequenceID: eventsource.NewULID(), Version: 2, Data: []byte("2"), Timestamp: 2}
+	require.NoError(t, store.SaveSnapshot(ctx, latest))
+
+	older := eventsource.Snapshot{AggregateID: aggID, SequenceID: eventsource.NewULID(), Version: 1, Data: []byte("1"), Timestamp: 1}
+	require.NoError(t, store.SaveSnapshot(ctx, older))
+
+	snapshot, err := store.LoadSnapshot(ctx, aggID)
+	require.NoError(t, err)
+	assert.Equal(t, latest, snapshot)
+}
+
+func TestGenericCheckpointStore(t *testing.T) { // nolint:paralleltest
+	db, eventsTable := setupDB(t)
+	defer cleanupDBGeneric(t, db, eventsTable)
+
+	tableName, query := createCheckpointTableQuery()
+	_, err := db.Exec(query)
+	require.NoError(t, err, "Could not create table")
+
+	testCheckpointStore(t, sqlstore.NewCheckpointStore(db, tableName))
+
+	_, err = db.Exec(fmt.Sprintf("DROP TABLE %s", tableName))
+	require.NoError(t, err, "Could not perform DB cleanup")
+}
+
+func TestPgxCheckpointStore(t *testing.T) { // nolint:paralleltest
+	db, eventsTable := setupDBPgx(t)
+	defer cleanupDBPgx(t, db, eventsTable)
+
+	tableName, query := createCheckpointTableQuery()
+	_, err := db.Exec(ctx, query)
+	require.NoError(t, err, "Could not create table")
+
+	testCheckpointStore(t, sqlstore.NewPgxCheckpointStore(db, tableName))
+
+	_, err = db.Exec(ctx, fmt.Sprintf("DROP TABLE %s", tableName))
+	require.NoError(t, err, "Could not perform DB cleanup")
+}
+
+func testCheckpointStore(t *testing.T, store projection.CheckpointStore) {
+	t.Helper()
+
+	sequenceID, err := store.LoadCheckpoint(ctx, "projection")
+	require.NoError(t, err)
+	assert.Empty(t, sequenceID)
+
+	for _, expected := range []string{eventsource.NewULID(), eventsource.NewULID(), ""} {
+		require.NoError(t, store.SaveCheckpoint(ctx, "projection", expected))
+
+		sequenceID, err = store.LoadCheckpoint(ctx, "projection")
+		require.NoError(t, err)
+		assert.Equal(t, expected, sequenceID)
+	}
+}
+
+func TestGenericOutbox(t *testing.T) { // nolint:paralleltest
+	db, tableName := setupDB(t)
+	defer cleanupDBGeneric(t, db, tableName)
+
+	outboxTable, query := createOutboxTableQuery()
+	_, err := db.Exec(query)
+	require.NoError(t, err, "Could not create table")
+
+	defer cleanupDBGeneric(t, db, outboxTable)
+
+	store := sqlstore.New(db, tableName, sqlstore.WithOutbox(outboxTable))
+
+	testOutbox(t, store, func(services ...eventsource.NotificationService) *sqlstore.Relay {
+		return sqlstore.NewRelay(db, outboxTable, services, sqlstore.WithBatchSize(2), sqlstore.WithMaxAttempts(2))
+	})
+
+	var pending int
+	require.NoError(t, db.QueryRow("SELECT count(*) FROM "+outboxTable).Scan(&pending))
+	assert.Zero(t, pending, "Delivered records are deleted")
+}
+
+func TestPgxOutbox(t *testing.T) { // nolint:paralleltest
+	db, tableName := setupDBPgx(t)
+	defer cleanupDBPgx(t, db, tableName)
+
+	outboxTable, query := createOutboxTableQuery()
+	_, err := db.Exec(ctx, query)
+	require.NoError(t, err, "Could not create table")
+
+	defer cleanupDBPgx(t, db, outboxTable)
+
+	store := sqlstore.NewPgx(db, tableName, sqlstore.WithOutbox(outboxTable))
+
+	testOutbox(t, store, func(services ...eventsource.NotificationService) *sqlstore.Relay {
+		return sqlstore.NewPgxRelay(db, outboxTable, services, sqlstore.WithBatchSize(2), sqlstore.WithMaxAttempts(2))
+	})
+
+	var pending int
+	require.NoError(t, db.QueryRow(ctx, "SELECT count(*) FROM "+outboxTable).Scan(&pending))
+	assert.Zero(t, pending, "Delivered records are deleted")
+}
+
+func testOutbox(t *testing.T, store eventsource.Store, newRelay func(...eventsource.NotificationService) *sqlstore.Relay) {
+	t.Helper()
+
+	records, err := createTestEvents(store, 3, nil, nil)
+	require.NoError(t, err)
+
+	sendErr := errors.New("unavailable")
+	service := eventsource.CreateNotificationServiceMock()
+	service.On("SendWithContext", mock.Anything, records[0]).Return(nil).Once()
+	service.On("SendWithContext", mock.Anything, records[1]).Return(sendErr).Once()
+
+	relay := newRelay(service)
+
+	delivered, err := relay.RelayPending(ctx)
+	require.ErrorIs(t, err, driver.ErrSendFailed)
+	assert.Equal(t, 1, delivered)
+	service.AssertExpectations(t)
+
+	rec := &recorder.Recorder{}
+	relay = newRelay(rec)
+
+	delivered, err = relay.RelayPending(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, delivered, "The failed record is retried in order")
+
+	delivered, err = relay.RelayPending(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 0, delivered, "All records have been delivered")
+
+	require.Len(t, rec.GetEventDatas(), 2)
+}
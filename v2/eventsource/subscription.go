@@ -0,0 +1,274 @@
+package eventsource
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/oklog/ulid"
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultMinPollInterval = 100 * time.Millisecond
+	defaultMaxPollInterval = 5 * time.Second
+	defaultPageSize        = 1000
+)
+
+// EventHandler handles the events delivered by a subscription
+type EventHandler func(ctx context.Context, event Event) error
+
+// Checkpoint keeps track of the sequence ID of the last event handled by a subscription,
+// so it can be resumed from there.
+type Checkpoint interface {
+	// Load the sequence ID of the last handled event, or an empty string if there is none
+	LoadCheckpoint(ctx context.Context) (sequenceID string, err error)
+	// Save the sequence ID of the last handled event
+	SaveCheckpoint(ctx context.Context, sequenceID string) error
+}
+
+// NotifyingStore is implemented by stores that can notify when new records have been
+// saved, letting subscriptions wait for them instead of polling.
+type NotifyingStore interface {
+	Store
+	// Notify returns a channel receiving a value each time records have been saved.
+	// The channel is closed when the context is done or the notifications fail. A nil
+	// channel is returned when notifications aren't enabled for the store.
+	Notify(ctx context.Context) (<-chan string, error)
+}
+
+// SubscribeOption is used for configuring a subscription
+type SubscribeOption func(sub *subscription)
+
+type subscription struct {
+	checkpoint      Checkpoint
+	untilCaughtUp   bool
+	minPollInterval time.Duration
+	maxPollInterval time.Duration
+	pageSize        int
+	lag             time.Duration
+}
+
+// WithCheckpoint makes the subscription resume from the checkpoint, if there is one,
+// and save the sequence ID of each handled event to it.
+func WithCheckpoint(checkpoint Checkpoint) SubscribeOption {
+	return func(sub *subscription) {
+		sub.checkpoint = checkpoint
+	}
+}
+
+// UntilCaughtUp makes the subscription return as soon as all saved events have been
+// delivered, instead of waiting for new events.
+func UntilCaughtUp() SubscribeOption {
+	return func(sub *subscription) {
+		sub.untilCaughtUp = true
+	}
+}
+
+// WithPollInterval sets how often the store is polled for new events. The interval
+// starts at minInterval and is doubled up to maxInterval while no new events are found.
+// When the store notifies about new events, it's only polled every maxInterval.
+// Stores without an index on the sequence ID, like the dynamo store, read the
+// whole table on each poll, which should be reflected in the intervals.
+func WithPollInterval(minInterval, maxInterval time.Duration) SubscribeOption {
+	return func(sub *subscription) {
+		sub.minPollInterval = minInterval
+		sub.maxPollInterval = max(minInterval, maxInterval)
+	}
+}
+
+// WithPageSize sets how many records are loaded at a time, from stores supporting
+// WithLimit. Other stores return all records after the last handled event.
+func WithPageSize(size int) SubscribeOption {
+	return func(sub *subscription) {
+		sub.pageSize = size
+	}
+}
+
+// WithLag holds back the records saved less than lag ago. The sequence IDs are created
+// when saving, before the transaction is committed, so a record may become visible after
+// a record with a greater sequence ID. Such records are skipped by the subscription,
+// unless they are committed within the lag.
+func WithLag(lag time.Duration) SubscribeOption {
+	return func(sub *subscription) {
+		sub.lag = lag
+	}
+}
+
+// Subscribe delivers all events with a sequence ID greater than fromSequenceID to the
+// handler, in sequence order, and then keeps delivering new events as they are saved.
+// It blocks until the context is done, returning nil, or until loading the events,
+// the handler or the checkpoint fails, returning the error.
+//
+// Events are delivered at least once, as an event may be handled again if the
+// checkpoint fails to be saved. Events committed after an event with a greater sequence
+// ID has been delivered are skipped, see WithLag.
+func (repo repository) Subscribe(ctx context.Context, fromSequenceID string, handler EventHandler, opts ...SubscribeOption) error {
+	sub := subscription{
+		minPollInterval: defaultMinPollInterval,
+		maxPollInterval: defaultMaxPollInterval,
+		pageSize:        defaultPageSize,
+	}
+
+	for _, opt := range opts {
+		opt(&sub)
+	}
+
+	sequenceID, err := sub.start(ctx, fromSequenceID)
+	if err != nil {
+		return err
+	}
+
+	notifications, err := repo.notifications(ctx, sub)
+	if err != nil {
+		return err
+	}
+
+	interval := sub.minPollInterval
+
+	for {
+		var delivered, held bool
+		if sequenceID, delivered, held, err = repo.deliver(ctx, sub, sequenceID, handler); err != nil {
+			return err
+		}
+
+		if sub.untilCaughtUp {
+			if !delivered {
+				return nil
+			}
+
+			continue
+		}
+
+		switch {
+		case delivered, held:
+			interval = sub.minPollInterval
+		case notifications != nil:
+			interval = sub.maxPollInterval
+		default:
+			interval = min(2*interval, sub.maxPollInterval)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case _, ok := <-notifications:
+			if !ok {
+				// fall back to polling
+				notifications = nil
+			}
+		case <-time.After(interval):
+		}
+	}
+}
+
+func (sub subscription) start(ctx context.Context, fromSequenceID string) (string, error) {
+	if sub.checkpoint == nil {
+		return fromSequenceID, nil
+	}
+
+	sequenceID, err := sub.checkpoint.LoadCheckpoint(ctx)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to load checkpoint")
+	}
+
+	if sequenceID == "" {
+		return fromSequenceID, nil
+	}
+
+	return sequenceID, nil
+}
+
+func (repo repository) notifications(ctx context.Context, sub subscription) (<-chan string, error) {
+	store, ok := findStore[NotifyingStore](repo.store)
+	if !ok || sub.untilCaughtUp {
+		return nil, nil
+	}
+
+	notifications, err := store.Notify(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to subscribe to store notifications")
+	}
+
+	return notifications, nil
+}
+
+// deliver hands the events saved after the sequence ID to the handler, one page at a
+// time, returning the sequence ID of the last handled event and whether records were
+// held back, as they were saved within the lag
+func (repo repository) deliver(ctx context.Context, sub subscription, sequenceID string, handler EventHandler) (string, bool, bool, error) {
+	var delivered bool
+
+	for ctx.Err() == nil {
+		records, err := CollectRecords(IterRecords(ctx, repo.store, BySequenceID(sequenceID), WithLimit(sub.pageSize)))
+		if err != nil {
+			return sequenceID, delivered, false, errors.Wrap(err, "failed to load events")
+		}
+
+		// Not all stores return the records in sequence order
+		sort.SliceStable(records, func(i, j int) bool {
+			return records[i].SequenceID < records[j].SequenceID
+		})
+
+		records = recordsAfter(records, sequenceID)
+		safe := sub.committed(records)
+
+		for _, record := range records[:safe] {
+			if ctx.Err() != nil {
+				break
+			}
+
+			if err = repo.handle(ctx, sub, record, handler); err != nil {
+				return sequenceID, delivered, false, err
+			}
+
+			sequenceID, delivered = record.SequenceID, true
+		}
+
+		if safe < len(records) {
+			return sequenceID, delivered, true, nil
+		}
+
+		if len(records) < sub.pageSize {
+			break
+		}
+	}
+
+	return sequenceID, delivered, false, nil
+}
+
+// committed returns the number of records, in sequence order, saved before the lag.
+// The time of a record is taken from its sequence ID, as events can set their timestamp.
+func (sub subscription) committed(records []Record) int {
+	if sub.lag <= 0 {
+		return len(records)
+	}
+
+	before := ulid.Timestamp(time.Now().Add(-sub.lag))
+	for i, record := range records {
+		if id, err := ulid.Parse(record.SequenceID); err == nil && id.Time() > before {
+			return i
+		}
+	}
+
+	return len(records)
+}
+
+func (repo repository) handle(ctx context.Context, sub subscription, record Record, handler EventHandler) error {
+	event, err := repo.unmarshalRecord(record)
+	if err != nil {
+		return err
+	}
+
+	if err = handler(ctx, event); err != nil {
+		return errors.Wrapf(err, "failed to handle event %s", record.SequenceID)
+	}
+
+	if sub.checkpoint != nil {
+		if err = sub.checkpoint.SaveCheckpoint(ctx, record.SequenceID); err != nil {
+			return errors.Wrap(err, "failed to save checkpoint")
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,235 @@
+package eventsource
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/oklog/ulid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type subscriptionStore struct {
+	*StoreMock
+	mutex         sync.Mutex
+	records       []Record
+	notifications chan string
+}
+
+func (s *subscriptionStore) Load(_ context.Context, _ ...QueryOption) ([]Record, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return append([]Record{}, s.records...), nil
+}
+
+func (s *subscriptionStore) Notify(_ context.Context) (<-chan string, error) {
+	return s.notifications, nil
+}
+
+func (s *subscriptionStore) add(sequenceID string) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.records = append(s.records, Record{SequenceID: sequenceID, Data: []byte(sequenceID)})
+}
+
+// pagedStore returns the records after the sequence ID in sequence order, at most limit
+type pagedStore struct {
+	*StoreMock
+	records []Record
+	loads   int
+}
+
+type pagedQuery struct {
+	sequenceID string
+	limit      int
+}
+
+func (q *pagedQuery) FilterBySequenceID(sequenceID string) {
+	q.sequenceID = sequenceID
+}
+
+func (q *pagedQuery) LimitRecords(limit int) {
+	q.limit = limit
+}
+
+func (s *pagedStore) Load(_ context.Context, opts ...QueryOption) ([]Record, error) {
+	s.loads++
+
+	query := &pagedQuery{}
+	for _, opt := range opts {
+		opt(query)
+	}
+
+	records := []Record{}
+	for _, record := range s.records {
+		if record.SequenceID > query.sequenceID && len(records) < query.limit {
+			records = append(records, record)
+		}
+	}
+
+	return records, nil
+}
+
+type sequenceSerializer struct{}
+
+func (sequenceSerializer) Unmarshal(data []byte, _ string) (Event, error) {
+	return &BaseEvent{SequenceID: string(data)}, nil
+}
+
+func (sequenceSerializer) Marshal(_ Event) ([]byte, error) {
+	return nil, nil
+}
+
+type memoryCheckpoint struct {
+	sequenceID string
+}
+
+func (c *memoryCheckpoint) LoadCheckpoint(_ context.Context) (string, error) {
+	return c.sequenceID, nil
+}
+
+func (c *memoryCheckpoint) SaveCheckpoint(_ context.Context, sequenceID string) error {
+	c.sequenceID = sequenceID
+	return nil
+}
+
+// subscribeUntil subscribes until the handler has received n events
+func subscribeUntil(t *testing.T, repo Repository, n int, opts ...SubscribeOption) []string {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var sequenceIDs []string
+	err := repo.Subscribe(ctx, "1", func(_ context.Context, event Event) error {
+		if sequenceIDs = append(sequenceIDs, event.GetSequenceID()); len(sequenceIDs) == n {
+			cancel()
+		}
+
+		return nil
+	}, opts...)
+
+	require.NoError(t, err)
+	require.Len(t, sequenceIDs, n, "timed out waiting for events")
+
+	return sequenceIDs
+}
+
+func Test_Subscribe(t *testing.T) {
+	store := &subscriptionStore{StoreMock: CreateStoreMock()}
+	for _, sequenceID := range []string{"3", "1", "2", "5", "4"} {
+		store.add(sequenceID)
+	}
+
+	checkpoint := &memoryCheckpoint{}
+	repo := NewRepository(store, sequenceSerializer{})
+
+	sequenceIDs := subscribeUntil(t, repo, 3, WithCheckpoint(checkpoint))
+	assert.Equal(t, []string{"2", "3", "4"}, sequenceIDs)
+	assert.Equal(t, "4", checkpoint.sequenceID)
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		store.add("6")
+	}()
+
+	sequenceIDs = subscribeUntil(t, repo, 2, WithCheckpoint(checkpoint), WithPollInterval(time.Millisecond, 5*time.Millisecond))
+	assert.Equal(t, []string{"5", "6"}, sequenceIDs)
+	assert.Equal(t, "6", checkpoint.sequenceID)
+}
+
+func Test_SubscribeWakesOnNotification(t *testing.T) {
+	store := &subscriptionStore{StoreMock: CreateStoreMock(), notifications: make(chan string, 1)}
+	store.add("2")
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		store.add("3")
+		store.notifications <- "3"
+	}()
+
+	repo := NewRepository(store, sequenceSerializer{})
+
+	sequenceIDs := subscribeUntil(t, repo, 2, WithPollInterval(time.Hour, time.Hour))
+	assert.Equal(t, []string{"2", "3"}, sequenceIDs)
+}
+
+func Test_SubscribeHandlerErr(t *testing.T) {
+	store := &subscriptionStore{StoreMock: CreateStoreMock()}
+	store.add("2")
+	store.add("3")
+
+	checkpoint := &memoryCheckpoint{}
+	repo := NewRepository(store, sequenceSerializer{})
+
+	err := repo.Subscribe(context.Background(), "", func(_ context.Context, event Event) error {
+		if event.GetSequenceID() == "3" {
+			return errors.New("failed")
+		}
+
+		return nil
+	}, WithCheckpoint(checkpoint))
+
+	assert.EqualError(t, err, "failed to handle event 3: failed")
+	assert.Equal(t, "2", checkpoint.sequenceID)
+}
+
+func Test_SubscribeUntilCaughtUp(t *testing.T) {
+	store := &subscriptionStore{StoreMock: CreateStoreMock()}
+	store.add("2")
+	store.add("1")
+
+	repo := NewRepository(store, sequenceSerializer{})
+
+	var sequenceIDs []string
+	err := repo.Subscribe(context.Background(), "", func(_ context.Context, event Event) error {
+		sequenceIDs = append(sequenceIDs, event.GetSequenceID())
+		return nil
+	}, UntilCaughtUp())
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"1", "2"}, sequenceIDs)
+}
+
+func Test_SubscribePages(t *testing.T) {
+	store := &pagedStore{StoreMock: CreateStoreMock()}
+	for _, sequenceID := range []string{"1", "2", "3", "4", "5"} {
+		store.records = append(store.records, Record{SequenceID: sequenceID, Data: []byte(sequenceID)})
+	}
+
+	repo := NewRepository(store, sequenceSerializer{})
+
+	var sequenceIDs []string
+	err := repo.Subscribe(context.Background(), "", func(_ context.Context, event Event) error {
+		sequenceIDs = append(sequenceIDs, event.GetSequenceID())
+		return nil
+	}, UntilCaughtUp(), WithPageSize(2))
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"1", "2", "3", "4", "5"}, sequenceIDs)
+	assert.Equal(t, 4, store.loads, "3 pages and a last empty poll")
+}
+
+func Test_SubscribeWithLag(t *testing.T) {
+	store := &subscriptionStore{StoreMock: CreateStoreMock()}
+	old := ulid.MustNew(ulid.Timestamp(time.Now().Add(-time.Minute)), nil).String()
+	recent := ulid.MustNew(ulid.Now(), nil).String()
+	store.add(old)
+	store.add(recent)
+
+	repo := NewRepository(store, sequenceSerializer{})
+
+	var sequenceIDs []string
+	err := repo.Subscribe(context.Background(), "", func(_ context.Context, event Event) error {
+		sequenceIDs = append(sequenceIDs, event.GetSequenceID())
+		return nil
+	}, UntilCaughtUp(), WithLag(time.Second))
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{old}, sequenceIDs, "The record saved within the lag is held back")
+}
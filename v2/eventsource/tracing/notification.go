@@ -0,0 +1,65 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/SKF/go-eventsource/v2/eventsource"
+)
+
+type notificationService struct {
+	service eventsource.NotificationService
+	tracer  tracer
+}
+
+type batchNotificationService struct {
+	notificationService
+	batch eventsource.BatchNotificationService
+}
+
+// WrapNotificationService returns a notification service creating a span for each record
+// sent, or batch of records if the service sends batches
+func WrapNotificationService(service eventsource.NotificationService, opts ...Option) eventsource.NotificationService {
+	traced := notificationService{
+		service: service,
+		tracer:  newTracer(append([]Option{WithAttributes(NotificationServiceKey.String(fmt.Sprintf("%T", service)))}, opts...)),
+	}
+
+	if batch, ok := service.(eventsource.BatchNotificationService); ok {
+		return &batchNotificationService{notificationService: traced, batch: batch}
+	}
+
+	return &traced
+}
+
+func (s *notificationService) Send(record eventsource.Record) error {
+	return s.SendWithContext(context.Background(), record)
+}
+
+func (s *notificationService) SendWithContext(ctx context.Context, record eventsource.Record) error {
+	ctx, span := s.tracer.start(ctx, "eventsource.NotificationService.SendWithContext", recordAttributes(record)...)
+
+	err := s.service.SendWithContext(ctx, record)
+	end(span, err)
+
+	return err // nolint:wrapcheck
+}
+
+func (s *batchNotificationService) SendBatchWithContext(ctx context.Context, records []eventsource.Record) error {
+	ctx, span := s.tracer.start(ctx, "eventsource.NotificationService.SendBatchWithContext", RecordCountKey.Int(len(records)))
+
+	err := s.batch.SendBatchWithContext(ctx, records)
+	end(span, err)
+
+	return err // nolint:wrapcheck
+}
+
+func recordAttributes(record eventsource.Record) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		AggregateIDKey.String(record.AggregateID),
+		SequenceIDKey.String(record.SequenceID),
+		EventTypeKey.String(record.Type),
+	}
+}
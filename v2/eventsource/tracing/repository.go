@@ -0,0 +1,74 @@
+package tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/SKF/go-eventsource/v2/eventsource"
+)
+
+type repository struct {
+	eventsource.Repository
+	tracer tracer
+}
+
+// WrapRepository returns a repository creating spans for saving and loading. Wrap the store
+// of the repository with WrapStore for spans of the store calls within them.
+func WrapRepository(wrapped eventsource.Repository, opts ...Option) eventsource.Repository {
+	return &repository{
+		Repository: wrapped,
+		tracer:     newTracer(opts),
+	}
+}
+
+func (repo *repository) Save(ctx context.Context, events ...eventsource.Event) error {
+	ctx, span := repo.tracer.start(ctx, "eventsource.Repository.Save", eventAttributes(events)...)
+
+	err := repo.Repository.Save(ctx, events...)
+	end(span, err)
+
+	return err // nolint:wrapcheck
+}
+
+func (repo *repository) SaveWithExpectedVersion(ctx context.Context, aggregateID string, expectedVersion int64, events ...eventsource.Event) error {
+	attributes := append(eventAttributes(events), AggregateIDKey.String(aggregateID), ExpectedVersionKey.Int64(expectedVersion))
+	ctx, span := repo.tracer.start(ctx, "eventsource.Repository.SaveWithExpectedVersion", attributes...)
+
+	err := repo.Repository.SaveWithExpectedVersion(ctx, aggregateID, expectedVersion, events...)
+	end(span, err)
+
+	return err // nolint:wrapcheck
+}
+
+func (repo *repository) Load(ctx context.Context, id string, aggr eventsource.Aggregate) (bool, error) {
+	ctx, span := repo.tracer.start(ctx, "eventsource.Repository.Load", AggregateIDKey.String(id))
+
+	deleted, err := repo.Repository.Load(ctx, id, aggr)
+	span.SetAttributes(DeletedKey.Bool(deleted))
+	end(span, err)
+
+	return deleted, err // nolint:wrapcheck
+}
+
+func (repo *repository) LoadEvents(ctx context.Context, opts ...eventsource.QueryOption) ([]eventsource.Event, error) {
+	ctx, span := repo.tracer.start(ctx, "eventsource.Repository.LoadEvents", queryAttributes(opts)...)
+
+	events, err := repo.Repository.LoadEvents(ctx, opts...)
+	span.SetAttributes(EventCountKey.Int(len(events)))
+	end(span, err)
+
+	return events, err // nolint:wrapcheck
+}
+
+func eventAttributes(events []eventsource.Event) []attribute.KeyValue {
+	types := make([]string, 0, len(events))
+	for _, event := range events {
+		types = append(types, eventsource.GetTypeName(event))
+	}
+
+	return []attribute.KeyValue{
+		EventCountKey.Int(len(events)),
+		EventTypeKey.StringSlice(types),
+	}
+}
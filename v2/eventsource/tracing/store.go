@@ -0,0 +1,185 @@
+package tracing
+
+import (
+	"context"
+	"iter"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/SKF/go-eventsource/v2/eventsource"
+)
+
+type store struct {
+	store  eventsource.Store
+	tracer tracer
+}
+
+// WrapStore returns a store creating a span for each call to the store. Iterating is passed
+// on if the store supports it, and its other optional interfaces are found through Unwrap.
+func WrapStore(wrapped eventsource.Store, opts ...Option) eventsource.IterStore {
+	return &store{
+		store:  wrapped,
+		tracer: newTracer(opts),
+	}
+}
+
+func (s *store) NewTransaction(ctx context.Context, records ...eventsource.Record) (eventsource.StoreTransaction, error) {
+	spanCtx, span := s.tracer.start(ctx, "eventsource.Store.NewTransaction", RecordCountKey.Int(len(records)))
+
+	tx, err := s.store.NewTransaction(spanCtx, records...)
+	end(span, err)
+
+	if err != nil {
+		return nil, err // nolint:wrapcheck
+	}
+
+	return &transaction{StoreTransaction: tx, ctx: ctx, tracer: s.tracer}, nil
+}
+
+func (s *store) LoadByAggregate(ctx context.Context, aggregateID string, opts ...eventsource.QueryOption) ([]eventsource.Record, error) {
+	attributes := append(queryAttributes(opts), AggregateIDKey.String(aggregateID))
+	ctx, span := s.tracer.start(ctx, "eventsource.Store.LoadByAggregate", attributes...)
+
+	records, err := s.store.LoadByAggregate(ctx, aggregateID, opts...)
+
+	return records, endLoad(span, records, err)
+}
+
+func (s *store) Load(ctx context.Context, opts ...eventsource.QueryOption) ([]eventsource.Record, error) {
+	ctx, span := s.tracer.start(ctx, "eventsource.Store.Load", queryAttributes(opts)...)
+
+	records, err := s.store.Load(ctx, opts...)
+
+	return records, endLoad(span, records, err)
+}
+
+func (s *store) LoadBySequenceID(ctx context.Context, sequenceID string, opts ...eventsource.QueryOption) ([]eventsource.Record, error) {
+	attributes := append(queryAttributes(opts), SequenceIDKey.String(sequenceID))
+	ctx, span := s.tracer.start(ctx, "eventsource.Store.LoadBySequenceID", attributes...)
+
+	records, err := s.store.LoadBySequenceID(ctx, sequenceID, opts...) // nolint:staticcheck
+
+	return records, endLoad(span, records, err)
+}
+
+func (s *store) LoadBySequenceIDAndType(ctx context.Context, sequenceID string, eventType string, opts ...eventsource.QueryOption) ([]eventsource.Record, error) {
+	attributes := append(queryAttributes(opts), SequenceIDKey.String(sequenceID), EventTypeKey.String(eventType))
+	ctx, span := s.tracer.start(ctx, "eventsource.Store.LoadBySequenceIDAndType", attributes...)
+
+	records, err := s.store.LoadBySequenceIDAndType(ctx, sequenceID, eventType, opts...) // nolint:staticcheck
+
+	return records, endLoad(span, records, err)
+}
+
+func (s *store) LoadByTimestamp(ctx context.Context, timestamp int64, opts ...eventsource.QueryOption) ([]eventsource.Record, error) {
+	attributes := append(queryAttributes(opts), TimestampKey.Int64(timestamp))
+	ctx, span := s.tracer.start(ctx, "eventsource.Store.LoadByTimestamp", attributes...)
+
+	records, err := s.store.LoadByTimestamp(ctx, timestamp, opts...) // nolint:staticcheck
+
+	return records, endLoad(span, records, err)
+}
+
+// Iter creates a span which ends when the iteration stops
+func (s *store) Iter(ctx context.Context, opts ...eventsource.QueryOption) iter.Seq2[eventsource.Record, error] {
+	return func(yield func(eventsource.Record, error) bool) {
+		ctx, span := s.tracer.start(ctx, "eventsource.Store.Iter", queryAttributes(opts)...)
+
+		iterate(span, eventsource.IterRecords(ctx, s.store, opts...), yield)
+	}
+}
+
+// IterByAggregate creates a span which ends when the iteration stops
+func (s *store) IterByAggregate(ctx context.Context, aggregateID string, opts ...eventsource.QueryOption) iter.Seq2[eventsource.Record, error] {
+	return func(yield func(eventsource.Record, error) bool) {
+		attributes := append(queryAttributes(opts), AggregateIDKey.String(aggregateID))
+		ctx, span := s.tracer.start(ctx, "eventsource.Store.IterByAggregate", attributes...)
+
+		iterStore, ok := s.store.(eventsource.IterStore)
+		if !ok {
+			records, err := s.store.LoadByAggregate(ctx, aggregateID, opts...)
+			iterate(span, iterSlice(records, err), yield)
+
+			return
+		}
+
+		iterate(span, iterStore.IterByAggregate(ctx, aggregateID, opts...), yield)
+	}
+}
+
+// Unwrap returns the traced store
+func (s *store) Unwrap() eventsource.Store {
+	return s.store
+}
+
+func endLoad(span trace.Span, records []eventsource.Record, err error) error {
+	span.SetAttributes(RecordCountKey.Int(len(records)))
+	end(span, err)
+
+	return err
+}
+
+func iterate(span trace.Span, seq iter.Seq2[eventsource.Record, error], yield func(eventsource.Record, error) bool) {
+	var (
+		count int
+		err   error
+	)
+
+	defer func() {
+		span.SetAttributes(RecordCountKey.Int(count))
+		end(span, err)
+	}()
+
+	for record, recordErr := range seq {
+		if recordErr != nil {
+			err = recordErr
+		} else {
+			count++
+		}
+
+		if !yield(record, recordErr) {
+			return
+		}
+	}
+}
+
+func iterSlice(records []eventsource.Record, err error) iter.Seq2[eventsource.Record, error] {
+	return func(yield func(eventsource.Record, error) bool) {
+		if err != nil {
+			yield(eventsource.Record{}, err)
+			return
+		}
+
+		for _, record := range records {
+			if !yield(record, nil) {
+				return
+			}
+		}
+	}
+}
+
+// transaction creates spans for committing and rolling back, within the context passed
+// when creating it
+type transaction struct {
+	eventsource.StoreTransaction
+	ctx    context.Context // nolint:containedctx
+	tracer tracer
+}
+
+func (tx *transaction) Commit() error {
+	_, span := tx.tracer.start(tx.ctx, "eventsource.StoreTransaction.Commit", RecordCountKey.Int(len(tx.GetRecords())))
+
+	err := tx.StoreTransaction.Commit()
+	end(span, err)
+
+	return err // nolint:wrapcheck
+}
+
+func (tx *transaction) Rollback() error {
+	_, span := tx.tracer.start(tx.ctx, "eventsource.StoreTransaction.Rollback")
+
+	err := tx.StoreTransaction.Rollback()
+	end(span, err)
+
+	return err // nolint:wrapcheck
+}
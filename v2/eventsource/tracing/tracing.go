@@ -0,0 +1,113 @@
+// Package tracing adds OpenTelemetry spans to repositories, stores and notification
+// services, by wrapping them
+package tracing
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/SKF/go-eventsource/v2/eventsource"
+)
+
+const tracerName = "github.com/SKF/go-eventsource/v2/eventsource/tracing"
+
+// The attributes of the spans
+const (
+	TableNameKey           = attribute.Key("db.collection.name")
+	AggregateIDKey         = attribute.Key("eventsource.aggregate_id")
+	SequenceIDKey          = attribute.Key("eventsource.sequence_id")
+	EventTypeKey           = attribute.Key("eventsource.event_type")
+	TimestampKey           = attribute.Key("eventsource.timestamp")
+	EventCountKey          = attribute.Key("eventsource.event_count")
+	RecordCountKey         = attribute.Key("eventsource.record_count")
+	QueryOptionsKey        = attribute.Key("eventsource.query_options")
+	DeletedKey             = attribute.Key("eventsource.deleted")
+	ExpectedVersionKey     = attribute.Key("eventsource.expected_version")
+	NotificationServiceKey = attribute.Key("eventsource.notification_service")
+)
+
+type config struct {
+	provider   trace.TracerProvider
+	attributes []attribute.KeyValue
+}
+
+// Option is used for configuring the tracing wrappers
+type Option func(c *config)
+
+// WithTracerProvider sets the provider of the tracer, the global provider by default
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(c *config) {
+		c.provider = provider
+	}
+}
+
+// WithAttributes adds the attributes to all spans of the wrapper
+func WithAttributes(attributes ...attribute.KeyValue) Option {
+	return func(c *config) {
+		c.attributes = append(c.attributes, attributes...)
+	}
+}
+
+// WithTableName adds the name of the table of the store to all spans of the wrapper
+func WithTableName(tableName string) Option {
+	return WithAttributes(TableNameKey.String(tableName))
+}
+
+type tracer struct {
+	tracer     trace.Tracer
+	attributes []attribute.KeyValue
+}
+
+func newTracer(opts []Option) tracer {
+	c := config{provider: otel.GetTracerProvider()}
+
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	return tracer{
+		tracer:     c.provider.Tracer(tracerName),
+		attributes: c.attributes,
+	}
+}
+
+func (t tracer) start(ctx context.Context, name string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	return t.tracer.Start(ctx, name, trace.WithAttributes(t.attributes...), trace.WithAttributes(attributes...)) // nolint:spancheck
+}
+
+// end records the error, if any, and ends the span
+func end(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
+
+// queryOptions evaluates the query options which aren't specific to a store
+type queryOptions struct {
+	sequenceID string
+}
+
+func (o *queryOptions) FilterBySequenceID(sequenceID string) {
+	o.sequenceID = sequenceID
+}
+
+func queryAttributes(opts []eventsource.QueryOption) []attribute.KeyValue {
+	evaluated := queryOptions{}
+	for _, opt := range opts {
+		opt(&evaluated)
+	}
+
+	attributes := []attribute.KeyValue{QueryOptionsKey.Int(len(opts))}
+	if evaluated.sequenceID != "" {
+		attributes = append(attributes, SequenceIDKey.String(evaluated.sequenceID))
+	}
+
+	return attributes
+}
@@ -0,0 +1,188 @@
+package tracing_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/SKF/go-eventsource/v2/eventsource"
+	"github.com/SKF/go-eventsource/v2/eventsource/serializers/json"
+	"github.com/SKF/go-eventsource/v2/eventsource/stores/memorystore"
+	"github.com/SKF/go-eventsource/v2/eventsource/tracing"
+)
+
+type OrderCreated struct {
+	*eventsource.BaseEvent
+}
+
+type order struct {
+	id string
+}
+
+func (o *order) On(context.Context, eventsource.Event) error {
+	return nil
+}
+
+func (o *order) SetAggregateID(id string) {
+	o.id = id
+}
+
+type failingService struct {
+	err error
+}
+
+func (s failingService) Send(record eventsource.Record) error {
+	return s.SendWithContext(context.Background(), record)
+}
+
+func (s failingService) SendWithContext(context.Context, eventsource.Record) error {
+	return s.err
+}
+
+type batchService struct {
+	failingService
+	batches int
+}
+
+func (s *batchService) SendBatchWithContext(context.Context, []eventsource.Record) error {
+	s.batches++
+
+	return nil
+}
+
+func setupTracing() (*tracetest.InMemoryExporter, tracing.Option) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	return exporter, tracing.WithTracerProvider(provider)
+}
+
+func spansByName(spans tracetest.SpanStubs) map[string]tracetest.SpanStub {
+	byName := map[string]tracetest.SpanStub{}
+	for _, span := range spans {
+		byName[span.Name] = span
+	}
+
+	return byName
+}
+
+func attributes(span tracetest.SpanStub) map[attribute.Key]attribute.Value {
+	values := map[attribute.Key]attribute.Value{}
+	for _, kv := range span.Attributes {
+		values[kv.Key] = kv.Value
+	}
+
+	return values
+}
+
+func TestRepositoryAndStore(t *testing.T) {
+	t.Parallel()
+
+	exporter, withProvider := setupTracing()
+	store := tracing.WrapStore(memorystore.New(), withProvider, tracing.WithTableName("events"))
+	repo := tracing.WrapRepository(eventsource.NewRepository(store, json.NewSerializer(OrderCreated{})), withProvider)
+
+	ctx := context.TODO()
+	require.NoError(t, repo.Save(ctx, OrderCreated{BaseEvent: &eventsource.BaseEvent{AggregateID: "order"}}))
+
+	_, err := repo.Load(ctx, "order", &order{})
+	require.NoError(t, err)
+
+	events, err := repo.LoadEvents(ctx, eventsource.BySequenceID("0"))
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+
+	spans := spansByName(exporter.GetSpans())
+	require.Len(t, spans, 7)
+
+	save := spans["eventsource.Repository.Save"]
+	assert.Equal(t, int64(1), attributes(save)[tracing.EventCountKey].AsInt64())
+	assert.Equal(t, []string{"OrderCreated"}, attributes(save)[tracing.EventTypeKey].AsStringSlice())
+
+	for _, name := range []string{"eventsource.Store.NewTransaction", "eventsource.StoreTransaction.Commit"} {
+		assert.Equal(t, save.SpanContext.SpanID(), spans[name].Parent.SpanID(), name)
+		assert.Equal(t, "events", attributes(spans[name])[tracing.TableNameKey].AsString(), name)
+	}
+
+	load := spans["eventsource.Store.LoadByAggregate"]
+	assert.Equal(t, spans["eventsource.Repository.Load"].SpanContext.SpanID(), load.Parent.SpanID())
+	assert.Equal(t, "order", attributes(load)[tracing.AggregateIDKey].AsString())
+	assert.Equal(t, int64(1), attributes(load)[tracing.RecordCountKey].AsInt64())
+
+	loadEvents := spans["eventsource.Repository.LoadEvents"]
+	assert.Equal(t, "0", attributes(loadEvents)[tracing.SequenceIDKey].AsString())
+	assert.Equal(t, int64(1), attributes(loadEvents)[tracing.EventCountKey].AsInt64())
+	assert.Contains(t, spans, "eventsource.Store.Load")
+}
+
+func TestStoreIter(t *testing.T) {
+	t.Parallel()
+
+	exporter, withProvider := setupTracing()
+	store := tracing.WrapStore(memorystore.New(), withProvider)
+	repo := eventsource.NewRepository(store, json.NewSerializer(OrderCreated{}))
+
+	ctx := context.TODO()
+	for range 3 {
+		require.NoError(t, repo.Save(ctx, OrderCreated{BaseEvent: &eventsource.BaseEvent{AggregateID: "order"}}))
+	}
+
+	exporter.Reset()
+
+	for _, err := range store.IterByAggregate(ctx, "order") {
+		require.NoError(t, err)
+
+		break
+	}
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "eventsource.Store.IterByAggregate", spans[0].Name)
+	assert.Equal(t, int64(1), attributes(spans[0])[tracing.RecordCountKey].AsInt64(), "The span ends when the iteration stops")
+}
+
+func TestNotificationService(t *testing.T) {
+	t.Parallel()
+
+	exporter, withProvider := setupTracing()
+	sendErr := errors.New("unavailable")
+	service := tracing.WrapNotificationService(failingService{err: sendErr}, withProvider)
+
+	err := service.SendWithContext(context.TODO(), eventsource.Record{AggregateID: "order", SequenceID: "1", Type: "OrderCreated"})
+	require.ErrorIs(t, err, sendErr)
+
+	_, ok := service.(eventsource.BatchNotificationService)
+	assert.False(t, ok)
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "eventsource.NotificationService.SendWithContext", spans[0].Name)
+	assert.Equal(t, codes.Error, spans[0].Status.Code)
+	assert.Equal(t, "OrderCreated", attributes(spans[0])[tracing.EventTypeKey].AsString())
+	assert.Equal(t, "tracing_test.failingService", attributes(spans[0])[tracing.NotificationServiceKey].AsString())
+}
+
+func TestBatchNotificationService(t *testing.T) {
+	t.Parallel()
+
+	exporter, withProvider := setupTracing()
+	batch := &batchService{}
+	service := tracing.WrapNotificationService(batch, withProvider)
+
+	batchService, ok := service.(eventsource.BatchNotificationService)
+	require.True(t, ok, "Batches are passed on")
+	require.NoError(t, batchService.SendBatchWithContext(context.TODO(), make([]eventsource.Record, 3)))
+	assert.Equal(t, 1, batch.batches)
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "eventsource.NotificationService.SendBatchWithContext", spans[0].Name)
+	assert.Equal(t, int64(3), attributes(spans[0])[tracing.RecordCountKey].AsInt64())
+}
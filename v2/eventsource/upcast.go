@@ -0,0 +1,162 @@
+package eventsource
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const schemaVersionSeparator = "/v"
+
+// SchemaVersioned is implemented by events whose shape has changed, returning the
+// version of the current shape. Events not implementing it are at version 1.
+type SchemaVersioned interface {
+	SchemaVersion() int
+}
+
+// RecordType returns the Record.Type of an event type at the schema version. For
+// version 1 it's the event type itself, otherwise the version is appended, like "OrderCreated/v2".
+func RecordType(eventType string, version int) string {
+	if version <= 1 {
+		return eventType
+	}
+
+	return eventType + schemaVersionSeparator + strconv.Itoa(version)
+}
+
+// ParseRecordType returns the event type and schema version of a Record.Type
+func ParseRecordType(recordType string) (eventType string, version int) {
+	i := strings.LastIndex(recordType, schemaVersionSeparator)
+	if i < 0 {
+		return recordType, 1
+	}
+
+	version, err := strconv.Atoi(recordType[i+len(schemaVersionSeparator):])
+	if err != nil {
+		return recordType, 1
+	}
+
+	return recordType[:i], version
+}
+
+func schemaVersion(event Event) int {
+	if versioned, ok := event.(SchemaVersioned); ok {
+		return versioned.SchemaVersion()
+	}
+
+	return 1
+}
+
+// UpcastFunc upgrades the data of a stored record from one shape to the next, returning
+// the event type and schema version of the new shape. The event type is changed when an
+// event type is renamed or split.
+type UpcastFunc func(data []byte) (eventType string, version int, upcasted []byte, err error)
+
+type upcastKey struct {
+	eventType string
+	version   int
+}
+
+// Upcasters is a chain of upcasters, upgrading stored records to the current shape of
+// their events before they are unmarshalled
+type Upcasters struct {
+	funcs map[upcastKey]UpcastFunc
+	// renames are the event types the upcasters registered with RegisterRename return
+	renames map[upcastKey]string
+}
+
+// NewUpcasters creates an empty chain of upcasters
+func NewUpcasters() *Upcasters {
+	return &Upcasters{funcs: map[upcastKey]UpcastFunc{}, renames: map[upcastKey]string{}}
+}
+
+// Register the upcaster for records of the event type at the schema version. Upcasters
+// renaming the event type should be registered with RegisterRename instead, or the records
+// of the old type aren't found by Repository.GetEventsBySequenceIDAndType.
+func (u *Upcasters) Register(eventType string, version int, upcast UpcastFunc) *Upcasters {
+	key := upcastKey{eventType: eventType, version: version}
+	u.funcs[key] = upcast
+	delete(u.renames, key)
+
+	return u
+}
+
+// RegisterRename registers the upcaster for records of the event type at the schema version,
+// which renames them to another event type. Upcasting fails if it returns another type.
+func (u *Upcasters) RegisterRename(eventType string, version int, renamedTo string, upcast UpcastFunc) *Upcasters {
+	key := upcastKey{eventType: eventType, version: version}
+	u.funcs[key] = upcast
+	u.renames[key] = renamedTo
+
+	return u
+}
+
+// renamedFrom returns the record types which are renamed to the event type by the upcasters,
+// directly or through other renames, including the older schema versions of the renamed types
+func (u *Upcasters) renamedFrom(eventType string) []string {
+	var (
+		types   []string
+		targets = []string{eventType}
+		seen    = map[string]bool{eventType: true}
+	)
+
+	for len(targets) > 0 {
+		target := targets[0]
+		targets = targets[1:]
+
+		for key, renamedTo := range u.renames {
+			if renamedTo != target {
+				continue
+			}
+
+			for v := 1; v <= key.version; v++ {
+				types = append(types, RecordType(key.eventType, v))
+			}
+
+			if !seen[key.eventType] {
+				seen[key.eventType] = true
+				targets = append(targets, key.eventType)
+			}
+		}
+	}
+
+	return types
+}
+
+// Upcast applies the registered upcasters to the record until there is none for its
+// event type and schema version
+func (u *Upcasters) Upcast(record Record) (Record, error) {
+	eventType, version := ParseRecordType(record.Type)
+
+	// each upcaster can be applied at most once, unless they form a cycle
+	for range len(u.funcs) + 1 {
+		key := upcastKey{eventType: eventType, version: version}
+
+		upcast, ok := u.funcs[key]
+		if !ok {
+			return record, nil
+		}
+
+		var err error
+		if eventType, version, record.Data, err = upcast(record.Data); err != nil {
+			return Record{}, errors.Wrapf(err, "failed to upcast record %s of type %s", record.SequenceID, record.Type)
+		}
+
+		if renamedTo, ok := u.renames[key]; ok && eventType != renamedTo {
+			return Record{}, errors.Errorf("upcaster of record %s of type %s returned type %s instead of %s", record.SequenceID, record.Type, eventType, renamedTo)
+		}
+
+		record.Type = RecordType(eventType, version)
+	}
+
+	return Record{}, errors.Errorf("upcasters of type %s form a cycle", record.Type)
+}
+
+// WithUpcasters makes the repository upgrade the stored records with the upcasters
+// before unmarshalling them, when loading aggregates and events
+func WithUpcasters(upcasters *Upcasters) RepositoryOption {
+	return func(repo *repository) {
+		repo.upcasters = upcasters
+	}
+}
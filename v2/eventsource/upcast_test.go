@@ -0,0 +1,258 @@
+package eventsource
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type VersionedEvent struct {
+	*BaseEvent
+}
+
+func (VersionedEvent) SchemaVersion() int {
+	return 3
+}
+
+// createTestUpcasters renames OrderPlaced to OrderCreated, and then upgrades
+// OrderCreated from version 1 to 2 and from 2 to 3
+func createTestUpcasters() *Upcasters {
+	return NewUpcasters().
+		RegisterRename("OrderPlaced", 1, "OrderCreated", func(data []byte) (string, int, []byte, error) {
+			return "OrderCreated", 1, data, nil
+		}).
+		Register("OrderCreated", 1, func(data []byte) (string, int, []byte, error) {
+			return "OrderCreated", 2, append(data, "+v2"...), nil
+		}).
+		Register("OrderCreated", 2, func(data []byte) (string, int, []byte, error) {
+			return "OrderCreated", 3, append(data, "+v3"...), nil
+		})
+}
+
+func Test_RecordType(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "OrderCreated", RecordType("OrderCreated", 1))
+	assert.Equal(t, "OrderCreated/v3", RecordType("OrderCreated", 3))
+
+	for recordType, expected := range map[string]struct {
+		eventType string
+		version   int
+	}{
+		"OrderCreated":     {"OrderCreated", 1},
+		"OrderCreated/v3":  {"OrderCreated", 3},
+		"OrderCreated/vX":  {"OrderCreated/vX", 1},
+		"OrderCreated/v12": {"OrderCreated", 12},
+	} {
+		eventType, version := ParseRecordType(recordType)
+		assert.Equal(t, expected.eventType, eventType, recordType)
+		assert.Equal(t, expected.version, version, recordType)
+	}
+}
+
+func Test_UpcastMultipleSteps(t *testing.T) {
+	t.Parallel()
+
+	upcasters := createTestUpcasters()
+
+	record, err := upcasters.Upcast(Record{Type: "OrderPlaced", Data: []byte("v1")})
+	require.NoError(t, err)
+	assert.Equal(t, Record{Type: "OrderCreated/v3", Data: []byte("v1+v2+v3")}, record)
+
+	record, err = upcasters.Upcast(Record{Type: "OrderCreated/v2", Data: []byte("v2")})
+	require.NoError(t, err)
+	assert.Equal(t, Record{Type: "OrderCreated/v3", Data: []byte("v2+v3")}, record)
+
+	record, err = upcasters.Upcast(Record{Type: "OrderCreated/v3", Data: []byte("v3")})
+	require.NoError(t, err)
+	assert.Equal(t, Record{Type: "OrderCreated/v3", Data: []byte("v3")}, record, "Current records are left as they are")
+}
+
+func Test_UpcastErrors(t *testing.T) {
+	t.Parallel()
+
+	upcastErr := errors.New("invalid data")
+	upcasters := NewUpcasters().
+		Register("Failing", 1, func([]byte) (string, int, []byte, error) {
+			return "", 0, nil, upcastErr
+		}).
+		Register("Ping", 1, func(data []byte) (string, int, []byte, error) {
+			return "Pong", 1, data, nil
+		}).
+		Register("Pong", 1, func(data []byte) (string, int, []byte, error) {
+			return "Ping", 1, data, nil
+		}).
+		RegisterRename("Renamed", 1, "Expected", func(data []byte) (string, int, []byte, error) {
+			return "Other", 1, data, nil
+		})
+
+	_, err := upcasters.Upcast(Record{Type: "Failing"})
+	require.ErrorIs(t, err, upcastErr)
+
+	_, err = upcasters.Upcast(Record{Type: "Ping"})
+	require.ErrorContains(t, err, "form a cycle")
+
+	_, err = upcasters.Upcast(Record{Type: "Renamed"})
+	require.ErrorContains(t, err, "returned type Other instead of Expected")
+}
+
+func Test_RepoLoadUpcasts(t *testing.T) {
+	t.Parallel()
+
+	storeMock, _, serializerMock, aggregatorMock := setupMocks()
+	baseEvent := &BaseEvent{AggregateID: "1234"}
+	history := []Record{
+		{SequenceID: "1", Type: "OrderPlaced", Data: []byte("a")},
+		{SequenceID: "2", Type: "OrderCreated/v2", Data: []byte("b")},
+	}
+
+	ctx := context.TODO()
+	storeMock.On("LoadByAggregate", ctx, "1234", []QueryOption(nil)).Return(history, nil)
+	serializerMock.On("Unmarshal", []byte("a+v2+v3"), "OrderCreated").Return(baseEvent, nil).Once()
+	serializerMock.On("Unmarshal", []byte("b+v3"), "OrderCreated").Return(baseEvent, nil).Once()
+	aggregatorMock.Mock.On("On", ctx, baseEvent).Return(nil).Twice()
+
+	repo := NewRepository(storeMock, serializerMock, WithUpcasters(createTestUpcasters()))
+	_, err := repo.Load(ctx, "1234", aggregatorMock)
+	require.NoError(t, err)
+
+	serializerMock.AssertExpectations(t)
+	aggregatorMock.Mock.AssertExpectations(t)
+}
+
+func Test_RepoLoadEventsUpcasts(t *testing.T) {
+	t.Parallel()
+
+	storeMock, _, serializerMock, _ := setupMocks()
+	baseEvent := &BaseEvent{AggregateID: "1234"}
+	records := []Record{{SequenceID: "1", Type: "OrderPlaced", Data: []byte("a")}}
+
+	ctx := context.TODO()
+	storeMock.On("Load", ctx, []QueryOption(nil)).Return(records, nil)
+	serializerMock.On("Unmarshal", []byte("a+v2+v3"), "OrderCreated").Return(baseEvent, nil).Twice()
+
+	repo := NewRepository(storeMock, serializerMock, WithUpcasters(createTestUpcasters()))
+
+	events, err := repo.LoadEvents(ctx)
+	require.NoError(t, err)
+	assert.Len(t, events, 1)
+
+	events, err = repo.UnmarshalRecords(records)
+	require.NoError(t, err)
+	assert.Len(t, events, 1)
+
+	serializerMock.AssertExpectations(t)
+}
+
+func Test_RepoSaveSchemaVersion(t *testing.T) {
+	t.Parallel()
+
+	storeMock, storeTransactionMock, serializerMock, _ := setupMocks()
+	event := VersionedEvent{BaseEvent: &BaseEvent{AggregateID: "1234"}}
+
+	ctx := context.TODO()
+	serializerMock.On("Marshal", event).Return([]byte("data"), nil)
+	storeMock.On("NewTransaction", ctx, mock.MatchedBy(func(rs []Record) bool {
+		return len(rs) == 1 && rs[0].Type == "VersionedEvent/v3"
+	})).Return(storeTransactionMock, nil).Once()
+	storeTransactionMock.On("Commit").Return(nil).Once()
+	storeTransactionMock.On("GetRecords").Return([]Record{}).Once()
+
+	repo := NewRepository(storeMock, serializerMock)
+	require.NoError(t, repo.Save(ctx, event))
+
+	storeMock.AssertExpectations(t)
+
+	serializerMock.On("Unmarshal", []byte("data"), "VersionedEvent").Return(event, nil).Once()
+
+	_, err := repo.UnmarshalRecords([]Record{{Type: "VersionedEvent/v3", Data: []byte("data")}})
+	require.NoError(t, err, "The schema version is stripped before unmarshalling")
+	serializerMock.AssertExpectations(t)
+}
+
+func Test_RepoGetEventsBySequenceIDAndTypeUpcasts(t *testing.T) {
+	t.Parallel()
+
+	storeMock, _, serializerMock, _ := setupMocks()
+	event := VersionedEvent{BaseEvent: &BaseEvent{AggregateID: "1234"}}
+	upcasters := NewUpcasters().
+		Register("VersionedEvent", 1, func(data []byte) (string, int, []byte, error) {
+			return "VersionedEvent", 3, append(data, "+v3"...), nil
+		}).
+		RegisterRename("OldEvent", 1, "VersionedEvent", func(data []byte) (string, int, []byte, error) {
+			return "VersionedEvent", 1, append(data, "+renamed"...), nil
+		}).
+		Register("Other", 1, func(data []byte) (string, int, []byte, error) {
+			return "Other", 2, data, nil
+		})
+
+	ctx := context.TODO()
+	storeMock.On("LoadBySequenceIDAndType", ctx, "0", "OldEvent", []QueryOption(nil)).Return([]Record{{SequenceID: "4", Type: "OldEvent", Data: []byte("c")}}, nil).Once()
+	storeMock.On("LoadBySequenceIDAndType", ctx, "0", "VersionedEvent", []QueryOption(nil)).Return([]Record{{SequenceID: "3", Type: "VersionedEvent", Data: []byte("a")}}, nil).Once()
+	storeMock.On("LoadBySequenceIDAndType", ctx, "0", "VersionedEvent/v2", []QueryOption(nil)).Return([]Record{}, nil).Once()
+	storeMock.On("LoadBySequenceIDAndType", ctx, "0", "VersionedEvent/v3", []QueryOption(nil)).Return([]Record{{SequenceID: "1", Type: "VersionedEvent/v3", Data: []byte("b")}}, nil).Once()
+	serializerMock.On("Unmarshal", []byte("b"), "VersionedEvent").Return(event, nil).Once()
+	serializerMock.On("Unmarshal", []byte("a+v3"), "VersionedEvent").Return(event, nil).Once()
+	serializerMock.On("Unmarshal", []byte("c+renamed+v3"), "VersionedEvent").Return(event, nil).Once()
+
+	repo := NewRepository(storeMock, serializerMock, WithUpcasters(upcasters))
+
+	events, err := repo.GetEventsBySequenceIDAndType(ctx, "0", event)
+	require.NoError(t, err)
+	assert.Len(t, events, 3)
+	storeMock.AssertNotCalled(t, "LoadBySequenceIDAndType", ctx, "0", "Other", mock.Anything)
+
+	storeMock.AssertExpectations(t)
+	serializerMock.AssertExpectations(t)
+}
+
+func Test_RepoGetEventsBySequenceIDAndTypeNotUpcasted(t *testing.T) {
+	t.Parallel()
+
+	storeMock, _, serializerMock, _ := setupMocks()
+	event := VersionedEvent{BaseEvent: &BaseEvent{AggregateID: "1234"}}
+
+	ctx := context.TODO()
+	storeMock.On("LoadBySequenceIDAndType", ctx, "0", "VersionedEvent", []QueryOption(nil)).Return([]Record{{SequenceID: "1", Type: "VersionedEvent"}}, nil).Once()
+
+	repo := NewRepository(storeMock, serializerMock)
+
+	_, err := repo.GetEventsBySequenceIDAndType(ctx, "0", event)
+	require.ErrorContains(t, err, "isn't upcasted to schema version 3")
+}
+
+func Test_RepoGetEventsBySequenceIDAndTypeLimit(t *testing.T) {
+	t.Parallel()
+
+	storeMock, _, serializerMock, _ := setupMocks()
+	event := VersionedEvent{BaseEvent: &BaseEvent{AggregateID: "1234"}}
+	upcasters := NewUpcasters().
+		Register("VersionedEvent", 1, func(data []byte) (string, int, []byte, error) {
+			return "VersionedEvent", 3, data, nil
+		})
+
+	ctx := context.TODO()
+	// records after "3" of the first type and after "5" of the last may not have been loaded
+	storeMock.On("LoadBySequenceIDAndType", ctx, "0", "VersionedEvent", mock.Anything).Return([]Record{
+		{SequenceID: "1", Type: "VersionedEvent", Data: []byte("1")}, {SequenceID: "3", Type: "VersionedEvent", Data: []byte("3")},
+	}, nil).Once()
+	storeMock.On("LoadBySequenceIDAndType", ctx, "0", "VersionedEvent/v2", mock.Anything).Return([]Record{}, nil).Once()
+	storeMock.On("LoadBySequenceIDAndType", ctx, "0", "VersionedEvent/v3", mock.Anything).Return([]Record{
+		{SequenceID: "2", Type: "VersionedEvent/v3", Data: []byte("2")}, {SequenceID: "5", Type: "VersionedEvent/v3", Data: []byte("5")},
+	}, nil).Once()
+	serializerMock.On("Unmarshal", []byte("1"), "VersionedEvent").Return(event, nil).Once()
+	serializerMock.On("Unmarshal", []byte("2"), "VersionedEvent").Return(event, nil).Once()
+
+	repo := NewRepository(storeMock, serializerMock, WithUpcasters(upcasters))
+
+	events, err := repo.GetEventsBySequenceIDAndType(ctx, "0", event, WithLimit(2))
+	require.NoError(t, err)
+	assert.Len(t, events, 2, "The first records of all types, up to the limit")
+
+	storeMock.AssertExpectations(t)
+	serializerMock.AssertExpectations(t)
+}